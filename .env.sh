@@ -11,11 +11,28 @@ export MONGODB_QUERY_TIMEOUT_SECONDS="5"
 
 export REDIS_URI="localhost:6379"
 export REDIS_QUERY_TIMEOUT_SECONDS="5"
+# Role sync polls at this interval only when MongoDB has no change streams (not a replica set)
 export REDIS_SYNC_INTERVAL="5"
+export REDIS_RECONCILE_INTERVAL="300"
+export ROLE_SYNC_LEASE_SECONDS="15"
+export CHANGEFEED_HISTORY_LENGTH="1000"
 
 export DB_PROJECTS_COLLECTION="projects"
 export DB_WORKSPACES_COLLECTION="workspaces"
+export DB_WORKSPACE_MEMBERS_COLLECTION="workspace_members"
+export DB_PROJECT_MEMBERS_COLLECTION="project_members"
 export DB_ROLES_COLLECTION="roles"
+export DB_ROLE_BINDINGS_COLLECTION="role_bindings"
+export DB_INVITATIONS_COLLECTION="invitations"
+export DB_OWNERSHIP_TRANSFERS_COLLECTION="ownership_transfers"
+export DB_AUDIT_EVENTS_COLLECTION="audit_events"
+export DB_DECISION_LOGS_COLLECTION="decision_logs"
+export DB_WEBHOOKS_COLLECTION="webhooks"
+export DB_WEBHOOK_DELIVERIES_COLLECTION="webhook_deliveries"
+# base64 Ed25519 seed used to sign audit exports, unset disables exports
+export AUDIT_SIGNING_KEY=""
+export INVITATION_TTL_HOURS="72"
+export OWNERSHIP_TRANSFER_TTL_HOURS="168"
 export DB_RESOURCES_COLLECTION="resources"
 
 export PORT="8002"
@@ -2,13 +2,18 @@ package cmd
 
 import (
 	"context"
+	"time"
 
 	"github.com/agent-auth/agent-auth-api/db/mongodb"
 	"github.com/agent-auth/agent-auth-api/db/redis_dal"
 	"github.com/agent-auth/agent-auth-api/db/redisdb"
+	"github.com/agent-auth/agent-auth-api/pkg/leader"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 	"github.com/agent-auth/agent-auth-api/web/server"
 	"github.com/agent-auth/common-lib/pkg/logger"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 // serveCmd represents the serve command
@@ -19,6 +24,17 @@ var serveCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		logger := logger.NewLogger()
 
+		var tracingConfig tracing.Config
+		if err := viper.UnmarshalKey("tracing", &tracingConfig); err != nil {
+			logger.Fatal("tracing is invalid", zap.Error(err))
+		}
+		tracingConfig.ServiceName = viper.GetString("service_name")
+		tracingConfig.ServiceVersion = viper.GetString("service_version")
+		shutdownTracing, err := tracing.Setup(context.Background(), tracingConfig)
+		if err != nil {
+			logger.Fatal("failed to set up tracing", zap.Error(err))
+		}
+
 		go func() {
 			logger.Info("Starting mongo client")
 			_ = mongodb.NewMongoClient()
@@ -29,13 +45,28 @@ var serveCmd = &cobra.Command{
 			_ = redisdb.NewRedisClient()
 		}()
 
+		// Only the replica holding the lease syncs roles; the others take
+		// over if it dies
 		go func() {
-			logger.Info("Starting initial sync of roles")
-			redis_dal.NewRedisRolesDal().SyncRolesCollection(context.Background())
+			elector := leader.NewElector(redis_dal.NewRedisLeaseDal(), redis_dal.RoleSyncLeaseKey, logger, leader.Config{
+				LeaseDuration: time.Duration(getEnvInt("ROLE_SYNC_LEASE_SECONDS", 15)) * time.Second,
+			})
+			syncer := redis_dal.NewRedisRolesDal()
+			elector.Run(context.Background(), func(ctx context.Context) {
+				logger.Info("Starting initial sync of roles")
+				syncer.SyncRolesCollection(ctx)
+			})
 		}()
 
 		server := server.NewServer()
 		server.Start()
+
+		// Flush the spans still buffered
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("failed to flush traces", zap.Error(err))
+		}
 	},
 }
 
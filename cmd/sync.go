@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/redis_dal"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	syncAll     bool
+	syncProject string
+)
+
+// syncCmd groups the role sync tools
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "inspect and repair the Redis role snapshots",
+	Long:  `sync compares the role snapshots the API reads from Redis with the roles in MongoDB and rebuilds those that drifted`,
+}
+
+// syncDriftCmd compares every project
+var syncDriftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "report projects whose role snapshot is stale or missing",
+	Long:  `drift compares, per project, the MongoDB role count and latest update with the Redis snapshot version and sync time. It lists the projects whose snapshot is stale, missing or left without roles, and exits with status 1 when there is any.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		reportSyncDrift()
+	},
+}
+
+// syncResyncCmd rebuilds one project
+var syncResyncCmd = &cobra.Command{
+	Use:   "resync",
+	Short: "rebuild the role snapshots of a project",
+	Long:  `resync rebuilds the Redis roles and bindings snapshots of a project from MongoDB, replacing whatever Redis holds. It is safe to run while the API is syncing.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		resyncProject()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(syncCmd)
+	syncCmd.AddCommand(syncDriftCmd, syncResyncCmd)
+
+	syncDriftCmd.Flags().BoolVar(&syncAll, "all", false, "list every project, not only those that drifted")
+
+	syncResyncCmd.Flags().StringVar(&syncProject, "project", "", "ID of the project to resync")
+}
+
+func reportSyncDrift() {
+	report, err := redis_dal.NewRedisRolesDal().InspectSync(context.Background())
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	states := report.Projects
+	if !syncAll {
+		states = nil
+		for _, state := range report.Projects {
+			if !state.InSync() {
+				states = append(states, state)
+			}
+		}
+	}
+	if len(states) > 0 {
+		printSyncStates(states)
+	}
+
+	fmt.Printf("checked %d projects: %d stale, %d missing, %d orphaned\n",
+		len(report.Projects), len(report.Stale), len(report.Missing), len(report.Orphaned))
+	if len(report.Stale)+len(report.Missing)+len(report.Orphaned) > 0 {
+		os.Exit(1)
+	}
+}
+
+func resyncProject() {
+	if syncProject == "" {
+		log.Fatal("Provide --project")
+	}
+
+	projectID, err := bson.ObjectIDFromHex(syncProject)
+	if err != nil {
+		log.Fatalf("Invalid project ID %q", syncProject)
+	}
+
+	state, err := redis_dal.NewRedisRolesDal().ResyncProject(context.Background(), projectID)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	printSyncStates([]*redis_dal.ProjectSyncState{state})
+	if !state.InSync() {
+		os.Exit(1)
+	}
+}
+
+func printSyncStates(states []*redis_dal.ProjectSyncState) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tSTATE\tMONGO ROLES\tMONGO UPDATED\tMONGO VERSION\tREDIS VERSION\tREDIS SYNCED\tBINDINGS STATE\tMONGO BINDINGS\tMONGO BINDINGS VERSION\tREDIS BINDINGS VERSION")
+	for _, state := range states {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%d\t%s\t%s\t%d\t%d\t%d\n",
+			state.ProjectID, state.State, state.MongoRoles, formatSyncTime(state.MongoUpdatedUTC),
+			state.MongoVersion, state.RedisVersion, formatSyncTime(state.RedisSyncedAt),
+			state.BindingsState, state.MongoBindings, state.MongoBindingsVersion, state.RedisBindingsVersion)
+	}
+	w.Flush()
+}
+
+func formatSyncTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
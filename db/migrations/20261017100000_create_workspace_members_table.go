@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"context"
+	"os"
+	"time"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func init() {
+	// Workspace member collection indexes
+	workspaceMemberIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "WorkspaceID", Value: 1}, {Key: "Email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.M{"Email": 1},
+		},
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			members := db.Collection(os.Getenv("DB_WORKSPACE_MEMBERS_COLLECTION"))
+
+			_, err := members.Indexes().CreateMany(ctx, workspaceMemberIndexes)
+			if err != nil {
+				return err
+			}
+
+			// Backfill memberships from the owner and member list of each workspace
+			cursor, err := db.Collection(os.Getenv("DB_WORKSPACES_COLLECTION")).Find(ctx, bson.M{})
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			now := time.Now()
+			for cursor.Next(ctx) {
+				var workspace struct {
+					ID      bson.ObjectID `bson:"_id"`
+					OwnerID string        `bson:"OwnerID"`
+					Members []string      `bson:"Members"`
+				}
+				if err := cursor.Decode(&workspace); err != nil {
+					return err
+				}
+
+				roles := make(map[string]string, len(workspace.Members)+1)
+				for _, email := range workspace.Members {
+					roles[email] = "member"
+				}
+				if workspace.OwnerID != "" {
+					roles[workspace.OwnerID] = "owner"
+				}
+
+				for email, role := range roles {
+					_, err := members.UpdateOne(ctx,
+						bson.M{"WorkspaceID": workspace.ID, "Email": email},
+						bson.M{"$setOnInsert": bson.M{
+							"WorkspaceID":         workspace.ID,
+							"Email":               email,
+							"Role":                role,
+							"CreatedTimestampUTC": now,
+							"UpdatedTimestampUTC": now,
+						}},
+						options.Update().SetUpsert(true),
+					)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			return cursor.Err()
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(os.Getenv("DB_WORKSPACE_MEMBERS_COLLECTION")).Drop(ctx)
+		},
+	)
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"os"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func init() {
+	// Role binding collection indexes
+	roleBindingIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "RoleID", Value: 1}, {Key: "Subject", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "ProjectID", Value: 1}, {Key: "Subject", Value: 1}},
+		},
+		{
+			Keys: bson.M{"UpdatedTimestampUTC": 1},
+		},
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(os.Getenv("DB_ROLE_BINDINGS_COLLECTION")).Indexes().CreateMany(ctx, roleBindingIndexes)
+			return err
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(os.Getenv("DB_ROLE_BINDINGS_COLLECTION")).Drop(ctx)
+		},
+	)
+}
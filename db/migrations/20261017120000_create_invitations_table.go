@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"os"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func init() {
+	// Invitation collection indexes
+	invitationIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"TokenHash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "WorkspaceID", Value: 1}, {Key: "Status", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "Email", Value: 1}, {Key: "Status", Value: 1}},
+		},
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(os.Getenv("DB_INVITATIONS_COLLECTION")).Indexes().CreateMany(ctx, invitationIndexes)
+			return err
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(os.Getenv("DB_INVITATIONS_COLLECTION")).Drop(ctx)
+		},
+	)
+}
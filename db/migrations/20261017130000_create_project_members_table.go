@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"context"
+	"os"
+	"time"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func init() {
+	// Project member collection indexes
+	projectMemberIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "ProjectID", Value: 1}, {Key: "Email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.M{"Email": 1},
+		},
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			members := db.Collection(os.Getenv("DB_PROJECT_MEMBERS_COLLECTION"))
+
+			_, err := members.Indexes().CreateMany(ctx, projectMemberIndexes)
+			if err != nil {
+				return err
+			}
+
+			// Backfill memberships from the owner and member list of each project
+			cursor, err := db.Collection(os.Getenv("DB_PROJECTS_COLLECTION")).Find(ctx, bson.M{})
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			now := time.Now()
+			for cursor.Next(ctx) {
+				var project struct {
+					ID      bson.ObjectID `bson:"_id"`
+					OwnerID string        `bson:"OwnerID"`
+					Members []string      `bson:"Members"`
+				}
+				if err := cursor.Decode(&project); err != nil {
+					return err
+				}
+
+				roles := make(map[string]string, len(project.Members)+1)
+				for _, email := range project.Members {
+					roles[email] = "member"
+				}
+				if project.OwnerID != "" {
+					roles[project.OwnerID] = "owner"
+				}
+
+				for email, role := range roles {
+					_, err := members.UpdateOne(ctx,
+						bson.M{"ProjectID": project.ID, "Email": email},
+						bson.M{"$setOnInsert": bson.M{
+							"ProjectID":           project.ID,
+							"Email":               email,
+							"Role":                role,
+							"CreatedTimestampUTC": now,
+							"UpdatedTimestampUTC": now,
+						}},
+						options.Update().SetUpsert(true),
+					)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			return cursor.Err()
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(os.Getenv("DB_PROJECT_MEMBERS_COLLECTION")).Drop(ctx)
+		},
+	)
+}
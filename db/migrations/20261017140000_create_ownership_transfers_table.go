@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"os"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func init() {
+	// Ownership transfer collection indexes
+	ownershipTransferIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "ResourceType", Value: 1}, {Key: "ResourceID", Value: 1}, {Key: "CreatedTimestampUTC", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "ToOwner", Value: 1}, {Key: "Status", Value: 1}},
+		},
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(os.Getenv("DB_OWNERSHIP_TRANSFERS_COLLECTION")).Indexes().CreateMany(ctx, ownershipTransferIndexes)
+			return err
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(os.Getenv("DB_OWNERSHIP_TRANSFERS_COLLECTION")).Drop(ctx)
+		},
+	)
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+	"os"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func init() {
+	// Audit event collection indexes, serving the newest-first queries of
+	// GET /audit within a workspace or project
+	auditEventIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "WorkspaceID", Value: 1}, {Key: "TimestampUTC", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "ProjectID", Value: 1}, {Key: "TimestampUTC", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "TargetType", Value: 1}, {Key: "TargetID", Value: 1}, {Key: "TimestampUTC", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "Actor", Value: 1}, {Key: "TimestampUTC", Value: -1}},
+		},
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(os.Getenv("DB_AUDIT_EVENTS_COLLECTION")).Indexes().CreateMany(ctx, auditEventIndexes)
+			return err
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(os.Getenv("DB_AUDIT_EVENTS_COLLECTION")).Drop(ctx)
+		},
+	)
+}
@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"context"
+	"os"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func init() {
+	// Each workspace has one audit chain; its sequence numbers must not repeat
+	auditChainIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "WorkspaceID", Value: 1}, {Key: "Sequence", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("audit_chain"),
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			events := db.Collection(os.Getenv("DB_AUDIT_EVENTS_COLLECTION"))
+
+			// Seal the events written before the chain existed, oldest first
+			// within each workspace, so they verify and get distinct sequence
+			// numbers before the unique index is built
+			opts := options.Find().
+				SetSort(bson.D{{Key: "WorkspaceID", Value: 1}, {Key: "_id", Value: 1}}).
+				SetAllowDiskUse(true)
+			cursor, err := events.Find(ctx, bson.M{}, opts)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			var previous *audit.Event
+			for cursor.Next(ctx) {
+				var event audit.Event
+				if err := cursor.Decode(&event); err != nil {
+					return err
+				}
+				if previous != nil && previous.WorkspaceID != event.WorkspaceID {
+					previous = nil
+				}
+
+				if err := audit.Seal(&event, previous); err != nil {
+					return err
+				}
+				_, err := events.UpdateOne(ctx,
+					bson.M{"_id": event.ID},
+					bson.M{"$set": bson.M{
+						"Sequence": event.Sequence,
+						"PrevHash": event.PrevHash,
+						"Hash":     event.Hash,
+					}},
+				)
+				if err != nil {
+					return err
+				}
+				previous = &event
+			}
+			if err := cursor.Err(); err != nil {
+				return err
+			}
+
+			_, err = events.Indexes().CreateOne(ctx, auditChainIndex)
+			return err
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(os.Getenv("DB_AUDIT_EVENTS_COLLECTION")).Indexes().DropOne(ctx, "audit_chain")
+		},
+	)
+}
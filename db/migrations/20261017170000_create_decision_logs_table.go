@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+	"os"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func init() {
+	// Decision log indexes. Each record carries the expiry of its project's
+	// retention, so the TTL index expires it on that date; records without
+	// one are kept.
+	decisionLogIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "ExpiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "ProjectID", Value: 1}, {Key: "TimestampUTC", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "Subject", Value: 1}, {Key: "TimestampUTC", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "Decision", Value: 1}, {Key: "TimestampUTC", Value: -1}},
+		},
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(os.Getenv("DB_DECISION_LOGS_COLLECTION")).Indexes().CreateMany(ctx, decisionLogIndexes)
+			return err
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(os.Getenv("DB_DECISION_LOGS_COLLECTION")).Drop(ctx)
+		},
+	)
+}
@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"context"
+	"os"
+
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func init() {
+	// Webhook subscriptions are looked up by workspace on every audit event
+	webhookIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "WorkspaceID", Value: 1}, {Key: "Active", Value: 1}},
+		},
+	}
+
+	// Deliveries are claimed by due time and listed per webhook or, for the
+	// dead-letter list, per workspace and status
+	webhookDeliveryIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "Status", Value: 1}, {Key: "NextAttemptAt", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "WebhookID", Value: 1}, {Key: "CreatedTimestampUTC", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "WorkspaceID", Value: 1}, {Key: "Status", Value: 1}, {Key: "CreatedTimestampUTC", Value: -1}},
+		},
+	}
+
+	migrate.MustRegister(
+		// up
+		func(ctx context.Context, db *mongo.Database) error {
+			if _, err := db.Collection(os.Getenv("DB_WEBHOOKS_COLLECTION")).Indexes().CreateMany(ctx, webhookIndexes); err != nil {
+				return err
+			}
+			_, err := db.Collection(os.Getenv("DB_WEBHOOK_DELIVERIES_COLLECTION")).Indexes().CreateMany(ctx, webhookDeliveryIndexes)
+			return err
+		},
+
+		// down
+		func(ctx context.Context, db *mongo.Database) error {
+			if err := db.Collection(os.Getenv("DB_WEBHOOK_DELIVERIES_COLLECTION")).Drop(ctx); err != nil {
+				return err
+			}
+			return db.Collection(os.Getenv("DB_WEBHOOKS_COLLECTION")).Drop(ctx)
+		},
+	)
+}
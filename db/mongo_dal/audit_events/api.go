@@ -0,0 +1,29 @@
+package audit_events_dal
+
+import (
+	"context"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Filter narrows an audit query. Zero fields match everything.
+type Filter struct {
+	WorkspaceID bson.ObjectID
+	ProjectID   bson.ObjectID
+	Actor       string
+	Action      string
+	TargetType  string
+	TargetID    string
+	Since       time.Time
+	Until       time.Time
+}
+
+// AuditEventsDal defines the interface for audit event database operations.
+// Events are append-only: there is no update or delete.
+type AuditEventsDal interface {
+	Append(ctx context.Context, event *audit.Event) error
+	Query(ctx context.Context, filter Filter, skip, limit int64) ([]*audit.Event, error)
+	Walk(ctx context.Context, workspaceID bson.ObjectID, since, until time.Time, fn func(event *audit.Event) error) error
+}
@@ -0,0 +1,199 @@
+package audit_events_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type auditEvents struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewAuditEventsDal creates a new AuditEventsDal instance
+func NewAuditEventsDal() AuditEventsDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &auditEvents{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_AUDIT_EVENTS_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// appendAttempts bounds the retries of an append that lost the race for the
+// next sequence number of its workspace chain
+const appendAttempts = 5
+
+// Append seals an event to the head of its workspace chain and stores it.
+// The unique index on WorkspaceID and Sequence rejects a concurrent append
+// that read the same head, which then retries on the new head.
+func (a *auditEvents) Append(ctx context.Context, event *audit.Event) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "audit_events", "Append")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "audit_events", "Append")()
+
+	if event == nil {
+		return fmt.Errorf("audit event cannot be nil")
+	}
+	collection := a.db.Collection(a.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(a.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	for attempt := 0; attempt < appendAttempts; attempt++ {
+		var head *audit.Event
+		var last audit.Event
+		opts := options.FindOne().SetSort(bson.M{"Sequence": -1})
+		err := collection.FindOne(ctx, bson.M{"WorkspaceID": event.WorkspaceID}, opts).Decode(&last)
+		switch {
+		case err == nil:
+			head = &last
+		case err != mongo.ErrNoDocuments:
+			return fmt.Errorf("failed to read audit chain head: %w", err)
+		}
+
+		if err := audit.Seal(event, head); err != nil {
+			return err
+		}
+
+		result, err := collection.InsertOne(ctx, event)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to append audit event: %w", err)
+		}
+
+		event.ID = result.InsertedID.(bson.ObjectID)
+		return nil
+	}
+
+	return fmt.Errorf("failed to append audit event: chain head kept moving")
+}
+
+// Query returns the events matching filter, newest first
+func (a *auditEvents) Query(ctx context.Context, filter Filter, skip, limit int64) ([]*audit.Event, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "audit_events", "Query")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "audit_events", "Query")()
+
+	collection := a.db.Collection(a.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(a.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	query := bson.M{}
+	if !filter.WorkspaceID.IsZero() {
+		query["WorkspaceID"] = filter.WorkspaceID
+	}
+	if !filter.ProjectID.IsZero() {
+		query["ProjectID"] = filter.ProjectID
+	}
+	if filter.Actor != "" {
+		query["Actor"] = filter.Actor
+	}
+	if filter.Action != "" {
+		query["Action"] = filter.Action
+	}
+	if filter.TargetType != "" {
+		query["TargetType"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		query["TargetID"] = filter.TargetID
+	}
+	if !filter.Since.IsZero() || !filter.Until.IsZero() {
+		timestamp := bson.M{}
+		if !filter.Since.IsZero() {
+			timestamp["$gte"] = filter.Since
+		}
+		if !filter.Until.IsZero() {
+			timestamp["$lt"] = filter.Until
+		}
+		query["TimestampUTC"] = timestamp
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "TimestampUTC", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []*audit.Event{}
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Walk calls fn for each event of a workspace chain in sequence order,
+// optionally limited to events recorded in [since, until)
+func (a *auditEvents) Walk(ctx context.Context, workspaceID bson.ObjectID, since, until time.Time, fn func(event *audit.Event) error) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "audit_events", "Walk")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "audit_events", "Walk")()
+
+	collection := a.db.Collection(a.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		// A walk reads a whole chain, so it gets more time than a single query
+		time.Duration(a.queryTimeoutSeconds)*time.Second*10,
+	)
+	defer cancel()
+
+	query := bson.M{"WorkspaceID": workspaceID}
+	if !since.IsZero() || !until.IsZero() {
+		timestamp := bson.M{}
+		if !since.IsZero() {
+			timestamp["$gte"] = since
+		}
+		if !until.IsZero() {
+			timestamp["$lt"] = until
+		}
+		query["TimestampUTC"] = timestamp
+	}
+
+	cursor, err := collection.Find(ctx, query, options.Find().SetSort(bson.M{"Sequence": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to walk audit events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var event audit.Event
+		if err := cursor.Decode(&event); err != nil {
+			return fmt.Errorf("failed to decode audit event: %w", err)
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to walk audit events: %w", err)
+	}
+	return nil
+}
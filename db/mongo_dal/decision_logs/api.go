@@ -0,0 +1,14 @@
+package decision_logs_dal
+
+import (
+	"context"
+
+	"github.com/agent-auth/agent-auth-api/pkg/decisionlog"
+)
+
+// DecisionLogsDal defines the interface for authorization decision log
+// database operations. It is a decisionlog.Sink; records past their
+// ExpiresAt are removed by the collection's TTL index.
+type DecisionLogsDal interface {
+	Write(ctx context.Context, records []*decisionlog.Record) error
+}
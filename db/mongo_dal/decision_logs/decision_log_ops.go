@@ -0,0 +1,66 @@
+package decision_logs_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/decisionlog"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type decisionLogs struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewDecisionLogsDal creates a new DecisionLogsDal instance
+func NewDecisionLogsDal() DecisionLogsDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &decisionLogs{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_DECISION_LOGS_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// Write stores a batch of decisions. The batch is unordered so one bad
+// record does not keep the rest out.
+func (d *decisionLogs) Write(ctx context.Context, records []*decisionlog.Record) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "decision_logs", "Write")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "decision_logs", "Write")()
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	collection := d.db.Collection(d.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(d.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	documents := make([]interface{}, len(records))
+	for i, record := range records {
+		documents[i] = record
+	}
+
+	if _, err := collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to write decision logs: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package invitations_dal
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Invitation states
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusDeclined = "declined"
+	StatusRevoked  = "revoked"
+)
+
+// Invitation offers an email address membership of a workspace. Only the
+// SHA-256 hash of the single-use token is stored.
+type Invitation struct {
+	ID                  bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	WorkspaceID         bson.ObjectID `json:"workspace_id" bson:"WorkspaceID"`
+	Email               string        `json:"email" bson:"Email"`
+	Role                string        `json:"role" bson:"Role"`
+	TokenHash           string        `json:"-" bson:"TokenHash"`
+	Status              string        `json:"status" bson:"Status"`
+	InvitedBy           string        `json:"invited_by" bson:"InvitedBy"`
+	ExpiresAtUTC        time.Time     `json:"expires_at_utc" bson:"ExpiresAtUTC"`
+	CreatedTimestampUTC time.Time     `json:"created_timestamp_utc" bson:"CreatedTimestampUTC"`
+	UpdatedTimestampUTC time.Time     `json:"updated_timestamp_utc" bson:"UpdatedTimestampUTC"`
+}
+
+// InvitationsDal defines the interface for invitation database operations
+type InvitationsDal interface {
+	Create(ctx context.Context, invitation *Invitation) (*Invitation, error)
+	Get(ctx context.Context, id bson.ObjectID) (*Invitation, error)
+	ListPendingByWorkspace(ctx context.Context, workspaceID bson.ObjectID) ([]*Invitation, error)
+	ListPendingByEmail(ctx context.Context, email string) ([]*Invitation, error)
+	Resolve(ctx context.Context, tokenHash, email, status string) (*Invitation, error)
+	Reopen(ctx context.Context, id bson.ObjectID, status string) error
+	Revoke(ctx context.Context, workspaceID, id bson.ObjectID) error
+}
@@ -0,0 +1,236 @@
+package invitations_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type invitations struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewInvitationsDal creates a new InvitationsDal instance
+func NewInvitationsDal() InvitationsDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &invitations{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_INVITATIONS_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// Create stores a new pending invitation
+func (i *invitations) Create(ctx context.Context, invitation *Invitation) (*Invitation, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "invitations", "Create")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "invitations", "Create")()
+
+	if invitation == nil {
+		return nil, fmt.Errorf("invitation cannot be nil")
+	}
+	collection := i.db.Collection(i.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(i.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	invitation.Status = StatusPending
+	invitation.CreatedTimestampUTC = now
+	invitation.UpdatedTimestampUTC = now
+
+	result, err := collection.InsertOne(ctx, invitation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	invitation.ID = result.InsertedID.(bson.ObjectID)
+	return invitation, nil
+}
+
+// Get returns an invitation by ID, or nil if there is none
+func (i *invitations) Get(ctx context.Context, id bson.ObjectID) (*Invitation, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "invitations", "Get")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "invitations", "Get")()
+
+	collection := i.db.Collection(i.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(i.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	var invitation Invitation
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// ListPendingByWorkspace returns the unexpired pending invitations of a workspace
+func (i *invitations) ListPendingByWorkspace(ctx context.Context, workspaceID bson.ObjectID) ([]*Invitation, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "invitations", "ListPendingByWorkspace")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "invitations", "ListPendingByWorkspace")()
+
+	return i.findPending(ctx, bson.M{"WorkspaceID": workspaceID})
+}
+
+// ListPendingByEmail returns the unexpired pending invitations sent to an email
+func (i *invitations) ListPendingByEmail(ctx context.Context, email string) ([]*Invitation, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "invitations", "ListPendingByEmail")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "invitations", "ListPendingByEmail")()
+
+	return i.findPending(ctx, bson.M{"Email": email})
+}
+
+// Resolve moves the pending, unexpired invitation matching tokenHash and email
+// to status. The transition is atomic, so a token can only be used once.
+// It returns nil if no such invitation exists.
+func (i *invitations) Resolve(ctx context.Context, tokenHash, email, status string) (*Invitation, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "invitations", "Resolve")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "invitations", "Resolve")()
+
+	collection := i.db.Collection(i.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(i.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"TokenHash":    tokenHash,
+		"Email":        email,
+		"Status":       StatusPending,
+		"ExpiresAtUTC": bson.M{"$gt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"Status":              status,
+			"UpdatedTimestampUTC": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var invitation Invitation
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to resolve invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// Reopen moves an invitation resolved to status back to pending, so its token
+// can be used again when acting on it failed
+func (i *invitations) Reopen(ctx context.Context, id bson.ObjectID, status string) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "invitations", "Reopen")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "invitations", "Reopen")()
+
+	collection := i.db.Collection(i.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(i.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"Status":              StatusPending,
+			"UpdatedTimestampUTC": time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "Status": status}, update)
+	if err != nil {
+		return fmt.Errorf("failed to reopen invitation: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s invitation not found with id: %v", status, id.Hex())
+	}
+
+	return nil
+}
+
+// Revoke cancels a pending invitation of a workspace
+func (i *invitations) Revoke(ctx context.Context, workspaceID, id bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "invitations", "Revoke")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "invitations", "Revoke")()
+
+	collection := i.db.Collection(i.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(i.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"Status":              StatusRevoked,
+			"UpdatedTimestampUTC": time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "WorkspaceID": workspaceID, "Status": StatusPending}, update)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("pending invitation not found with id: %v", id.Hex())
+	}
+
+	return nil
+}
+
+func (i *invitations) findPending(ctx context.Context, filter bson.M) ([]*Invitation, error) {
+	collection := i.db.Collection(i.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(i.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	filter["Status"] = StatusPending
+	filter["ExpiresAtUTC"] = bson.M{"$gt": time.Now()}
+
+	opts := options.Find().SetSort(bson.M{"CreatedTimestampUTC": 1})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var pending []*Invitation
+	if err = cursor.All(ctx, &pending); err != nil {
+		return nil, fmt.Errorf("failed to decode invitations: %w", err)
+	}
+
+	return pending, nil
+}
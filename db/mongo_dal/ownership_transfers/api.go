@@ -0,0 +1,52 @@
+package ownership_transfers_dal
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Kinds of resource whose ownership can be transferred
+const (
+	ResourceWorkspace = "workspace"
+	ResourceProject   = "project"
+)
+
+// Transfer states
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// OwnershipTransfer hands a workspace or project to a new owner. Transfers
+// requiring acceptance stay pending until the recipient answers; together
+// they form the ownership history of the resource.
+type OwnershipTransfer struct {
+	ID                    bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	ResourceType          string        `json:"resource_type" bson:"ResourceType"`
+	ResourceID            bson.ObjectID `json:"resource_id" bson:"ResourceID"`
+	FromOwner             string        `json:"from_owner" bson:"FromOwner"`
+	ToOwner               string        `json:"to_owner" bson:"ToOwner"`
+	RequestedBy           string        `json:"requested_by" bson:"RequestedBy"`
+	RequireAcceptance     bool          `json:"require_acceptance" bson:"RequireAcceptance"`
+	Status                string        `json:"status" bson:"Status"`
+	ExpiresAtUTC          time.Time     `json:"expires_at_utc" bson:"ExpiresAtUTC"`
+	CompletedTimestampUTC *time.Time    `json:"completed_timestamp_utc,omitempty" bson:"CompletedTimestampUTC,omitempty"`
+	CreatedTimestampUTC   time.Time     `json:"created_timestamp_utc" bson:"CreatedTimestampUTC"`
+	UpdatedTimestampUTC   time.Time     `json:"updated_timestamp_utc" bson:"UpdatedTimestampUTC"`
+}
+
+// OwnershipTransfersDal defines the interface for ownership transfer database operations
+type OwnershipTransfersDal interface {
+	Create(ctx context.Context, transfer *OwnershipTransfer) (*OwnershipTransfer, error)
+	Get(ctx context.Context, id bson.ObjectID) (*OwnershipTransfer, error)
+	ListByResource(ctx context.Context, resourceType string, resourceID bson.ObjectID) ([]*OwnershipTransfer, error)
+	ListPendingByRecipient(ctx context.Context, email string) ([]*OwnershipTransfer, error)
+	CancelPending(ctx context.Context, resourceType string, resourceID bson.ObjectID) error
+	Resolve(ctx context.Context, id bson.ObjectID, status string) (*OwnershipTransfer, error)
+	Fail(ctx context.Context, id bson.ObjectID) (*OwnershipTransfer, error)
+}
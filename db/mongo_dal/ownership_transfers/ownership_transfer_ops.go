@@ -0,0 +1,237 @@
+package ownership_transfers_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type ownershipTransfers struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewOwnershipTransfersDal creates a new OwnershipTransfersDal instance
+func NewOwnershipTransfersDal() OwnershipTransfersDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &ownershipTransfers{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_OWNERSHIP_TRANSFERS_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// Create stores a new pending transfer
+func (t *ownershipTransfers) Create(ctx context.Context, transfer *OwnershipTransfer) (*OwnershipTransfer, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "ownership_transfers", "Create")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "ownership_transfers", "Create")()
+
+	if transfer == nil {
+		return nil, fmt.Errorf("ownership transfer cannot be nil")
+	}
+	collection := t.db.Collection(t.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(t.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	transfer.Status = StatusPending
+	transfer.CreatedTimestampUTC = now
+	transfer.UpdatedTimestampUTC = now
+
+	result, err := collection.InsertOne(ctx, transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ownership transfer: %w", err)
+	}
+
+	transfer.ID = result.InsertedID.(bson.ObjectID)
+	return transfer, nil
+}
+
+// Get returns a transfer by ID, or nil if there is none
+func (t *ownershipTransfers) Get(ctx context.Context, id bson.ObjectID) (*OwnershipTransfer, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "ownership_transfers", "Get")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "ownership_transfers", "Get")()
+
+	collection := t.db.Collection(t.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(t.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	var transfer OwnershipTransfer
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&transfer)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find ownership transfer: %w", err)
+	}
+
+	return &transfer, nil
+}
+
+// ListByResource returns the ownership history of a resource, newest first
+func (t *ownershipTransfers) ListByResource(ctx context.Context, resourceType string, resourceID bson.ObjectID) ([]*OwnershipTransfer, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "ownership_transfers", "ListByResource")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "ownership_transfers", "ListByResource")()
+
+	opts := options.Find().SetSort(bson.M{"CreatedTimestampUTC": -1})
+	return t.find(ctx, bson.M{"ResourceType": resourceType, "ResourceID": resourceID}, opts)
+}
+
+// ListPendingByRecipient returns the unexpired transfers awaiting an answer from email
+func (t *ownershipTransfers) ListPendingByRecipient(ctx context.Context, email string) ([]*OwnershipTransfer, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "ownership_transfers", "ListPendingByRecipient")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "ownership_transfers", "ListPendingByRecipient")()
+
+	opts := options.Find().SetSort(bson.M{"CreatedTimestampUTC": 1})
+	return t.find(ctx, bson.M{
+		"ToOwner":      email,
+		"Status":       StatusPending,
+		"ExpiresAtUTC": bson.M{"$gt": time.Now()},
+	}, opts)
+}
+
+// CancelPending cancels every pending transfer of a resource
+func (t *ownershipTransfers) CancelPending(ctx context.Context, resourceType string, resourceID bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "ownership_transfers", "CancelPending")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "ownership_transfers", "CancelPending")()
+
+	collection := t.db.Collection(t.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(t.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"Status":              StatusCancelled,
+			"UpdatedTimestampUTC": time.Now(),
+		},
+	}
+
+	filter := bson.M{"ResourceType": resourceType, "ResourceID": resourceID, "Status": StatusPending}
+	if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to cancel ownership transfers: %w", err)
+	}
+
+	return nil
+}
+
+// Resolve moves a pending, unexpired transfer to status. The transition is
+// atomic, so a transfer is answered only once. It returns nil if no such
+// transfer exists.
+func (t *ownershipTransfers) Resolve(ctx context.Context, id bson.ObjectID, status string) (*OwnershipTransfer, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "ownership_transfers", "Resolve")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "ownership_transfers", "Resolve")()
+
+	collection := t.db.Collection(t.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(t.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	set := bson.M{
+		"Status":              status,
+		"UpdatedTimestampUTC": now,
+	}
+	if status == StatusCompleted {
+		set["CompletedTimestampUTC"] = now
+	}
+
+	filter := bson.M{
+		"_id":          id,
+		"Status":       StatusPending,
+		"ExpiresAtUTC": bson.M{"$gt": now},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var transfer OwnershipTransfer
+	err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&transfer)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to resolve ownership transfer: %w", err)
+	}
+
+	return &transfer, nil
+}
+
+// Fail marks a completed transfer whose change could not be applied as
+// failed, so the ownership history does not show a transfer that never
+// happened. It returns nil if no such transfer exists.
+func (t *ownershipTransfers) Fail(ctx context.Context, id bson.ObjectID) (*OwnershipTransfer, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "ownership_transfers", "Fail")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "ownership_transfers", "Fail")()
+
+	collection := t.db.Collection(t.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(t.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	update := bson.M{
+		"$set":   bson.M{"Status": StatusFailed, "UpdatedTimestampUTC": time.Now()},
+		"$unset": bson.M{"CompletedTimestampUTC": ""},
+	}
+	filter := bson.M{"_id": id, "Status": StatusCompleted}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var transfer OwnershipTransfer
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&transfer)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fail ownership transfer: %w", err)
+	}
+
+	return &transfer, nil
+}
+
+func (t *ownershipTransfers) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]*OwnershipTransfer, error) {
+	collection := t.db.Collection(t.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(t.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ownership transfers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var transfers []*OwnershipTransfer
+	if err = cursor.All(ctx, &transfers); err != nil {
+		return nil, fmt.Errorf("failed to decode ownership transfers: %w", err)
+	}
+
+	return transfers, nil
+}
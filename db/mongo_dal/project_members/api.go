@@ -0,0 +1,35 @@
+package project_members_dal
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Roles a member can hold within a project
+const (
+	RoleOwner  = "owner"
+	RoleMember = "member"
+)
+
+// ProjectMember binds a user to a project with a project-scoped role
+type ProjectMember struct {
+	ID                  bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	ProjectID           bson.ObjectID `json:"project_id" bson:"ProjectID"`
+	Email               string        `json:"email" bson:"Email"`
+	Role                string        `json:"role" bson:"Role"`
+	AddedBy             string        `json:"added_by" bson:"AddedBy"`
+	CreatedTimestampUTC time.Time     `json:"created_timestamp_utc" bson:"CreatedTimestampUTC"`
+	UpdatedTimestampUTC time.Time     `json:"updated_timestamp_utc" bson:"UpdatedTimestampUTC"`
+}
+
+// ProjectMembersDal defines the interface for project membership database operations
+type ProjectMembersDal interface {
+	Upsert(ctx context.Context, projectID bson.ObjectID, email, role, addedBy string) (*ProjectMember, error)
+	Get(ctx context.Context, projectID bson.ObjectID, email string) (*ProjectMember, error)
+	ListByProject(ctx context.Context, projectID bson.ObjectID) ([]*ProjectMember, error)
+	CountByRole(ctx context.Context, projectID bson.ObjectID, role string) (int64, error)
+	Remove(ctx context.Context, projectID bson.ObjectID, email string) error
+	DeleteByProjectID(ctx context.Context, projectID bson.ObjectID) error
+}
@@ -0,0 +1,190 @@
+package project_members_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type projectMembers struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewProjectMembersDal creates a new ProjectMembersDal instance
+func NewProjectMembersDal() ProjectMembersDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &projectMembers{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_PROJECT_MEMBERS_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// Upsert sets the role of a user in a project, adding the membership if needed
+func (m *projectMembers) Upsert(ctx context.Context, projectID bson.ObjectID, email, role, addedBy string) (*ProjectMember, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "project_members", "Upsert")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "project_members", "Upsert")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"Role":                role,
+			"UpdatedTimestampUTC": now,
+		},
+		"$setOnInsert": bson.M{
+			"ProjectID":           projectID,
+			"Email":               email,
+			"AddedBy":             addedBy,
+			"CreatedTimestampUTC": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var member ProjectMember
+	err := collection.FindOneAndUpdate(ctx, bson.M{"ProjectID": projectID, "Email": email}, update, opts).Decode(&member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert project member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// Get returns the membership of a user in a project, or nil if there is none
+func (m *projectMembers) Get(ctx context.Context, projectID bson.ObjectID, email string) (*ProjectMember, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "project_members", "Get")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "project_members", "Get")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	var member ProjectMember
+	err := collection.FindOne(ctx, bson.M{"ProjectID": projectID, "Email": email}).Decode(&member)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find project member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// ListByProject returns every member of a project
+func (m *projectMembers) ListByProject(ctx context.Context, projectID bson.ObjectID) ([]*ProjectMember, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "project_members", "ListByProject")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "project_members", "ListByProject")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"CreatedTimestampUTC": 1})
+
+	cursor, err := collection.Find(ctx, bson.M{"ProjectID": projectID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project members: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var members []*ProjectMember
+	if err = cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode project members: %w", err)
+	}
+
+	return members, nil
+}
+
+// CountByRole returns how many members of a project hold role
+func (m *projectMembers) CountByRole(ctx context.Context, projectID bson.ObjectID, role string) (int64, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "project_members", "CountByRole")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "project_members", "CountByRole")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	count, err := collection.CountDocuments(ctx, bson.M{"ProjectID": projectID, "Role": role})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count project members: %w", err)
+	}
+
+	return count, nil
+}
+
+// Remove deletes the membership of a user in a project
+func (m *projectMembers) Remove(ctx context.Context, projectID bson.ObjectID, email string) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "project_members", "Remove")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "project_members", "Remove")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"ProjectID": projectID, "Email": email})
+	if err != nil {
+		return fmt.Errorf("failed to remove project member: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("project member not found: %v", email)
+	}
+
+	return nil
+}
+
+// DeleteByProjectID deletes every membership of a project
+func (m *projectMembers) DeleteByProjectID(ctx context.Context, projectID bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "project_members", "DeleteByProjectID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "project_members", "DeleteByProjectID")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	if _, err := collection.DeleteMany(ctx, bson.M{"ProjectID": projectID}); err != nil {
+		return fmt.Errorf("failed to delete project members: %w", err)
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package projects_dal
 
 import (
+	"context"
+
 	"go.mongodb.org/mongo-driver/v2/bson"
 
 	"github.com/agent-auth/common-lib/models"
@@ -8,14 +10,15 @@ import (
 
 // ProjectsDal defines the interface for project database operations
 type ProjectsDal interface {
-	Create(project *models.Project) (*models.Project, error)
-	Update(project *models.Project) error
-	GetByID(id bson.ObjectID) (*models.Project, error)
-	Delete(id bson.ObjectID) error
-	List(email string, skip, limit int64) ([]*models.Project, error)
-	GetBySlug(workspaceID bson.ObjectID, slug string) (*models.Project, error)
-	GetByOwnerID(ownerID string) ([]*models.Project, error)
-	AddMember(projectID bson.ObjectID, email string) error
-	RemoveMember(projectID bson.ObjectID, email string) error
-	IsMember(projectID bson.ObjectID, email string) (bool, error)
+	Create(ctx context.Context, project *models.Project) (*models.Project, error)
+	Update(ctx context.Context, project *models.Project) error
+	GetByID(ctx context.Context, id bson.ObjectID) (*models.Project, error)
+	Delete(ctx context.Context, id bson.ObjectID) error
+	List(ctx context.Context, email string, skip, limit int64) ([]*models.Project, error)
+	GetBySlug(ctx context.Context, workspaceID bson.ObjectID, slug string) (*models.Project, error)
+	GetByOwnerID(ctx context.Context, ownerID string) ([]*models.Project, error)
+	AddMember(ctx context.Context, projectID bson.ObjectID, email string) error
+	RemoveMember(ctx context.Context, projectID bson.ObjectID, email string) error
+	IsMember(ctx context.Context, projectID bson.ObjectID, email string) (bool, error)
+	SetOwner(ctx context.Context, projectID bson.ObjectID, email string, entry models.AuditLog) error
 }
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 	"github.com/agent-auth/common-lib/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -36,13 +38,16 @@ func NewProjectsDal() ProjectsDal {
 }
 
 // Create creates a new project
-func (p *projects) Create(project *models.Project) (*models.Project, error) {
+func (p *projects) Create(ctx context.Context, project *models.Project) (*models.Project, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "Create")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "Create")()
+
 	if project == nil {
 		return nil, fmt.Errorf("project cannot be nil")
 	}
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -67,7 +72,10 @@ func (p *projects) Create(project *models.Project) (*models.Project, error) {
 }
 
 // Update updates a project's mutable fields
-func (p *projects) Update(project *models.Project) error {
+func (p *projects) Update(ctx context.Context, project *models.Project) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "Update")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "Update")()
+
 	if project == nil {
 		return fmt.Errorf("project cannot be nil")
 	}
@@ -76,7 +84,7 @@ func (p *projects) Update(project *models.Project) error {
 	}
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -104,7 +112,10 @@ func (p *projects) Update(project *models.Project) error {
 }
 
 // List retrieves projects for a user with pagination
-func (p *projects) List(email string, skip, limit int64) ([]*models.Project, error) {
+func (p *projects) List(ctx context.Context, email string, skip, limit int64) ([]*models.Project, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "List")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "List")()
+
 	if limit <= 0 {
 		limit = 10 // Set a default limit
 	}
@@ -113,7 +124,7 @@ func (p *projects) List(email string, skip, limit int64) ([]*models.Project, err
 	}
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -144,10 +155,13 @@ func (p *projects) List(email string, skip, limit int64) ([]*models.Project, err
 }
 
 // GetByID retrieves a project by its ID
-func (p *projects) GetByID(id bson.ObjectID) (*models.Project, error) {
+func (p *projects) GetByID(ctx context.Context, id bson.ObjectID) (*models.Project, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "GetByID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "GetByID")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -166,10 +180,13 @@ func (p *projects) GetByID(id bson.ObjectID) (*models.Project, error) {
 }
 
 // Delete soft-deletes a project by ID
-func (p *projects) Delete(id bson.ObjectID) error {
+func (p *projects) Delete(ctx context.Context, id bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "Delete")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "Delete")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -193,10 +210,13 @@ func (p *projects) Delete(id bson.ObjectID) error {
 }
 
 // GetBySlug retrieves a project by its slug within a workspace
-func (p *projects) GetBySlug(workspaceID bson.ObjectID, slug string) (*models.Project, error) {
+func (p *projects) GetBySlug(ctx context.Context, workspaceID bson.ObjectID, slug string) (*models.Project, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "GetBySlug")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "GetBySlug")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -216,10 +236,13 @@ func (p *projects) GetBySlug(workspaceID bson.ObjectID, slug string) (*models.Pr
 }
 
 // GetByOwnerID retrieves all projects owned by a specific user
-func (p *projects) GetByOwnerID(ownerID string) ([]*models.Project, error) {
+func (p *projects) GetByOwnerID(ctx context.Context, ownerID string) ([]*models.Project, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "GetByOwnerID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "GetByOwnerID")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -240,13 +263,16 @@ func (p *projects) GetByOwnerID(ownerID string) ([]*models.Project, error) {
 }
 
 // AddMember adds a member to a project
-func (p *projects) AddMember(projectID bson.ObjectID, email string) error {
+func (p *projects) AddMember(ctx context.Context, projectID bson.ObjectID, email string) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "AddMember")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "AddMember")()
+
 	if projectID.IsZero() {
 		return fmt.Errorf("project ID cannot be empty")
 	}
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -270,10 +296,13 @@ func (p *projects) AddMember(projectID bson.ObjectID, email string) error {
 }
 
 // RemoveMember removes a member from a project
-func (p *projects) RemoveMember(projectID bson.ObjectID, email string) error {
+func (p *projects) RemoveMember(ctx context.Context, projectID bson.ObjectID, email string) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "RemoveMember")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "RemoveMember")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -297,7 +326,10 @@ func (p *projects) RemoveMember(projectID bson.ObjectID, email string) error {
 }
 
 // IsMember checks if the given email is a member of the specified project
-func (p *projects) IsMember(projectID bson.ObjectID, email string) (bool, error) {
+func (p *projects) IsMember(ctx context.Context, projectID bson.ObjectID, email string) (bool, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "IsMember")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "IsMember")()
+
 	if projectID.IsZero() {
 		return false, fmt.Errorf("project ID cannot be empty")
 	}
@@ -306,7 +338,7 @@ func (p *projects) IsMember(projectID bson.ObjectID, email string) (bool, error)
 	}
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -322,3 +354,39 @@ func (p *projects) IsMember(projectID bson.ObjectID, email string) (bool, error)
 
 	return count > 0, nil
 }
+
+// SetOwner records email as the owner of a project and appends entry to its
+// audit log
+func (p *projects) SetOwner(ctx context.Context, projectID bson.ObjectID, email string, entry models.AuditLog) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "SetOwner")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "SetOwner")()
+
+	if email == "" {
+		return fmt.Errorf("owner cannot be empty")
+	}
+	collection := p.db.Collection(p.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(p.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	update := bson.M{
+		"$addToSet": bson.M{"Members": email},
+		"$push":     bson.M{"AuditLogs": entry},
+		"$set": bson.M{
+			"OwnerID":             email,
+			"UpdatedTimestampUTC": time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": projectID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set project owner: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("project not found with id: %v", projectID)
+	}
+
+	return nil
+}
@@ -1,16 +1,18 @@
 package resources_dal
 
 import (
+	"context"
+
 	"github.com/agent-auth/common-lib/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // ResourcesDal defines the interface for resource database operations
 type ResourcesDal interface {
-	Create(resource *models.Resource) (*models.Resource, error)
-	Update(resource *models.Resource) error
-	GetByID(id bson.ObjectID) (*models.Resource, error)
-	Delete(id bson.ObjectID) error
-	GetByProjectID(projectID bson.ObjectID) ([]*models.Resource, error)
-	GetByURNAndProjectID(urn string, projectID bson.ObjectID) (*models.Resource, error)
+	Create(ctx context.Context, resource *models.Resource) (*models.Resource, error)
+	Update(ctx context.Context, resource *models.Resource) error
+	GetByID(ctx context.Context, id bson.ObjectID) (*models.Resource, error)
+	Delete(ctx context.Context, id bson.ObjectID) error
+	GetByProjectID(ctx context.Context, projectID bson.ObjectID) ([]*models.Resource, error)
+	GetByURNAndProjectID(ctx context.Context, urn string, projectID bson.ObjectID) (*models.Resource, error)
 }
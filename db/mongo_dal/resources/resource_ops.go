@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 	"github.com/agent-auth/common-lib/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -35,13 +37,16 @@ func NewResourcesDal() ResourcesDal {
 }
 
 // Create creates a new resource
-func (r *resources) Create(resource *models.Resource) (*models.Resource, error) {
+func (r *resources) Create(ctx context.Context, resource *models.Resource) (*models.Resource, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "resources", "Create")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "resources", "Create")()
+
 	if resource == nil {
 		return nil, fmt.Errorf("resource cannot be nil")
 	}
 	collection := r.db.Collection(r.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(r.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -62,7 +67,10 @@ func (r *resources) Create(resource *models.Resource) (*models.Resource, error)
 }
 
 // Update updates a resource's mutable fields
-func (r *resources) Update(resource *models.Resource) error {
+func (r *resources) Update(ctx context.Context, resource *models.Resource) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "resources", "Update")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "resources", "Update")()
+
 	if resource == nil {
 		return fmt.Errorf("resource cannot be nil")
 	}
@@ -71,7 +79,7 @@ func (r *resources) Update(resource *models.Resource) error {
 	}
 	collection := r.db.Collection(r.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(r.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -104,13 +112,16 @@ func (r *resources) Update(resource *models.Resource) error {
 }
 
 // GetByID retrieves a resource by its ID
-func (r *resources) GetByID(id bson.ObjectID) (*models.Resource, error) {
+func (r *resources) GetByID(ctx context.Context, id bson.ObjectID) (*models.Resource, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "resources", "GetByID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "resources", "GetByID")()
+
 	if id.IsZero() {
 		return nil, fmt.Errorf("invalid resource ID")
 	}
 	collection := r.db.Collection(r.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(r.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -129,13 +140,16 @@ func (r *resources) GetByID(id bson.ObjectID) (*models.Resource, error) {
 }
 
 // Delete soft-deletes a resource by ID
-func (r *resources) Delete(id bson.ObjectID) error {
+func (r *resources) Delete(ctx context.Context, id bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "resources", "Delete")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "resources", "Delete")()
+
 	if id.IsZero() {
 		return fmt.Errorf("invalid resource ID")
 	}
 	collection := r.db.Collection(r.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(r.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -166,13 +180,16 @@ func (r *resources) Delete(id bson.ObjectID) error {
 }
 
 // GetByProjectID retrieves all non-deleted resources for a given project ID
-func (r *resources) GetByProjectID(projectID bson.ObjectID) ([]*models.Resource, error) {
+func (r *resources) GetByProjectID(ctx context.Context, projectID bson.ObjectID) ([]*models.Resource, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "resources", "GetByProjectID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "resources", "GetByProjectID")()
+
 	if projectID.IsZero() {
 		return nil, fmt.Errorf("invalid project ID")
 	}
 	collection := r.db.Collection(r.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(r.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -197,13 +214,16 @@ func (r *resources) GetByProjectID(projectID bson.ObjectID) ([]*models.Resource,
 }
 
 // GetByURNAndProjectID retrieves a resource by URN and project ID
-func (r *resources) GetByURNAndProjectID(urn string, projectID bson.ObjectID) (*models.Resource, error) {
+func (r *resources) GetByURNAndProjectID(ctx context.Context, urn string, projectID bson.ObjectID) (*models.Resource, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "resources", "GetByURNAndProjectID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "resources", "GetByURNAndProjectID")()
+
 	if projectID.IsZero() {
 		return nil, fmt.Errorf("invalid project ID")
 	}
 	collection := r.db.Collection(r.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(r.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
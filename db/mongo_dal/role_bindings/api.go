@@ -0,0 +1,39 @@
+package role_bindings_dal
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Subject types a project role can be assigned to
+const (
+	SubjectTypeUser  = "user"
+	SubjectTypeAgent = "agent"
+)
+
+// RoleBinding assigns a project role to a user or agent
+type RoleBinding struct {
+	ID                  bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	ProjectID           bson.ObjectID `json:"project_id" bson:"ProjectID"`
+	RoleID              bson.ObjectID `json:"role_id" bson:"RoleID"`
+	Role                string        `json:"role" bson:"Role"`
+	Subject             string        `json:"subject" bson:"Subject"`
+	SubjectType         string        `json:"subject_type" bson:"SubjectType"`
+	AssignedBy          string        `json:"assigned_by" bson:"AssignedBy"`
+	Deleted             bool          `json:"deleted" bson:"Deleted"`
+	CreatedTimestampUTC time.Time     `json:"created_timestamp_utc" bson:"CreatedTimestampUTC"`
+	UpdatedTimestampUTC time.Time     `json:"updated_timestamp_utc" bson:"UpdatedTimestampUTC"`
+}
+
+// RoleBindingsDal defines the interface for role binding database operations
+type RoleBindingsDal interface {
+	Assign(ctx context.Context, binding *RoleBinding) (*RoleBinding, error)
+	Unassign(ctx context.Context, roleID bson.ObjectID, subject string) error
+	ListByRole(ctx context.Context, roleID bson.ObjectID) ([]*RoleBinding, error)
+	ListBySubject(ctx context.Context, projectID bson.ObjectID, subject string) ([]*RoleBinding, error)
+	ListByProject(ctx context.Context, projectID bson.ObjectID) ([]*RoleBinding, error)
+	DeleteByRoleID(ctx context.Context, roleID bson.ObjectID) error
+	DeleteByProjectID(ctx context.Context, projectID bson.ObjectID) error
+}
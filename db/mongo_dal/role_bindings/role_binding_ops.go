@@ -0,0 +1,196 @@
+package role_bindings_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type roleBindings struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewRoleBindingsDal creates a new RoleBindingsDal instance
+func NewRoleBindingsDal() RoleBindingsDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &roleBindings{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_ROLE_BINDINGS_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// Assign binds a role to a subject, restoring a previously removed binding
+func (b *roleBindings) Assign(ctx context.Context, binding *RoleBinding) (*RoleBinding, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "role_bindings", "Assign")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "role_bindings", "Assign")()
+
+	if binding == nil {
+		return nil, fmt.Errorf("role binding cannot be nil")
+	}
+	collection := b.db.Collection(b.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(b.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"ProjectID":           binding.ProjectID,
+			"Role":                binding.Role,
+			"SubjectType":         binding.SubjectType,
+			"AssignedBy":          binding.AssignedBy,
+			"Deleted":             false,
+			"UpdatedTimestampUTC": now,
+		},
+		"$setOnInsert": bson.M{
+			"CreatedTimestampUTC": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var assigned RoleBinding
+	err := collection.FindOneAndUpdate(ctx, bson.M{"RoleID": binding.RoleID, "Subject": binding.Subject}, update, opts).Decode(&assigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return &assigned, nil
+}
+
+// Unassign soft deletes the binding of a role to a subject
+func (b *roleBindings) Unassign(ctx context.Context, roleID bson.ObjectID, subject string) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "role_bindings", "Unassign")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "role_bindings", "Unassign")()
+
+	collection := b.db.Collection(b.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(b.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"Deleted":             true,
+			"UpdatedTimestampUTC": time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"RoleID": roleID, "Subject": subject, "Deleted": false}, update)
+	if err != nil {
+		return fmt.Errorf("failed to unassign role: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("role binding not found for subject: %v", subject)
+	}
+
+	return nil
+}
+
+// ListByRole returns the active bindings of a role
+func (b *roleBindings) ListByRole(ctx context.Context, roleID bson.ObjectID) ([]*RoleBinding, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "role_bindings", "ListByRole")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "role_bindings", "ListByRole")()
+
+	return b.find(ctx, bson.M{"RoleID": roleID, "Deleted": false})
+}
+
+// ListBySubject returns the active bindings of a subject in a project
+func (b *roleBindings) ListBySubject(ctx context.Context, projectID bson.ObjectID, subject string) ([]*RoleBinding, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "role_bindings", "ListBySubject")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "role_bindings", "ListBySubject")()
+
+	return b.find(ctx, bson.M{"ProjectID": projectID, "Subject": subject, "Deleted": false})
+}
+
+// ListByProject returns every active binding of a project
+func (b *roleBindings) ListByProject(ctx context.Context, projectID bson.ObjectID) ([]*RoleBinding, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "role_bindings", "ListByProject")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "role_bindings", "ListByProject")()
+
+	return b.find(ctx, bson.M{"ProjectID": projectID, "Deleted": false})
+}
+
+// DeleteByRoleID soft deletes every binding of a role
+func (b *roleBindings) DeleteByRoleID(ctx context.Context, roleID bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "role_bindings", "DeleteByRoleID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "role_bindings", "DeleteByRoleID")()
+
+	return b.deleteMany(ctx, bson.M{"RoleID": roleID, "Deleted": false})
+}
+
+// DeleteByProjectID soft deletes every binding of a project
+func (b *roleBindings) DeleteByProjectID(ctx context.Context, projectID bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "role_bindings", "DeleteByProjectID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "role_bindings", "DeleteByProjectID")()
+
+	return b.deleteMany(ctx, bson.M{"ProjectID": projectID, "Deleted": false})
+}
+
+func (b *roleBindings) find(ctx context.Context, filter bson.M) ([]*RoleBinding, error) {
+	collection := b.db.Collection(b.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(b.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"CreatedTimestampUTC": 1})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role bindings: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var bindings []*RoleBinding
+	if err = cursor.All(ctx, &bindings); err != nil {
+		return nil, fmt.Errorf("failed to decode role bindings: %w", err)
+	}
+
+	return bindings, nil
+}
+
+func (b *roleBindings) deleteMany(ctx context.Context, filter bson.M) error {
+	collection := b.db.Collection(b.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(b.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"Deleted":             true,
+			"UpdatedTimestampUTC": time.Now(),
+		},
+	}
+
+	if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to delete role bindings: %w", err)
+	}
+
+	return nil
+}
@@ -5,16 +5,21 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 	"github.com/agent-auth/common-lib/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 // UpdatePermission updates a specific permission attribute using dot notation
-func (p *roles) UpdatePermission(id bson.ObjectID, resource string, actions []models.Action) error {
+func (p *roles) UpdatePermission(ctx context.Context, id bson.ObjectID, resource string, actions []models.Action) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "roles_permissions", "UpdatePermission")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "roles_permissions", "UpdatePermission")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
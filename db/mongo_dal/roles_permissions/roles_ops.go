@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 	"github.com/agent-auth/common-lib/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -35,10 +37,13 @@ func NewRolesDal() RolesDal {
 }
 
 // Create creates a new role record
-func (p *roles) Create(role *models.Roles) (*models.Roles, error) {
+func (p *roles) Create(ctx context.Context, role *models.Roles) (*models.Roles, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "roles_permissions", "Create")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "roles_permissions", "Create")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -57,10 +62,13 @@ func (p *roles) Create(role *models.Roles) (*models.Roles, error) {
 }
 
 // Delete removes a role record by ID
-func (p *roles) Delete(id bson.ObjectID) error {
+func (p *roles) Delete(ctx context.Context, id bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "roles_permissions", "Delete")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "roles_permissions", "Delete")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -82,10 +90,13 @@ func (p *roles) Delete(id bson.ObjectID) error {
 }
 
 // Get retrieves a role by ID
-func (p *roles) Get(id bson.ObjectID) (*models.Roles, error) {
+func (p *roles) Get(ctx context.Context, id bson.ObjectID) (*models.Roles, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "roles_permissions", "Get")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "roles_permissions", "Get")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -103,10 +114,13 @@ func (p *roles) Get(id bson.ObjectID) (*models.Roles, error) {
 }
 
 // DeleteByProjectID removes all roles for a specific project
-func (p *roles) DeleteByProjectID(projectID bson.ObjectID) error {
+func (p *roles) DeleteByProjectID(ctx context.Context, projectID bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "roles_permissions", "DeleteByProjectID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "roles_permissions", "DeleteByProjectID")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -128,10 +142,13 @@ func (p *roles) DeleteByProjectID(projectID bson.ObjectID) error {
 }
 
 // GetByProjectID retrieves all roles for a specific project
-func (p *roles) GetByProjectID(projectID bson.ObjectID) ([]*models.Roles, error) {
+func (p *roles) GetByProjectID(ctx context.Context, projectID bson.ObjectID) ([]*models.Roles, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "roles_permissions", "GetByProjectID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "roles_permissions", "GetByProjectID")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -154,10 +171,13 @@ func (p *roles) GetByProjectID(projectID bson.ObjectID) ([]*models.Roles, error)
 }
 
 // GetByProjectIDAndRole retrieves a role by project ID and role
-func (p *roles) GetByProjectIDAndRole(projectID bson.ObjectID, r string) (*models.Roles, error) {
+func (p *roles) GetByProjectIDAndRole(ctx context.Context, projectID bson.ObjectID, r string) (*models.Roles, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "roles_permissions", "GetByProjectIDAndRole")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "roles_permissions", "GetByProjectIDAndRole")()
+
 	collection := p.db.Collection(p.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(p.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -0,0 +1,25 @@
+package webhook_deliveries_dal
+
+import (
+	"context"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/webhook"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Filter narrows a delivery listing. Zero fields match everything.
+type Filter struct {
+	WorkspaceID bson.ObjectID
+	WebhookID   bson.ObjectID
+	Status      string
+}
+
+// WebhookDeliveriesDal defines the interface for webhook delivery database operations
+type WebhookDeliveriesDal interface {
+	Create(ctx context.Context, delivery *webhook.Delivery) (*webhook.Delivery, error)
+	Get(ctx context.Context, id bson.ObjectID) (*webhook.Delivery, error)
+	List(ctx context.Context, filter Filter, skip, limit int64) ([]*webhook.Delivery, error)
+	Claim(ctx context.Context, now, leaseUntil time.Time) (*webhook.Delivery, error)
+	Update(ctx context.Context, delivery *webhook.Delivery) error
+}
@@ -0,0 +1,202 @@
+package webhook_deliveries_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/agent-auth/agent-auth-api/pkg/webhook"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type webhookDeliveries struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewWebhookDeliveriesDal creates a new WebhookDeliveriesDal instance
+func NewWebhookDeliveriesDal() WebhookDeliveriesDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &webhookDeliveries{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_WEBHOOK_DELIVERIES_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// Create stores a new pending delivery
+func (wd *webhookDeliveries) Create(ctx context.Context, delivery *webhook.Delivery) (*webhook.Delivery, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhook_deliveries", "Create")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhook_deliveries", "Create")()
+
+	if delivery == nil {
+		return nil, fmt.Errorf("webhook delivery cannot be nil")
+	}
+	collection := wd.db.Collection(wd.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wd.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	delivery.Status = webhook.StatusPending
+	delivery.Attempts = []webhook.Attempt{}
+	delivery.CreatedTimestampUTC = now
+	delivery.UpdatedTimestampUTC = now
+
+	result, err := collection.InsertOne(ctx, delivery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	delivery.ID = result.InsertedID.(bson.ObjectID)
+	return delivery, nil
+}
+
+// Get returns a delivery by ID, or nil if there is none
+func (wd *webhookDeliveries) Get(ctx context.Context, id bson.ObjectID) (*webhook.Delivery, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhook_deliveries", "Get")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhook_deliveries", "Get")()
+
+	collection := wd.db.Collection(wd.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wd.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	var delivery webhook.Delivery
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+// List returns the deliveries matching filter, newest first
+func (wd *webhookDeliveries) List(ctx context.Context, filter Filter, skip, limit int64) ([]*webhook.Delivery, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhook_deliveries", "List")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhook_deliveries", "List")()
+
+	collection := wd.db.Collection(wd.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wd.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	query := bson.M{}
+	if !filter.WorkspaceID.IsZero() {
+		query["WorkspaceID"] = filter.WorkspaceID
+	}
+	if !filter.WebhookID.IsZero() {
+		query["WebhookID"] = filter.WebhookID
+	}
+	if filter.Status != "" {
+		query["Status"] = filter.Status
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "CreatedTimestampUTC", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []*webhook.Delivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Claim leases the pending delivery that has been due the longest by moving
+// its next attempt to leaseUntil. It returns nil when none is due.
+func (wd *webhookDeliveries) Claim(ctx context.Context, now, leaseUntil time.Time) (*webhook.Delivery, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhook_deliveries", "Claim")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhook_deliveries", "Claim")()
+
+	collection := wd.db.Collection(wd.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wd.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	filter := bson.M{
+		"Status":        webhook.StatusPending,
+		"NextAttemptAt": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"NextAttemptAt": leaseUntil}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"NextAttemptAt": 1}).
+		SetReturnDocument(options.After)
+
+	var delivery webhook.Delivery
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+// Update saves the outcome of an attempt
+func (wd *webhookDeliveries) Update(ctx context.Context, delivery *webhook.Delivery) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhook_deliveries", "Update")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhook_deliveries", "Update")()
+
+	if delivery == nil {
+		return fmt.Errorf("webhook delivery cannot be nil")
+	}
+	collection := wd.db.Collection(wd.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wd.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	delivery.UpdatedTimestampUTC = time.Now()
+	updateDoc := bson.M{
+		"Status":              delivery.Status,
+		"Attempts":            delivery.Attempts,
+		"NextAttemptAt":       delivery.NextAttemptAt,
+		"UpdatedTimestampUTC": delivery.UpdatedTimestampUTC,
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{"$set": updateDoc})
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("webhook delivery not found with id: %v", delivery.ID)
+	}
+
+	return nil
+}
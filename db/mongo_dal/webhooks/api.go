@@ -0,0 +1,18 @@
+package webhooks_dal
+
+import (
+	"context"
+
+	"github.com/agent-auth/agent-auth-api/pkg/webhook"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// WebhooksDal defines the interface for webhook subscription database operations
+type WebhooksDal interface {
+	Create(ctx context.Context, subscription *webhook.Subscription) (*webhook.Subscription, error)
+	Get(ctx context.Context, id bson.ObjectID) (*webhook.Subscription, error)
+	List(ctx context.Context, workspaceID bson.ObjectID) ([]*webhook.Subscription, error)
+	ListActive(ctx context.Context, workspaceID bson.ObjectID) ([]*webhook.Subscription, error)
+	Update(ctx context.Context, subscription *webhook.Subscription) error
+	Delete(ctx context.Context, id bson.ObjectID) error
+}
@@ -0,0 +1,188 @@
+package webhooks_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/agent-auth/agent-auth-api/pkg/webhook"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type webhooks struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewWebhooksDal creates a new WebhooksDal instance
+func NewWebhooksDal() WebhooksDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &webhooks{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_WEBHOOKS_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// Create stores a new subscription
+func (wh *webhooks) Create(ctx context.Context, subscription *webhook.Subscription) (*webhook.Subscription, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhooks", "Create")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhooks", "Create")()
+
+	if subscription == nil {
+		return nil, fmt.Errorf("webhook subscription cannot be nil")
+	}
+	collection := wh.db.Collection(wh.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wh.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	subscription.CreatedTimestampUTC = now
+	subscription.UpdatedTimestampUTC = now
+
+	result, err := collection.InsertOne(ctx, subscription)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	subscription.ID = result.InsertedID.(bson.ObjectID)
+	return subscription, nil
+}
+
+// Get returns a subscription by ID, or nil if there is none
+func (wh *webhooks) Get(ctx context.Context, id bson.ObjectID) (*webhook.Subscription, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhooks", "Get")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhooks", "Get")()
+
+	collection := wh.db.Collection(wh.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wh.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	var subscription webhook.Subscription
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&subscription)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find webhook subscription: %w", err)
+	}
+
+	return &subscription, nil
+}
+
+// List returns every subscription of a workspace, oldest first
+func (wh *webhooks) List(ctx context.Context, workspaceID bson.ObjectID) ([]*webhook.Subscription, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhooks", "List")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhooks", "List")()
+
+	return wh.find(ctx, bson.M{"WorkspaceID": workspaceID})
+}
+
+// ListActive returns the enabled subscriptions of a workspace
+func (wh *webhooks) ListActive(ctx context.Context, workspaceID bson.ObjectID) ([]*webhook.Subscription, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhooks", "ListActive")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhooks", "ListActive")()
+
+	return wh.find(ctx, bson.M{"WorkspaceID": workspaceID, "Active": true})
+}
+
+func (wh *webhooks) find(ctx context.Context, filter bson.M) ([]*webhook.Subscription, error) {
+	collection := wh.db.Collection(wh.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wh.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"CreatedTimestampUTC": 1})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var subscriptions []*webhook.Subscription
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// Update saves the mutable fields of a subscription
+func (wh *webhooks) Update(ctx context.Context, subscription *webhook.Subscription) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhooks", "Update")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhooks", "Update")()
+
+	if subscription == nil {
+		return fmt.Errorf("webhook subscription cannot be nil")
+	}
+	collection := wh.db.Collection(wh.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wh.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	subscription.UpdatedTimestampUTC = time.Now()
+	updateDoc := bson.M{
+		"URL":                 subscription.URL,
+		"EventTypes":          subscription.EventTypes,
+		"Description":         subscription.Description,
+		"Active":              subscription.Active,
+		"UpdatedTimestampUTC": subscription.UpdatedTimestampUTC,
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": subscription.ID}, bson.M{"$set": updateDoc})
+	if err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("webhook subscription not found with id: %v", subscription.ID)
+	}
+
+	return nil
+}
+
+// Delete removes a subscription. Its pending deliveries are dead-lettered
+// when next attempted.
+func (wh *webhooks) Delete(ctx context.Context, id bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "webhooks", "Delete")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "webhooks", "Delete")()
+
+	collection := wh.db.Collection(wh.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(wh.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("webhook subscription not found with id: %v", id)
+	}
+
+	return nil
+}
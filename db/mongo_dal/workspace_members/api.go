@@ -0,0 +1,27 @@
+package workspace_members_dal
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// WorkspaceMember binds a user to a workspace with a workspace-scoped role
+type WorkspaceMember struct {
+	ID                  bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	WorkspaceID         bson.ObjectID `json:"workspace_id" bson:"WorkspaceID"`
+	Email               string        `json:"email" bson:"Email"`
+	Role                string        `json:"role" bson:"Role"`
+	CreatedTimestampUTC time.Time     `json:"created_timestamp_utc" bson:"CreatedTimestampUTC"`
+	UpdatedTimestampUTC time.Time     `json:"updated_timestamp_utc" bson:"UpdatedTimestampUTC"`
+}
+
+// WorkspaceMembersDal defines the interface for workspace membership database operations
+type WorkspaceMembersDal interface {
+	Upsert(ctx context.Context, workspaceID bson.ObjectID, email, role string) (*WorkspaceMember, error)
+	Get(ctx context.Context, workspaceID bson.ObjectID, email string) (*WorkspaceMember, error)
+	ListByWorkspace(ctx context.Context, workspaceID bson.ObjectID) ([]*WorkspaceMember, error)
+	ListWorkspaceIDs(ctx context.Context, email string) ([]bson.ObjectID, error)
+	Remove(ctx context.Context, workspaceID bson.ObjectID, email string) error
+}
@@ -0,0 +1,183 @@
+package workspace_members_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type workspaceMembers struct {
+	db                  *mongo.Database
+	collectionName      string
+	queryTimeoutSeconds int
+}
+
+// NewWorkspaceMembersDal creates a new WorkspaceMembersDal instance
+func NewWorkspaceMembersDal() WorkspaceMembersDal {
+	timeoutStr := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil {
+		timeout = 30 // default timeout
+	}
+
+	return &workspaceMembers{
+		db:                  mongodb.NewMongoClient(),
+		collectionName:      os.Getenv("DB_WORKSPACE_MEMBERS_COLLECTION"),
+		queryTimeoutSeconds: timeout,
+	}
+}
+
+// Upsert sets the role of a user in a workspace, adding the membership if needed
+func (m *workspaceMembers) Upsert(ctx context.Context, workspaceID bson.ObjectID, email, role string) (*WorkspaceMember, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspace_members", "Upsert")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspace_members", "Upsert")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"Role":                role,
+			"UpdatedTimestampUTC": now,
+		},
+		"$setOnInsert": bson.M{
+			"WorkspaceID":         workspaceID,
+			"Email":               email,
+			"CreatedTimestampUTC": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var member WorkspaceMember
+	err := collection.FindOneAndUpdate(ctx, bson.M{"WorkspaceID": workspaceID, "Email": email}, update, opts).Decode(&member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert workspace member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// Get returns the membership of a user in a workspace, or nil if there is none
+func (m *workspaceMembers) Get(ctx context.Context, workspaceID bson.ObjectID, email string) (*WorkspaceMember, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspace_members", "Get")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspace_members", "Get")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	var member WorkspaceMember
+	err := collection.FindOne(ctx, bson.M{"WorkspaceID": workspaceID, "Email": email}).Decode(&member)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find workspace member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// ListByWorkspace returns every member of a workspace
+func (m *workspaceMembers) ListByWorkspace(ctx context.Context, workspaceID bson.ObjectID) ([]*WorkspaceMember, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspace_members", "ListByWorkspace")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspace_members", "ListByWorkspace")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"CreatedTimestampUTC": 1})
+
+	cursor, err := collection.Find(ctx, bson.M{"WorkspaceID": workspaceID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace members: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var members []*WorkspaceMember
+	if err = cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace members: %w", err)
+	}
+
+	return members, nil
+}
+
+// ListWorkspaceIDs returns the IDs of every workspace a user belongs to
+func (m *workspaceMembers) ListWorkspaceIDs(ctx context.Context, email string) ([]bson.ObjectID, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspace_members", "ListWorkspaceIDs")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspace_members", "ListWorkspaceIDs")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"WorkspaceID": 1})
+
+	cursor, err := collection.Find(ctx, bson.M{"Email": email}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member workspaces: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var members []*WorkspaceMember
+	if err = cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace members: %w", err)
+	}
+
+	ids := make([]bson.ObjectID, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.WorkspaceID)
+	}
+
+	return ids, nil
+}
+
+// Remove deletes the membership of a user in a workspace
+func (m *workspaceMembers) Remove(ctx context.Context, workspaceID bson.ObjectID, email string) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspace_members", "Remove")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspace_members", "Remove")()
+
+	collection := m.db.Collection(m.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(m.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"WorkspaceID": workspaceID, "Email": email})
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("workspace member not found: %v", email)
+	}
+
+	return nil
+}
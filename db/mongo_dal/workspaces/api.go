@@ -1,20 +1,24 @@
 package workspaces_dal
 
 import (
+	"context"
+
 	"github.com/agent-auth/common-lib/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // WorkspaceDal defines the interface for workspace database operations
 type WorkspaceDal interface {
-	Create(workspace *models.Workspace) (*models.Workspace, error)
-	Update(workspace *models.Workspace) error
-	GetByID(id bson.ObjectID) (*models.Workspace, error)
-	Delete(id bson.ObjectID) error
-	List(skip, limit int64) ([]*models.Workspace, error)
-	GetBySlug(slug string) (*models.Workspace, error)
-	GetByOwnerID(ownerID bson.ObjectID) ([]*models.Workspace, error)
-	AddMember(workspaceID string, memberID string) error
-	RemoveMember(workspaceID string, memberID string) error
-	IsMember(workspaceID, email string) (bool, error)
+	Create(ctx context.Context, workspace *models.Workspace) (*models.Workspace, error)
+	Update(ctx context.Context, workspace *models.Workspace) error
+	GetByID(ctx context.Context, id bson.ObjectID) (*models.Workspace, error)
+	Delete(ctx context.Context, id bson.ObjectID) error
+	List(ctx context.Context, skip, limit int64) ([]*models.Workspace, error)
+	ListByIDs(ctx context.Context, ids []bson.ObjectID, skip, limit int64) ([]*models.Workspace, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Workspace, error)
+	GetByOwnerID(ctx context.Context, ownerID bson.ObjectID) ([]*models.Workspace, error)
+	AddMember(ctx context.Context, workspaceID string, memberID string) error
+	RemoveMember(ctx context.Context, workspaceID string, memberID string) error
+	IsMember(ctx context.Context, workspaceID, email string) (bool, error)
+	SetOwner(ctx context.Context, workspaceID bson.ObjectID, email string, entry models.AuditLog) error
 }
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/agent-auth/agent-auth-api/db/mongodb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 	"github.com/agent-auth/common-lib/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -36,10 +38,13 @@ func NewWorkspaceDal() WorkspaceDal {
 }
 
 // Delete soft deletes a workspace by setting its deleted flag to true
-func (w *workspaces) Delete(id bson.ObjectID) error {
+func (w *workspaces) Delete(ctx context.Context, id bson.ObjectID) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "Delete")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "Delete")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -63,10 +68,13 @@ func (w *workspaces) Delete(id bson.ObjectID) error {
 }
 
 // List retrieves workspaces with pagination
-func (w *workspaces) List(skip, limit int64) ([]*models.Workspace, error) {
+func (w *workspaces) List(ctx context.Context, skip, limit int64) ([]*models.Workspace, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "List")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "List")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -90,11 +98,45 @@ func (w *workspaces) List(skip, limit int64) ([]*models.Workspace, error) {
 	return workspaces, nil
 }
 
+// ListByIDs retrieves the given workspaces with pagination
+func (w *workspaces) ListByIDs(ctx context.Context, ids []bson.ObjectID, skip, limit int64) ([]*models.Workspace, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "ListByIDs")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "ListByIDs")()
+
+	collection := w.db.Collection(w.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(w.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	opts := options.Find().
+		SetSkip(skip).
+		SetLimit(limit).
+		SetSort(bson.M{"CreatedTimestampUTC": -1}) // Sort by creation date, newest first
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "Deleted": false}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var workspaces []*models.Workspace
+	if err = cursor.All(ctx, &workspaces); err != nil {
+		return nil, fmt.Errorf("failed to decode workspaces: %v", err)
+	}
+
+	return workspaces, nil
+}
+
 // GetBySlug retrieves a workspace by its slug
-func (w *workspaces) GetBySlug(slug string) (*models.Workspace, error) {
+func (w *workspaces) GetBySlug(ctx context.Context, slug string) (*models.Workspace, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "GetBySlug")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "GetBySlug")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -108,10 +150,13 @@ func (w *workspaces) GetBySlug(slug string) (*models.Workspace, error) {
 }
 
 // GetByOwnerID retrieves all workspaces owned by a specific user
-func (w *workspaces) GetByOwnerID(ownerID bson.ObjectID) ([]*models.Workspace, error) {
+func (w *workspaces) GetByOwnerID(ctx context.Context, ownerID bson.ObjectID) ([]*models.Workspace, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "GetByOwnerID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "GetByOwnerID")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -131,14 +176,22 @@ func (w *workspaces) GetByOwnerID(ownerID bson.ObjectID) ([]*models.Workspace, e
 }
 
 // AddMember adds a member to a workspace
-func (w *workspaces) AddMember(workspaceID string, memberID string) error {
+func (w *workspaces) AddMember(ctx context.Context, workspaceID string, memberID string) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "AddMember")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "AddMember")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
 
+	id, err := bson.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return fmt.Errorf("invalid workspace id: %v", err)
+	}
+
 	update := bson.M{
 		"$addToSet": bson.M{"Members": memberID},
 		"$set": bson.M{
@@ -146,7 +199,7 @@ func (w *workspaces) AddMember(workspaceID string, memberID string) error {
 		},
 	}
 
-	result, err := collection.UpdateOne(ctx, bson.M{"_id": workspaceID}, update)
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
 	if err != nil {
 		return fmt.Errorf("failed to add member to workspace: %v", err)
 	}
@@ -158,14 +211,22 @@ func (w *workspaces) AddMember(workspaceID string, memberID string) error {
 }
 
 // RemoveMember removes a member from a workspace
-func (w *workspaces) RemoveMember(workspaceID string, memberID string) error {
+func (w *workspaces) RemoveMember(ctx context.Context, workspaceID string, memberID string) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "RemoveMember")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "RemoveMember")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
 
+	id, err := bson.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return fmt.Errorf("invalid workspace id: %v", err)
+	}
+
 	update := bson.M{
 		"$pull": bson.M{"Members": memberID},
 		"$set": bson.M{
@@ -173,7 +234,7 @@ func (w *workspaces) RemoveMember(workspaceID string, memberID string) error {
 		},
 	}
 
-	result, err := collection.UpdateOne(ctx, bson.M{"_id": workspaceID}, update)
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
 	if err != nil {
 		return fmt.Errorf("failed to remove member from workspace: %v", err)
 	}
@@ -185,10 +246,13 @@ func (w *workspaces) RemoveMember(workspaceID string, memberID string) error {
 }
 
 // Update method needs to be modified to handle more fields
-func (w *workspaces) Update(workspace *models.Workspace) error {
+func (w *workspaces) Update(ctx context.Context, workspace *models.Workspace) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "Update")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "Update")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -216,10 +280,13 @@ func (w *workspaces) Update(workspace *models.Workspace) error {
 }
 
 // Create creates a new workspace
-func (w *workspaces) Create(workspace *models.Workspace) (*models.Workspace, error) {
+func (w *workspaces) Create(ctx context.Context, workspace *models.Workspace) (*models.Workspace, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "Create")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "Create")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -239,10 +306,13 @@ func (w *workspaces) Create(workspace *models.Workspace) (*models.Workspace, err
 }
 
 // GetByID retrieves a workspace by its ID
-func (w *workspaces) GetByID(id bson.ObjectID) (*models.Workspace, error) {
+func (w *workspaces) GetByID(ctx context.Context, id bson.ObjectID) (*models.Workspace, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "GetByID")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "GetByID")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
@@ -256,17 +326,25 @@ func (w *workspaces) GetByID(id bson.ObjectID) (*models.Workspace, error) {
 }
 
 // IsMember checks if the given email is a member of the specified project
-func (w *workspaces) IsMember(workspaceID, email string) (bool, error) {
+func (w *workspaces) IsMember(ctx context.Context, workspaceID, email string) (bool, error) {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "IsMember")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "IsMember")()
+
 	collection := w.db.Collection(w.collectionName)
 	ctx, cancel := context.WithTimeout(
-		context.Background(),
+		ctx,
 		time.Duration(w.queryTimeoutSeconds)*time.Second,
 	)
 	defer cancel()
 
+	id, err := bson.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("invalid workspace id: %v", err)
+	}
+
 	// Find project members where email matches
 	count, err := collection.CountDocuments(ctx, bson.M{
-		"_id":     workspaceID,
+		"_id":     id,
 		"Members": email,
 	})
 	if err != nil {
@@ -275,3 +353,39 @@ func (w *workspaces) IsMember(workspaceID, email string) (bool, error) {
 
 	return count > 0, nil
 }
+
+// SetOwner records email as the owner of a workspace and appends entry to its
+// audit log
+func (w *workspaces) SetOwner(ctx context.Context, workspaceID bson.ObjectID, email string, entry models.AuditLog) error {
+	defer metrics.ObserveOperation(metrics.StoreMongo, "workspaces", "SetOwner")()
+	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "workspaces", "SetOwner")()
+
+	if email == "" {
+		return fmt.Errorf("owner cannot be empty")
+	}
+	collection := w.db.Collection(w.collectionName)
+	ctx, cancel := context.WithTimeout(
+		ctx,
+		time.Duration(w.queryTimeoutSeconds)*time.Second,
+	)
+	defer cancel()
+
+	update := bson.M{
+		"$addToSet": bson.M{"Members": email},
+		"$push":     bson.M{"AuditLogs": entry},
+		"$set": bson.M{
+			"OwnerID":             email,
+			"UpdatedTimestampUTC": time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": workspaceID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set workspace owner: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("workspace not found with id: %v", workspaceID)
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package redis_dal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	role_bindings_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/role_bindings"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	bindingsKeyPrefix        = "bindings:"
+	bindingsVersionKeyPrefix = "sync:bindings:version:"
+)
+
+// projectBindings is the Redis snapshot of who holds which roles in a
+// project. Like a roles snapshot, Version is the latest UpdatedTimestampUTC
+// of the project's bindings, deleted ones included, in Unix milliseconds.
+type projectBindings struct {
+	ProjectID string              `json:"project_id"`
+	Version   int64               `json:"version"`
+	SyncedAt  time.Time           `json:"synced_at"`
+	Subjects  map[string][]string `json:"subjects"`
+}
+
+func (s *projectBindings) objectID() bson.ObjectID {
+	id, _ := bson.ObjectIDFromHex(s.ProjectID)
+	return id
+}
+
+// buildBindingSnapshots groups bindings by project. Deleted bindings only
+// count towards the version.
+func buildBindingSnapshots(bindings []role_bindings_dal.RoleBinding) map[string]*projectBindings {
+	snapshots := make(map[string]*projectBindings)
+
+	for _, binding := range bindings {
+		projectID := binding.ProjectID.Hex()
+
+		snapshot, exists := snapshots[projectID]
+		if !exists {
+			snapshot = &projectBindings{
+				ProjectID: projectID,
+				Subjects:  make(map[string][]string),
+			}
+			snapshots[projectID] = snapshot
+		}
+
+		if version := binding.UpdatedTimestampUTC.UnixMilli(); version > snapshot.Version {
+			snapshot.Version = version
+		}
+		if binding.Deleted {
+			continue
+		}
+		snapshot.Subjects[binding.Subject] = append(snapshot.Subjects[binding.Subject], binding.Role)
+	}
+
+	for _, snapshot := range snapshots {
+		for _, roles := range snapshot.Subjects {
+			sort.Strings(roles)
+		}
+	}
+
+	return snapshots
+}
+
+// findBindings returns the bindings matching filter, skipping undecodable
+// documents
+func (r *redis_roles_dal) findBindings(ctx context.Context, filter bson.M) ([]role_bindings_dal.RoleBinding, error) {
+	cursor, err := r.mongo.Collection(r.bindingsCollectionName).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bindings []role_bindings_dal.RoleBinding
+	for cursor.Next(ctx) {
+		var binding role_bindings_dal.RoleBinding
+		if err := cursor.Decode(&binding); err != nil {
+			r.logger.Error("Failed to decode role binding", zap.Error(err))
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, cursor.Err()
+}
+
+// syncBindings rebuilds the bindings snapshot of every project with a binding
+// changed after since
+func (r *redis_roles_dal) syncBindings(ctx context.Context, since time.Time) error {
+	var projectIDs []bson.ObjectID
+	err := r.mongo.Collection(r.bindingsCollectionName).Distinct(ctx, "ProjectID", bson.M{
+		"UpdatedTimestampUTC": bson.M{"$gt": since},
+	}).Decode(&projectIDs)
+	if err != nil {
+		return fmt.Errorf("failed to find changed role bindings: %v", err)
+	}
+
+	for _, projectID := range projectIDs {
+		if err := r.storeProjectBindings(ctx, projectID, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storeProjectBindings rebuilds the bindings snapshot of one project from all
+// of its bindings. A project left without any binding document loses its
+// snapshot. Unless force is set, a newer stored snapshot is kept.
+func (r *redis_roles_dal) storeProjectBindings(ctx context.Context, projectID bson.ObjectID, force bool) error {
+	ctx, span := tracing.Start(ctx, "rolesync.store_project_bindings",
+		attribute.String("project_id", projectID.Hex()),
+		attribute.Bool("force", force))
+	defer span.End()
+
+	bindings, err := r.findBindings(ctx, bson.M{"ProjectID": projectID})
+	if err != nil {
+		return fmt.Errorf("failed to fetch role bindings: %w", err)
+	}
+
+	snapshot, exists := buildBindingSnapshots(bindings)[projectID.Hex()]
+	if !exists {
+		return r.deleteVersioned(ctx, bindingsKeyPrefix+projectID.Hex(), bindingsVersionKeyPrefix+projectID.Hex())
+	}
+
+	snapshot.SyncedAt = time.Now().UTC()
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	written, err := r.writeVersioned(ctx,
+		bindingsKeyPrefix+snapshot.ProjectID, bindingsVersionKeyPrefix+snapshot.ProjectID,
+		snapshot.Version, data, force)
+	if err != nil {
+		r.logger.Error("Failed to store role bindings in Redis",
+			zap.String("projectID", snapshot.ProjectID),
+			zap.Error(err))
+		return fmt.Errorf("failed to store bindings snapshot: %w", err)
+	}
+
+	if !written {
+		r.logger.Info("Skipped bindings snapshot older than the stored one",
+			zap.String("projectID", snapshot.ProjectID),
+			zap.Int64("version", snapshot.Version))
+	}
+	return nil
+}
+
+// readBindingsSnapshot returns the stored bindings snapshot of a project, or
+// nil when there is none or it cannot be decoded
+func (r *redis_roles_dal) readBindingsSnapshot(ctx context.Context, projectID string) (*projectBindings, error) {
+	data, err := r.redis.Get(ctx, bindingsKeyPrefix+projectID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bindings snapshot: %w", err)
+	}
+
+	var snapshot projectBindings
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		r.logger.Warn("Undecodable bindings snapshot", zap.String("projectID", projectID), zap.Error(err))
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+// reconcileBindings does for bindings snapshots what Reconcile does for
+// roles snapshots, adding its repairs to report
+func (r *redis_roles_dal) reconcileBindings(ctx context.Context, report *ReconcileReport) error {
+	bindings, err := r.findBindings(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch role bindings: %w", err)
+	}
+	expected := buildBindingSnapshots(bindings)
+
+	report.BindingProjects = len(expected)
+	for projectID, snapshot := range expected {
+		current, err := r.readBindingsSnapshot(ctx, projectID)
+		if err != nil {
+			return err
+		}
+		if current != nil && current.Version == snapshot.Version && bindingsEqual(current, snapshot) {
+			continue
+		}
+
+		r.logger.Warn("Repairing drifted bindings snapshot",
+			zap.String("projectID", projectID),
+			zap.Bool("missing", current == nil),
+			zap.Int64("version", snapshot.Version))
+		if err := r.storeProjectBindings(ctx, snapshot.objectID(), true); err != nil {
+			return err
+		}
+		report.BindingsRepaired++
+	}
+
+	var orphans []string
+	iter := r.redis.Scan(ctx, 0, bindingsKeyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if projectID := strings.TrimPrefix(iter.Val(), bindingsKeyPrefix); expected[projectID] == nil {
+			orphans = append(orphans, projectID)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to list bindings snapshots: %w", err)
+	}
+
+	for _, projectID := range orphans {
+		id, err := bson.ObjectIDFromHex(projectID)
+		if err != nil {
+			r.logger.Warn("Removing bindings snapshot with invalid project ID", zap.String("projectID", projectID))
+			if err := r.deleteVersioned(ctx, bindingsKeyPrefix+projectID, bindingsVersionKeyPrefix+projectID); err != nil {
+				return err
+			}
+			report.BindingsRemoved++
+			continue
+		}
+
+		r.logger.Warn("Removing bindings snapshot of project without bindings", zap.String("projectID", projectID))
+		if err := r.storeProjectBindings(ctx, id, true); err != nil {
+			return err
+		}
+		report.BindingsRemoved++
+	}
+
+	return nil
+}
+
+// bindingsEqual compares the subjects of two bindings snapshots as they are
+// stored
+func bindingsEqual(a, b *projectBindings) bool {
+	aData, aErr := json.Marshal(a.Subjects)
+	bData, bErr := json.Marshal(b.Subjects)
+	return aErr == nil && bErr == nil && bytes.Equal(aData, bData)
+}
+
+// GetProjectBindings returns the roles held by each subject of a project from
+// its Redis snapshot. It returns nil when the project has no snapshot.
+func (r *redis_roles_dal) GetProjectBindings(ctx context.Context, projectID string) (map[string][]string, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "roles", "GetProjectBindings")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "roles", "GetProjectBindings")()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+	defer cancel()
+
+	data, err := r.redis.Get(ctx, bindingsKeyPrefix+projectID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project role bindings: %w", err)
+	}
+
+	var snapshot projectBindings
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode project role bindings: %w", err)
+	}
+
+	return snapshot.Subjects, nil
+}
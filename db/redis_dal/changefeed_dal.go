@@ -0,0 +1,211 @@
+package redis_dal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/redisdb"
+	"github.com/agent-auth/agent-auth-api/pkg/changefeed"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	changefeedKeyPrefix = "changefeed:project:"
+	changefeedChannel   = "changefeed:events"
+
+	// changefeedHistoryTTL drops the history of projects that stopped changing
+	changefeedHistoryTTL = 7 * 24 * time.Hour
+)
+
+// redis_changefeed_dal keeps each project's recent changes in a Redis stream,
+// whose entry IDs order the events, and relays new ones over pub/sub
+type redis_changefeed_dal struct {
+	logger         *zap.Logger
+	redis          *redis.Client
+	timeoutSeconds int
+	historyLength  int64
+}
+
+// NewRedisChangefeedDal returns new instance of datastore
+func NewRedisChangefeedDal() changefeed.Store {
+	redisQueryTimeout, err := strconv.Atoi(os.Getenv("REDIS_QUERY_TIMEOUT_SECONDS"))
+	if err != nil {
+		redisQueryTimeout = 30
+	}
+
+	// Watchers disconnected for longer than this many changes must resync
+	historyLength, err := strconv.ParseInt(os.Getenv("CHANGEFEED_HISTORY_LENGTH"), 10, 64)
+	if err != nil || historyLength <= 0 {
+		historyLength = 1000
+	}
+
+	return &redis_changefeed_dal{
+		logger:         logger.NewLogger(),
+		redis:          redisdb.NewRedisClient(),
+		timeoutSeconds: redisQueryTimeout,
+		historyLength:  historyLength,
+	}
+}
+
+func (r *redis_changefeed_dal) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+}
+
+// Append adds event to its project's stream, which assigns its ID, and
+// publishes it to every replica
+func (r *redis_changefeed_dal) Append(ctx context.Context, event *changefeed.Event) error {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "changefeed", "Append")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "changefeed", "Append")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	key := changefeedKeyPrefix + event.ProjectID
+	id, err := r.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: r.historyLength,
+		Approx: true,
+		ID:     "*",
+		Values: map[string]interface{}{"event": data},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to append project change: %w", err)
+	}
+	if err := r.redis.Expire(ctx, key, changefeedHistoryTTL).Err(); err != nil {
+		r.logger.Error("Failed to set project change history expiry", zap.String("projectID", event.ProjectID), zap.Error(err))
+	}
+
+	event.ID = id
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := r.redis.Publish(ctx, changefeedChannel, payload).Err(); err != nil {
+		// Watchers receive the change from the history when they reconnect
+		r.logger.Error("Failed to publish project change", zap.String("projectID", event.ProjectID), zap.Error(err))
+	}
+
+	return nil
+}
+
+// Replay returns the events of a project after the given ID. The history is
+// incomplete when its oldest entry is newer than that ID, since trimming may
+// have dropped events in between.
+func (r *redis_changefeed_dal) Replay(ctx context.Context, projectID, after string) ([]*changefeed.Event, bool, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "changefeed", "Replay")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "changefeed", "Replay")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	key := changefeedKeyPrefix + projectID
+
+	oldest, err := r.redis.XRangeN(ctx, key, "-", "+", 1).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read project change history: %w", err)
+	}
+	if len(oldest) == 0 {
+		// The history expired, so whatever followed after is gone
+		return nil, false, nil
+	}
+	complete := changefeed.CompareIDs(oldest[0].ID, after) <= 0
+
+	// The range is inclusive; the entry with ID after was already seen
+	messages, err := r.redis.XRange(ctx, key, after, "+").Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read project change history: %w", err)
+	}
+
+	events := make([]*changefeed.Event, 0, len(messages))
+	for _, message := range messages {
+		if message.ID == after {
+			continue
+		}
+
+		data, _ := message.Values["event"].(string)
+		var event changefeed.Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			r.logger.Error("Failed to decode project change", zap.String("id", message.ID), zap.Error(err))
+			continue
+		}
+		event.ID = message.ID
+		events = append(events, &event)
+	}
+
+	return events, complete, nil
+}
+
+// Latest returns the ID of the newest entry in a project's stream
+func (r *redis_changefeed_dal) Latest(ctx context.Context, projectID string) (string, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "changefeed", "Latest")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "changefeed", "Latest")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	newest, err := r.redis.XRevRangeN(ctx, changefeedKeyPrefix+projectID, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read project change history: %w", err)
+	}
+	if len(newest) == 0 {
+		return "", nil
+	}
+	return newest[0].ID, nil
+}
+
+// Subscribe streams the project changes published by any replica. The
+// channel is closed when ctx is done or the subscription fails.
+func (r *redis_changefeed_dal) Subscribe(ctx context.Context) (<-chan *changefeed.Event, error) {
+	pubsub := r.redis.Subscribe(ctx, changefeedChannel)
+
+	// Wait for the subscription to be confirmed so no event is missed afterwards
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to project changes: %w", err)
+	}
+
+	events := make(chan *changefeed.Event)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var event changefeed.Event
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					r.logger.Error("Failed to decode project change", zap.Error(err))
+					continue
+				}
+
+				select {
+				case events <- &event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,120 @@
+package redis_dal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/redisdb"
+	"github.com/agent-auth/agent-auth-api/pkg/leader"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/go-redis/redis/v8"
+)
+
+// renewLeaseScript extends a lease only for its current holder
+var renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeaseScript frees a lease only for its current holder
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// redis_lease_dal stores leases as keys holding the holder's name, which
+// expire with the lease
+type redis_lease_dal struct {
+	redis          *redis.Client
+	timeoutSeconds int
+}
+
+// NewRedisLeaseDal returns new instance of datastore
+func NewRedisLeaseDal() leader.LeaseStore {
+	redisQueryTimeout, err := strconv.Atoi(os.Getenv("REDIS_QUERY_TIMEOUT_SECONDS"))
+	if err != nil {
+		redisQueryTimeout = 30
+	}
+
+	return &redis_lease_dal{
+		redis:          redisdb.NewRedisClient(),
+		timeoutSeconds: redisQueryTimeout,
+	}
+}
+
+func (r *redis_lease_dal) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+}
+
+// Acquire takes the lease if it is free
+func (r *redis_lease_dal) Acquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "leases", "Acquire")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "leases", "Acquire")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	acquired, err := r.redis.SetNX(ctx, key, holder, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return acquired, nil
+}
+
+// Renew extends the lease if holder still has it
+func (r *redis_lease_dal) Renew(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "leases", "Renew")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "leases", "Renew")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	renewed, err := renewLeaseScript.Run(ctx, r.redis, []string{key}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// Release frees the lease if holder still has it
+func (r *redis_lease_dal) Release(ctx context.Context, key, holder string) error {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "leases", "Release")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "leases", "Release")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if err := releaseLeaseScript.Run(ctx, r.redis, []string{key}, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+// Holder returns who holds the lease and for how much longer
+func (r *redis_lease_dal) Holder(ctx context.Context, key string) (string, time.Duration, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "leases", "Holder")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "leases", "Holder")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pipe := r.redis.Pipeline()
+	holder := pipe.Get(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return "", 0, fmt.Errorf("failed to read lease: %w", err)
+	}
+
+	if holder.Err() == redis.Nil {
+		return "", 0, nil
+	}
+	return holder.Val(), ttl.Val(), nil
+}
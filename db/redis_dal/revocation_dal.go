@@ -0,0 +1,271 @@
+package redis_dal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/db/redisdb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/revocation"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	revokedTokenKeyPrefix   = "revocation:jti:"
+	revokedSubjectKeyPrefix = "revocation:subject:"
+	revocationChannel       = "revocation:events"
+)
+
+// revokeSubjectScript only ever moves a subject's cutoff forward, so a stale
+// request cannot shorten an earlier "revoke all" marker
+var revokeSubjectScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tonumber(ARGV[1]) > current then
+	redis.call("SET", KEYS[1], ARGV[1], "EX", ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// redis_revocation_dal stores revoked jtis and subject cutoffs in Redis
+type redis_revocation_dal struct {
+	logger                  *zap.Logger
+	redis                   *redis.Client
+	timeoutSeconds          int
+	maxTokenLifetimeSeconds int
+}
+
+// NewRedisRevocationDal returns new instance of datastore
+func NewRedisRevocationDal() revocation.Store {
+	redisQueryTimeout, err := strconv.Atoi(os.Getenv("REDIS_QUERY_TIMEOUT_SECONDS"))
+	if err != nil {
+		redisQueryTimeout = 30
+	}
+
+	// Subject markers only matter while tokens issued before them can still be valid
+	maxTokenLifetime, err := strconv.Atoi(os.Getenv("API_AUTH_MAX_TOKEN_LIFETIME_SECONDS"))
+	if err != nil || maxTokenLifetime <= 0 {
+		maxTokenLifetime = 86400
+	}
+
+	return &redis_revocation_dal{
+		logger:                  logger.NewLogger(),
+		redis:                   redisdb.NewRedisClient(),
+		timeoutSeconds:          redisQueryTimeout,
+		maxTokenLifetimeSeconds: maxTokenLifetime,
+	}
+}
+
+func (r *redis_revocation_dal) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+}
+
+// RevokeToken stores a jti until the token it belongs to expires
+func (r *redis_revocation_dal) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "revocations", "RevokeToken")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "revocations", "RevokeToken")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	ttl := time.Until(expiresAt)
+	if expiresAt.IsZero() || ttl > time.Duration(r.maxTokenLifetimeSeconds)*time.Second {
+		ttl = time.Duration(r.maxTokenLifetimeSeconds) * time.Second
+	}
+	if ttl <= 0 {
+		// The token has already expired, nothing to deny
+		return nil
+	}
+
+	if err := r.redis.Set(ctx, revokedTokenKeyPrefix+jti, expiresAt.Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return r.publish(ctx, revocation.Event{Type: revocation.EventTokenRevoked, JTI: jti})
+}
+
+// RevokeSubject stores a cutoff before which all tokens issued by issuer to
+// subject are revoked
+func (r *redis_revocation_dal) RevokeSubject(ctx context.Context, issuer, subject string, before time.Time) error {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "revocations", "RevokeSubject")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "revocations", "RevokeSubject")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := revokeSubjectScript.Run(ctx, r.redis,
+		[]string{revokedSubjectKeyPrefix + revocation.SubjectKey(issuer, subject)},
+		before.Unix(), r.maxTokenLifetimeSeconds,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to revoke subject: %w", err)
+	}
+
+	return r.publish(ctx, revocation.Event{Type: revocation.EventSubjectRevoked, Issuer: issuer, Subject: subject, Before: before})
+}
+
+// IsTokenRevoked reports whether a jti has been revoked
+func (r *redis_revocation_dal) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "revocations", "IsTokenRevoked")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "revocations", "IsTokenRevoked")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	count, err := r.redis.Exists(ctx, revokedTokenKeyPrefix+jti).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// GetSubjectRevocation returns the revocation cutoff of an issuer's subject, if any
+func (r *redis_revocation_dal) GetSubjectRevocation(ctx context.Context, issuer, subject string) (time.Time, bool, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "revocations", "GetSubjectRevocation")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "revocations", "GetSubjectRevocation")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	value, err := r.redis.Get(ctx, revokedSubjectKeyPrefix+revocation.SubjectKey(issuer, subject)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to check subject revocation: %w", err)
+	}
+
+	return time.Unix(value, 0), true, nil
+}
+
+// ListRevokedTokens returns every jti that is currently revoked
+func (r *redis_revocation_dal) ListRevokedTokens(ctx context.Context) ([]string, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "revocations", "ListRevokedTokens")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "revocations", "ListRevokedTokens")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var jtis []string
+	iter := r.redis.Scan(ctx, 0, revokedTokenKeyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		jtis = append(jtis, strings.TrimPrefix(iter.Val(), revokedTokenKeyPrefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list revoked tokens: %w", err)
+	}
+
+	return jtis, nil
+}
+
+// ListRevokedSubjects returns every subject cutoff that is currently stored,
+// keyed by revocation.SubjectKey
+func (r *redis_revocation_dal) ListRevokedSubjects(ctx context.Context) (map[string]time.Time, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "revocations", "ListRevokedSubjects")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "revocations", "ListRevokedSubjects")()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var keys []string
+	iter := r.redis.Scan(ctx, 0, revokedSubjectKeyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list revoked subjects: %w", err)
+	}
+
+	subjects := make(map[string]time.Time, len(keys))
+	if len(keys) == 0 {
+		return subjects, nil
+	}
+
+	values, err := r.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revoked subjects: %w", err)
+	}
+
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			// Expired between SCAN and MGET
+			continue
+		}
+		seconds, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			r.logger.Error("Invalid subject revocation marker", zap.String("key", keys[i]), zap.Error(err))
+			continue
+		}
+		subjects[strings.TrimPrefix(keys[i], revokedSubjectKeyPrefix)] = time.Unix(seconds, 0)
+	}
+
+	return subjects, nil
+}
+
+// Subscribe streams revocation events published by any replica. The channel
+// is closed when ctx is done or the subscription fails.
+func (r *redis_revocation_dal) Subscribe(ctx context.Context) (<-chan revocation.Event, error) {
+	pubsub := r.redis.Subscribe(ctx, revocationChannel)
+
+	// Wait for the subscription to be confirmed so no event is missed afterwards
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to revocation events: %w", err)
+	}
+
+	events := make(chan revocation.Event)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var event revocation.Event
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					r.logger.Error("Failed to decode revocation event", zap.Error(err))
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func (r *redis_revocation_dal) publish(ctx context.Context, event revocation.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := r.redis.Publish(ctx, revocationChannel, payload).Err(); err != nil {
+		// Other replicas pick the revocation up on their next reload
+		r.logger.Error("Failed to publish revocation event", zap.Error(err))
+	}
+
+	return nil
+}
@@ -2,7 +2,7 @@ package redis_dal
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -10,6 +10,8 @@ import (
 
 	"github.com/agent-auth/agent-auth-api/db/mongodb"
 	"github.com/agent-auth/agent-auth-api/db/redisdb"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 	"github.com/agent-auth/common-lib/models"
 	"github.com/agent-auth/common-lib/pkg/logger"
 	"github.com/go-redis/redis/v8"
@@ -20,12 +22,14 @@ import (
 
 // RedisRolesDal ...
 type redis_roles_dal struct {
-	logger         *zap.Logger
-	mongo          *mongo.Database
-	redis          *redis.Client
-	collectionName string
-	timeoutSeconds int
-	syncInterval   int
+	logger                 *zap.Logger
+	mongo                  *mongo.Database
+	redis                  *redis.Client
+	collectionName         string
+	bindingsCollectionName string
+	timeoutSeconds         int
+	syncInterval           int
+	reconcileInterval      int
 }
 
 // NewRedisRolesDal returns new instance of datastore
@@ -43,55 +47,82 @@ func NewRedisRolesDal() *redis_roles_dal {
 		redisSyncInterval = 10
 	}
 
+	// Full reconciles repair whatever incremental syncs missed
+	redisReconcileInterval, err := strconv.Atoi(os.Getenv("REDIS_RECONCILE_INTERVAL"))
+	if err != nil || redisReconcileInterval <= 0 {
+		redisReconcileInterval = 300
+	}
+
 	rolesCollectionName := os.Getenv("DB_ROLES_COLLECTION")
 	if rolesCollectionName == "" {
 		l.Fatal("DB_ROLES_COLLECTION is not set")
 	}
 
+	bindingsCollectionName := os.Getenv("DB_ROLE_BINDINGS_COLLECTION")
+	if bindingsCollectionName == "" {
+		l.Fatal("DB_ROLE_BINDINGS_COLLECTION is not set")
+	}
+
 	return &redis_roles_dal{
-		logger:         logger.NewLogger(),
-		mongo:          mongodb.NewMongoClient(),
-		redis:          redisdb.NewRedisClient(),
-		collectionName: rolesCollectionName,
-		timeoutSeconds: redisQueryTimeout,
-		syncInterval:   redisSyncInterval,
+		logger:                 logger.NewLogger(),
+		mongo:                  mongodb.NewMongoClient(),
+		redis:                  redisdb.NewRedisClient(),
+		collectionName:         rolesCollectionName,
+		bindingsCollectionName: bindingsCollectionName,
+		timeoutSeconds:         redisQueryTimeout,
+		syncInterval:           redisSyncInterval,
+		reconcileInterval:      redisReconcileInterval,
 	}
 }
 
-func (r *redis_roles_dal) InitialSync() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.timeoutSeconds)*time.Second)
+// InitialSync rebuilds every roles and bindings snapshot. It stops once ctx
+// is done, as when the syncer loses its lease. Like the periodic reconcile it
+// may take up to REDIS_RECONCILE_INTERVAL, rather than the timeout of a
+// single query.
+func (r *redis_roles_dal) InitialSync(ctx context.Context) error {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "roles", "InitialSync")()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.reconcileInterval)*time.Second)
 	defer cancel()
 
-	var roles []models.Roles
-	cursor, err := r.mongo.Collection(r.collectionName).Find(ctx, bson.M{
-		"Deleted": bson.M{"$ne": true},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to fetch initial data: %v", err)
-	}
-	defer cursor.Close(ctx)
+	ctx, span := tracing.Start(ctx, "rolesync.initial_sync")
+	defer span.End()
 
-	// Collect all roles
-	for cursor.Next(ctx) {
-		var role models.Roles
-		if err := cursor.Decode(&role); err != nil {
-			r.logger.Error("Failed to decode document during initial sync", zap.Error(err))
-			continue
-		}
-		roles = append(roles, role)
-	}
-
-	projectRoles := r.transformRolesToProjectMap(roles)
-	if err := r.storeProjectRolesInRedis(ctx, projectRoles); err != nil {
+	report, err := r.Reconcile(ctx)
+	if err != nil {
 		return fmt.Errorf("failed to store roles in Redis: %v", err)
 	}
-
+	r.logger.Info("Synced roles and bindings snapshots",
+		zap.Int("projects", report.Projects),
+		zap.Int("repaired", report.Repaired),
+		zap.Int("removed", report.Removed),
+		zap.Int("bindingProjects", report.BindingProjects),
+		zap.Int("bindingsRepaired", report.BindingsRepaired),
+		zap.Int("bindingsRemoved", report.BindingsRemoved))
+
+	r.recordSync(ctx)
 	r.logger.Info("Initial redis sync completed successfully")
 	return nil
 }
 
+// SyncRolesCollection keeps the Redis snapshots current until ctx is done. It
+// follows a MongoDB change stream, and polls instead when the deployment is
+// not a replica set and so has no change streams.
 func (r *redis_roles_dal) SyncRolesCollection(ctx context.Context) {
-	if err := r.InitialSync(); err != nil {
+	go r.reconcilePeriodically(ctx, time.Duration(r.reconcileInterval)*time.Second)
+
+	if err := r.streamChanges(ctx); errors.Is(err, errChangeStreamsUnsupported) {
+		r.logger.Info("MongoDB change streams are not supported, polling for role changes instead")
+		r.pollChanges(ctx)
+	}
+}
+
+// pollChanges syncs roles and bindings updated since the previous poll every
+// REDIS_SYNC_INTERVAL seconds
+func (r *redis_roles_dal) pollChanges(ctx context.Context) {
+	syncMode.Set(syncModePolling)
+
+	if err := r.InitialSync(ctx); err != nil {
 		r.logger.Error("Failed to perform initial sync", zap.Error(err))
 		return
 	}
@@ -100,6 +131,7 @@ func (r *redis_roles_dal) SyncRolesCollection(ctx context.Context) {
 	defer ticker.Stop()
 
 	lastSync := time.Now().UTC()
+	lastBindingsSync := lastSync
 
 	for {
 		select {
@@ -108,38 +140,34 @@ func (r *redis_roles_dal) SyncRolesCollection(ctx context.Context) {
 			return
 		case <-ticker.C:
 			syncCtx, cancel := context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
-			// Query for updated roles
-			filter := bson.M{
-				"UpdatedTimestampUTC": bson.M{"$gt": lastSync},
-				"Deleted":             bson.M{"$ne": true},
-			}
+			syncCtx, span := tracing.Start(syncCtx, "rolesync.poll")
 
-			var roles []models.Roles
-			cursor, err := r.mongo.Collection(r.collectionName).Find(syncCtx, filter)
-			if err != nil {
-				r.logger.Error("Failed to poll for changes", zap.Error(err))
-				cancel()
-				continue
-			}
-
-			// Collect all roles
-			for cursor.Next(syncCtx) {
-				var role models.Roles
-				if err := cursor.Decode(&role); err != nil {
-					r.logger.Error("Failed to decode document during polling", zap.Error(err))
-					continue
-				}
-				roles = append(roles, role)
+			// Deleted roles are included so their removal reaches Redis, and
+			// roles changed while syncing are picked up on the next tick
+			synced := true
+			syncStarted := time.Now().UTC()
+			if err := r.syncRoles(syncCtx, lastSync); err != nil {
+				r.logger.Error("Failed to store roles in Redis", zap.Error(err))
+				synced = false
+			} else {
+				// Changes are picked up at most one interval late
+				syncLagSeconds.Set(time.Since(lastSync).Seconds())
+				lastSync = syncStarted
 			}
-			cursor.Close(syncCtx)
 
-			projectRoles := r.transformRolesToProjectMap(roles)
-			if err := r.storeProjectRolesInRedis(syncCtx, projectRoles); err != nil {
-				r.logger.Error("Failed to store roles in Redis", zap.Error(err))
+			// Bindings changed while syncing are picked up on the next tick
+			bindingsSyncStarted := time.Now().UTC()
+			if err := r.syncBindings(syncCtx, lastBindingsSync); err != nil {
+				r.logger.Error("Failed to store role bindings in Redis", zap.Error(err))
+				synced = false
 			} else {
-				lastSync = time.Now().UTC()
+				lastBindingsSync = bindingsSyncStarted
 			}
 
+			if synced {
+				r.recordSync(syncCtx)
+			}
+			span.End()
 			cancel()
 
 			r.logger.Info("Roles collection sync completed successfully, looking for more changes")
@@ -147,58 +175,61 @@ func (r *redis_roles_dal) SyncRolesCollection(ctx context.Context) {
 	}
 }
 
-// New helper function to handle the transformation
-func (r *redis_roles_dal) transformRolesToProjectMap(roles []models.Roles) map[string]map[string]map[string]models.Permission {
-	projectRoles := make(map[string]map[string]map[string]models.Permission)
-
-	for _, role := range roles {
-		projectID := role.ProjectID.Hex()
-
-		// Initialize project map if it doesn't exist
-		if _, exists := projectRoles[projectID]; !exists {
-			projectRoles[projectID] = make(map[string]map[string]models.Permission)
-		}
-
-		// Initialize role map if it doesn't exist
-		if _, exists := projectRoles[projectID][role.Role]; !exists {
-			projectRoles[projectID][role.Role] = make(map[string]models.Permission)
-		}
+// findRoles returns the roles matching filter, skipping undecodable documents
+func (r *redis_roles_dal) findRoles(ctx context.Context, filter bson.M) ([]models.Roles, error) {
+	cursor, err := r.mongo.Collection(r.collectionName).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
-		// Add permissions to the role
-		for urn, permission := range role.Permissions {
-			projectRoles[projectID][role.Role][urn] = permission
+	var roles []models.Roles
+	for cursor.Next(ctx) {
+		var role models.Roles
+		if err := cursor.Decode(&role); err != nil {
+			r.logger.Error("Failed to decode role", zap.Error(err))
+			continue
 		}
+		roles = append(roles, role)
 	}
 
-	return projectRoles
+	return roles, cursor.Err()
 }
 
-// Helper function to store transformed data in Redis
-func (r *redis_roles_dal) storeProjectRolesInRedis(ctx context.Context, projectRoles map[string]map[string]map[string]models.Permission) error {
-	for projectID, roleData := range projectRoles {
-		transformedData := struct {
-			ProjectID   string                                  `json:"project_id"`
-			Permissions map[string]map[string]models.Permission `json:"permissions"`
-		}{
-			ProjectID:   projectID,
-			Permissions: roleData,
-		}
+// syncRoles rebuilds the snapshot of every project with a role changed after
+// since
+func (r *redis_roles_dal) syncRoles(ctx context.Context, since time.Time) error {
+	var projectIDs []bson.ObjectID
+	err := r.mongo.Collection(r.collectionName).Distinct(ctx, "ProjectID", bson.M{
+		"UpdatedTimestampUTC": bson.M{"$gt": since},
+	}).Decode(&projectIDs)
+	if err != nil {
+		return fmt.Errorf("failed to find changed roles: %v", err)
+	}
 
-		jsonData, err := json.Marshal(transformedData)
-		if err != nil {
-			r.logger.Error("Failed to marshal transformed data", zap.Error(err))
+	for _, projectID := range projectIDs {
+		if err := r.storeProjectRoles(ctx, projectID, false); err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
 
-		r.logger.Info("Storing transformed data in Redis", zap.String("projectID", fmt.Sprintf("roles:%s", projectID)))
+// GetProjectRoles returns the permissions of every role of a project from its
+// Redis snapshot, keyed by role and resource URN. It returns nil when the
+// project has no snapshot.
+func (r *redis_roles_dal) GetProjectRoles(ctx context.Context, projectID string) (map[string]map[string]models.Permission, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "roles", "GetProjectRoles")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "roles", "GetProjectRoles")()
 
-		err = r.redis.Set(ctx, fmt.Sprintf("roles:%s", projectID), jsonData, 0).Err()
-		if err != nil {
-			r.logger.Error("Failed to store transformed data in Redis",
-				zap.String("projectID", projectID),
-				zap.Error(err))
-			return err
-		}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+	defer cancel()
+
+	snapshot, err := r.readSnapshot(ctx, projectID)
+	if err != nil || snapshot == nil {
+		return nil, err
 	}
-	return nil
+
+	return snapshot.Permissions, nil
 }
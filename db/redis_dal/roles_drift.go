@@ -0,0 +1,266 @@
+package redis_dal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// How a project's Redis snapshot compares with its roles or bindings in
+// MongoDB
+const (
+	// ProjectSyncCurrent snapshots have the version of the latest change
+	ProjectSyncCurrent = "current"
+	// ProjectSyncStale snapshots have another version than MongoDB
+	ProjectSyncStale = "stale"
+	// ProjectSyncMissing projects have documents but no snapshot
+	ProjectSyncMissing = "missing"
+	// ProjectSyncOrphaned snapshots belong to projects without documents
+	ProjectSyncOrphaned = "orphaned"
+)
+
+// ProjectSyncState compares the roles and bindings of a project in MongoDB
+// with their Redis snapshots. Versions are update times in Unix
+// milliseconds, and a snapshot is current when both are equal.
+type ProjectSyncState struct {
+	ProjectID            string     `json:"project_id"`
+	State                string     `json:"state"`
+	MongoRoles           int        `json:"mongo_roles"`
+	MongoUpdatedUTC      *time.Time `json:"mongo_updated_utc,omitempty"`
+	MongoVersion         int64      `json:"mongo_version"`
+	RedisVersion         int64      `json:"redis_version"`
+	RedisSyncedAt        *time.Time `json:"redis_synced_at,omitempty"`
+	BindingsState        string     `json:"bindings_state"`
+	MongoBindings        int        `json:"mongo_bindings"`
+	MongoBindingsVersion int64      `json:"mongo_bindings_version"`
+	RedisBindingsVersion int64      `json:"redis_bindings_version"`
+}
+
+// InSync reports whether both snapshots of the project are current
+func (s *ProjectSyncState) InSync() bool {
+	return s.State == ProjectSyncCurrent && s.BindingsState == ProjectSyncCurrent
+}
+
+// SyncDriftReport compares every project, listing those whose roles or
+// bindings snapshot needs a resync
+type SyncDriftReport struct {
+	CheckedAt time.Time           `json:"checked_at"`
+	Projects  []*ProjectSyncState `json:"projects"`
+	Stale     []string            `json:"stale"`
+	Missing   []string            `json:"missing"`
+	Orphaned  []string            `json:"orphaned"`
+}
+
+// projectSummary is a project's roles or bindings as counted in MongoDB.
+// Deleted documents are not counted but still date the project, as in its
+// snapshot.
+type projectSummary struct {
+	ProjectID bson.ObjectID `bson:"_id"`
+	Count     int           `bson:"count"`
+	Latest    time.Time     `bson:"latest"`
+}
+
+// summarise counts the documents of collection matching filter per project,
+// without reading their contents
+func (r *redis_roles_dal) summarise(ctx context.Context, collection string, filter bson.M) (map[string]*projectSummary, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$ProjectID",
+			"count":  bson.M{"$sum": bson.M{"$cond": bson.A{"$Deleted", 0, 1}}},
+			"latest": bson.M{"$max": "$UpdatedTimestampUTC"},
+		}}},
+	}
+
+	cursor, err := r.mongo.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count project documents: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var summaries []*projectSummary
+	if err := cursor.All(ctx, &summaries); err != nil {
+		return nil, fmt.Errorf("failed to decode project document counts: %w", err)
+	}
+
+	byProject := make(map[string]*projectSummary, len(summaries))
+	for _, summary := range summaries {
+		byProject[summary.ProjectID.Hex()] = summary
+	}
+	return byProject, nil
+}
+
+// syncState compares a MongoDB summary, nil when the project has no
+// documents, with the version of its snapshot, when there is one
+func syncState(summary *projectSummary, snapshot bool, redisVersion int64) string {
+	switch {
+	case summary == nil && !snapshot:
+		return ProjectSyncCurrent
+	case summary == nil:
+		return ProjectSyncOrphaned
+	case !snapshot:
+		return ProjectSyncMissing
+	case summary.Latest.UnixMilli() != redisVersion:
+		return ProjectSyncStale
+	default:
+		return ProjectSyncCurrent
+	}
+}
+
+// compareProject reads the snapshots of a project and compares them with its
+// roles and bindings, a summary being nil when the project has none
+func (r *redis_roles_dal) compareProject(ctx context.Context, projectID string, roles, bindings *projectSummary) (*ProjectSyncState, error) {
+	snapshot, err := r.readSnapshot(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	bindingsSnapshot, err := r.readBindingsSnapshot(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &ProjectSyncState{ProjectID: projectID}
+	if roles != nil {
+		latest := roles.Latest.UTC()
+		state.MongoRoles = roles.Count
+		state.MongoUpdatedUTC = &latest
+		state.MongoVersion = latest.UnixMilli()
+	}
+	if snapshot != nil {
+		syncedAt := snapshot.SyncedAt
+		state.RedisVersion = snapshot.Version
+		state.RedisSyncedAt = &syncedAt
+	}
+	if bindings != nil {
+		state.MongoBindings = bindings.Count
+		state.MongoBindingsVersion = bindings.Latest.UnixMilli()
+	}
+	if bindingsSnapshot != nil {
+		state.RedisBindingsVersion = bindingsSnapshot.Version
+	}
+
+	state.State = syncState(roles, snapshot != nil, state.RedisVersion)
+	state.BindingsState = syncState(bindings, bindingsSnapshot != nil, state.RedisBindingsVersion)
+	return state, nil
+}
+
+// InspectSync compares every project with roles or bindings in MongoDB or a
+// snapshot in Redis. Unlike Reconcile it only compares versions, and repairs
+// nothing. A project is listed under each state either of its snapshots is in.
+func (r *redis_roles_dal) InspectSync(ctx context.Context) (*SyncDriftReport, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "roles", "InspectSync")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "roles", "InspectSync")()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+	defer cancel()
+
+	report := &SyncDriftReport{
+		CheckedAt: time.Now().UTC(),
+		Projects:  []*ProjectSyncState{},
+		Stale:     []string{},
+		Missing:   []string{},
+		Orphaned:  []string{},
+	}
+
+	roles, err := r.summarise(ctx, r.collectionName, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := r.summarise(ctx, r.bindingsCollectionName, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make(map[string]bool, len(roles))
+	for projectID := range roles {
+		projects[projectID] = true
+	}
+	for projectID := range bindings {
+		projects[projectID] = true
+	}
+	for _, prefix := range []string{rolesKeyPrefix, bindingsKeyPrefix} {
+		iter := r.redis.Scan(ctx, 0, prefix+"*", 1000).Iterator()
+		for iter.Next(ctx) {
+			projects[strings.TrimPrefix(iter.Val(), prefix)] = true
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list snapshots: %w", err)
+		}
+	}
+
+	projectIDs := make([]string, 0, len(projects))
+	for projectID := range projects {
+		projectIDs = append(projectIDs, projectID)
+	}
+	sort.Strings(projectIDs)
+
+	for _, projectID := range projectIDs {
+		state, err := r.compareProject(ctx, projectID, roles[projectID], bindings[projectID])
+		if err != nil {
+			return nil, err
+		}
+		report.Projects = append(report.Projects, state)
+
+		for _, list := range []struct {
+			state    string
+			projects *[]string
+		}{
+			{ProjectSyncStale, &report.Stale},
+			{ProjectSyncMissing, &report.Missing},
+			{ProjectSyncOrphaned, &report.Orphaned},
+		} {
+			if state.State == list.state || state.BindingsState == list.state {
+				*list.projects = append(*list.projects, projectID)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// InspectProject compares one project's roles and bindings with their snapshots
+func (r *redis_roles_dal) InspectProject(ctx context.Context, projectID bson.ObjectID) (*ProjectSyncState, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "roles", "InspectProject")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "roles", "InspectProject")()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+	defer cancel()
+
+	roles, err := r.summarise(ctx, r.collectionName, bson.M{"ProjectID": projectID})
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := r.summarise(ctx, r.bindingsCollectionName, bson.M{"ProjectID": projectID})
+	if err != nil {
+		return nil, err
+	}
+	return r.compareProject(ctx, projectID.Hex(), roles[projectID.Hex()], bindings[projectID.Hex()])
+}
+
+// ResyncProject rebuilds the roles and bindings snapshots of one project from
+// MongoDB, replacing whatever Redis holds, and returns how it compares
+// afterwards. It is safe to run while the syncer is running, as the rebuild
+// reads the roles afresh.
+func (r *redis_roles_dal) ResyncProject(ctx context.Context, projectID bson.ObjectID) (*ProjectSyncState, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "roles", "ResyncProject")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "roles", "ResyncProject")()
+
+	syncCtx, cancel := context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+	defer cancel()
+
+	if err := r.storeProjectRoles(syncCtx, projectID, true); err != nil {
+		return nil, err
+	}
+	if err := r.storeProjectBindings(syncCtx, projectID, true); err != nil {
+		return nil, fmt.Errorf("failed to store project bindings: %w", err)
+	}
+
+	return r.InspectProject(ctx, projectID)
+}
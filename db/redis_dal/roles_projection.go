@@ -0,0 +1,321 @@
+package redis_dal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/agent-auth/common-lib/models"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	rolesKeyPrefix        = "roles:"
+	rolesVersionKeyPrefix = "sync:roles:version:"
+)
+
+// Reconcile metrics, published through expvar
+var (
+	syncDriftRepaired = expvar.NewInt("role_sync_drift_repaired")
+	syncLastReconcile = expvar.NewString("role_sync_last_reconcile_utc")
+)
+
+// writeSnapshotScript replaces a project's roles or bindings snapshot and
+// records its version in one step, unless a newer version is already stored. A forced write, used
+// to repair drift, replaces any version.
+var writeSnapshotScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[2]) or "0")
+if ARGV[3] ~= "1" and tonumber(ARGV[1]) < current then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2])
+redis.call("SET", KEYS[2], ARGV[1])
+return 1
+`)
+
+// deleteSnapshotScript removes a project's roles or bindings snapshot and its
+// version together
+var deleteSnapshotScript = redis.NewScript(`
+redis.call("DEL", KEYS[1], KEYS[2])
+return 1
+`)
+
+// projectRolesSnapshot is the Redis projection of a project's roles, keyed
+// by role and resource URN. Version is the latest UpdatedTimestampUTC of the
+// project's roles, deleted ones included, in Unix milliseconds, so a rebuild
+// from older data never replaces a newer snapshot.
+type projectRolesSnapshot struct {
+	ProjectID   string                                  `json:"project_id"`
+	Version     int64                                   `json:"version"`
+	SyncedAt    time.Time                               `json:"synced_at"`
+	Permissions map[string]map[string]models.Permission `json:"permissions"`
+}
+
+func (s *projectRolesSnapshot) objectID() bson.ObjectID {
+	id, _ := bson.ObjectIDFromHex(s.ProjectID)
+	return id
+}
+
+// ReconcileReport summarises a full reconcile of the roles and bindings
+// snapshots
+type ReconcileReport struct {
+	Projects         int `json:"projects"`
+	Repaired         int `json:"repaired"`
+	Removed          int `json:"removed"`
+	BindingProjects  int `json:"binding_projects"`
+	BindingsRepaired int `json:"bindings_repaired"`
+	BindingsRemoved  int `json:"bindings_removed"`
+}
+
+// buildSnapshots groups roles by project. Deleted roles only count towards
+// the version, so deleting a role produces a newer snapshot without it.
+func buildSnapshots(roles []models.Roles) map[string]*projectRolesSnapshot {
+	snapshots := make(map[string]*projectRolesSnapshot)
+
+	for _, role := range roles {
+		projectID := role.ProjectID.Hex()
+
+		snapshot, exists := snapshots[projectID]
+		if !exists {
+			snapshot = &projectRolesSnapshot{
+				ProjectID:   projectID,
+				Permissions: make(map[string]map[string]models.Permission),
+			}
+			snapshots[projectID] = snapshot
+		}
+
+		if version := role.UpdatedTimestampUTC.UnixMilli(); version > snapshot.Version {
+			snapshot.Version = version
+		}
+		if role.Deleted {
+			continue
+		}
+
+		if _, exists := snapshot.Permissions[role.Role]; !exists {
+			snapshot.Permissions[role.Role] = make(map[string]models.Permission)
+		}
+		for urn, permission := range role.Permissions {
+			snapshot.Permissions[role.Role][urn] = permission
+		}
+	}
+
+	return snapshots
+}
+
+// storeProjectRoles rebuilds the snapshot of one project from all of its
+// roles, so a change to one role keeps the others and deletions drop out.
+// A project left without any role document loses its snapshot. Unless force
+// is set, a newer stored snapshot is kept.
+func (r *redis_roles_dal) storeProjectRoles(ctx context.Context, projectID bson.ObjectID, force bool) error {
+	ctx, span := tracing.Start(ctx, "rolesync.store_project_roles",
+		attribute.String("project_id", projectID.Hex()),
+		attribute.Bool("force", force))
+	defer span.End()
+
+	roles, err := r.findRoles(ctx, bson.M{"ProjectID": projectID})
+	if err != nil {
+		return fmt.Errorf("failed to fetch project roles: %w", err)
+	}
+
+	snapshot, exists := buildSnapshots(roles)[projectID.Hex()]
+	if !exists {
+		return r.deleteSnapshot(ctx, projectID.Hex())
+	}
+
+	_, err = r.writeSnapshot(ctx, snapshot, force)
+	return err
+}
+
+// writeSnapshot stores snapshot unless a newer version is already stored, or
+// regardless when force is set. It reports whether the snapshot was written.
+func (r *redis_roles_dal) writeSnapshot(ctx context.Context, snapshot *projectRolesSnapshot, force bool) (bool, error) {
+	snapshot.SyncedAt = time.Now().UTC()
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return false, err
+	}
+
+	written, err := r.writeVersioned(ctx,
+		rolesKeyPrefix+snapshot.ProjectID, rolesVersionKeyPrefix+snapshot.ProjectID,
+		snapshot.Version, data, force)
+	if err != nil {
+		r.logger.Error("Failed to store roles snapshot in Redis",
+			zap.String("projectID", snapshot.ProjectID),
+			zap.Error(err))
+		return false, fmt.Errorf("failed to store roles snapshot: %w", err)
+	}
+
+	if !written {
+		r.logger.Info("Skipped roles snapshot older than the stored one",
+			zap.String("projectID", snapshot.ProjectID),
+			zap.Int64("version", snapshot.Version))
+	}
+	return written, nil
+}
+
+// writeVersioned stores data under key and version under versionKey through
+// writeSnapshotScript
+func (r *redis_roles_dal) writeVersioned(ctx context.Context, key, versionKey string, version int64, data []byte, force bool) (bool, error) {
+	forced := "0"
+	if force {
+		forced = "1"
+	}
+
+	written, err := writeSnapshotScript.Run(ctx, r.redis, []string{key, versionKey}, version, data, forced).Int()
+	if err != nil {
+		return false, err
+	}
+	return written == 1, nil
+}
+
+func (r *redis_roles_dal) deleteSnapshot(ctx context.Context, projectID string) error {
+	return r.deleteVersioned(ctx, rolesKeyPrefix+projectID, rolesVersionKeyPrefix+projectID)
+}
+
+// deleteVersioned removes a snapshot and its version through
+// deleteSnapshotScript
+func (r *redis_roles_dal) deleteVersioned(ctx context.Context, key, versionKey string) error {
+	if err := deleteSnapshotScript.Run(ctx, r.redis, []string{key, versionKey}).Err(); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
+
+// readSnapshot returns the stored snapshot of a project, or nil when there is
+// none or it cannot be decoded
+func (r *redis_roles_dal) readSnapshot(ctx context.Context, projectID string) (*projectRolesSnapshot, error) {
+	data, err := r.redis.Get(ctx, rolesKeyPrefix+projectID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read roles snapshot: %w", err)
+	}
+
+	var snapshot projectRolesSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		r.logger.Warn("Undecodable roles snapshot", zap.String("projectID", projectID), zap.Error(err))
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+// Reconcile compares the roles and bindings snapshots of every project with
+// MongoDB, rewriting those that are missing or differ and removing those of
+// projects that no longer have roles or bindings. Each repair rebuilds the project from a fresh
+// read, so a change synced since the comparison is not undone.
+func (r *redis_roles_dal) Reconcile(ctx context.Context) (*ReconcileReport, error) {
+	defer metrics.ObserveOperation(metrics.StoreRedis, "roles", "Reconcile")()
+	defer tracing.TraceOperation(ctx, metrics.StoreRedis, "roles", "Reconcile")()
+
+	roles, err := r.findRoles(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch roles: %w", err)
+	}
+	expected := buildSnapshots(roles)
+
+	report := &ReconcileReport{Projects: len(expected)}
+	for projectID, snapshot := range expected {
+		current, err := r.readSnapshot(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && current.Version == snapshot.Version && snapshotsEqual(current, snapshot) {
+			continue
+		}
+
+		r.logger.Warn("Repairing drifted roles snapshot",
+			zap.String("projectID", projectID),
+			zap.Bool("missing", current == nil),
+			zap.Int64("version", snapshot.Version))
+		if err := r.storeProjectRoles(ctx, snapshot.objectID(), true); err != nil {
+			return nil, err
+		}
+		report.Repaired++
+	}
+
+	var orphans []string
+	iter := r.redis.Scan(ctx, 0, rolesKeyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if projectID := strings.TrimPrefix(iter.Val(), rolesKeyPrefix); expected[projectID] == nil {
+			orphans = append(orphans, projectID)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list roles snapshots: %w", err)
+	}
+
+	for _, projectID := range orphans {
+		id, err := bson.ObjectIDFromHex(projectID)
+		if err != nil {
+			r.logger.Warn("Removing roles snapshot with invalid project ID", zap.String("projectID", projectID))
+			if err := r.deleteSnapshot(ctx, projectID); err != nil {
+				return nil, err
+			}
+			report.Removed++
+			continue
+		}
+
+		r.logger.Warn("Removing roles snapshot of project without roles", zap.String("projectID", projectID))
+		if err := r.storeProjectRoles(ctx, id, true); err != nil {
+			return nil, err
+		}
+		report.Removed++
+	}
+
+	if err := r.reconcileBindings(ctx, report); err != nil {
+		return nil, err
+	}
+
+	syncDriftRepaired.Add(int64(report.Repaired + report.Removed + report.BindingsRepaired + report.BindingsRemoved))
+	syncLastReconcile.Set(time.Now().UTC().Format(time.RFC3339))
+	return report, nil
+}
+
+// snapshotsEqual compares the permissions of two snapshots as they are
+// stored; map keys are encoded in sorted order
+func snapshotsEqual(a, b *projectRolesSnapshot) bool {
+	aData, aErr := json.Marshal(a.Permissions)
+	bData, bErr := json.Marshal(b.Permissions)
+	return aErr == nil && bErr == nil && bytes.Equal(aData, bData)
+}
+
+// reconcilePeriodically runs Reconcile every interval until ctx is done
+func (r *redis_roles_dal) reconcilePeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reconcileCtx, cancel := context.WithTimeout(ctx, interval)
+			reconcileCtx, span := tracing.Start(reconcileCtx, "rolesync.reconcile")
+			report, err := r.Reconcile(reconcileCtx)
+			span.End()
+			cancel()
+			if err != nil {
+				r.logger.Error("Failed to reconcile roles and bindings snapshots", zap.Error(err))
+				continue
+			}
+			r.recordSync(ctx)
+			r.logger.Info("Reconciled roles and bindings snapshots",
+				zap.Int("projects", report.Projects),
+				zap.Int("repaired", report.Repaired),
+				zap.Int("removed", report.Removed),
+				zap.Int("bindingProjects", report.BindingProjects),
+				zap.Int("bindingsRepaired", report.BindingsRepaired),
+				zap.Int("bindingsRemoved", report.BindingsRemoved))
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package redis_dal
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	// roleSyncResumeTokenKey holds the position of the change stream, so a
+	// restarted syncer continues where the previous one stopped. It lives
+	// next to the snapshots it describes: if Redis loses them it loses the
+	// token too, and the next start resyncs everything.
+	roleSyncResumeTokenKey = "sync:roles:resume_token"
+
+	syncModeChangeStream = "change_stream"
+	syncModePolling      = "polling"
+
+	changeStreamRetryInterval = 5 * time.Second
+	changeStreamMaxAwait      = time.Second
+
+	// syncHeartbeatInterval is how often a caught-up stream records that the
+	// snapshots are still current, rather than after every idle wait
+	syncHeartbeatInterval = 30 * time.Second
+)
+
+// MongoDB error codes the syncer reacts to
+const (
+	errCodeInvalidResumeToken       = 260
+	errCodeChangeStreamFatal        = 280
+	errCodeChangeStreamHistoryLost  = 286
+	errCodeChangeStreamNotSupported = 40573
+)
+
+var errChangeStreamsUnsupported = errors.New("change streams are not supported")
+
+// Role sync metrics, published through expvar. The lag is the age of the
+// last change applied, or zero once the stream has caught up.
+var (
+	syncMode       = expvar.NewString("role_sync_mode")
+	syncLagSeconds = expvar.NewFloat("role_sync_lag_seconds")
+	syncEvents     = expvar.NewInt("role_sync_events")
+)
+
+// The same role sync metrics, for Prometheus
+func init() {
+	metrics.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Name:      "role_sync_lag_seconds",
+			Help:      "Age of the last role change applied to Redis, zero once caught up.",
+		}, syncLagSeconds.Value),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Name:      "role_sync_events_total",
+			Help:      "Role and binding changes applied from the change stream.",
+		}, func() float64 { return float64(syncEvents.Value()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Name:      "role_sync_drift_repaired_total",
+			Help:      "Role and binding snapshots rewritten or removed by reconciles.",
+		}, func() float64 { return float64(syncDriftRepaired.Value()) }),
+	)
+}
+
+// roleChangeEvent is the part of a change event the syncer reads
+type roleChangeEvent struct {
+	Namespace struct {
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+	ClusterTime  bson.Timestamp `bson:"clusterTime"`
+	FullDocument *struct {
+		ProjectID bson.ObjectID `bson:"ProjectID"`
+	} `bson:"fullDocument"`
+}
+
+// streamChanges follows the change stream of the roles and role bindings
+// collections, reopening it after failures. It returns
+// errChangeStreamsUnsupported when the deployment has no change streams, and
+// otherwise only once ctx is done.
+func (r *redis_roles_dal) streamChanges(ctx context.Context) error {
+	for {
+		err := r.followChangeStream(ctx)
+		if ctx.Err() != nil {
+			r.logger.Info("Stopping roles collection sync due to context cancellation")
+			return ctx.Err()
+		}
+		if errors.Is(err, errChangeStreamsUnsupported) {
+			return err
+		}
+
+		r.logger.Error("Role change stream failed, reopening", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(changeStreamRetryInterval):
+		}
+	}
+}
+
+// followChangeStream resumes the change stream from the saved token, or opens
+// it afresh and runs a full sync when there is no usable token, then applies
+// changes until the stream fails
+func (r *redis_roles_dal) followChangeStream(ctx context.Context) error {
+	token, err := r.loadResumeToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, err := r.openChangeStream(ctx, token)
+	if token != nil && resumeHistoryLost(err) {
+		r.logger.Warn("Role change stream can no longer be resumed, resyncing everything", zap.Error(err))
+		token = nil
+		stream, err = r.openChangeStream(ctx, nil)
+	}
+	if err != nil {
+		if hasErrorCode(err, errCodeChangeStreamNotSupported) {
+			return errChangeStreamsUnsupported
+		}
+		return fmt.Errorf("failed to open role change stream: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	syncMode.Set(syncModeChangeStream)
+
+	if token == nil {
+		// The stream is already open, so changes made during the full sync
+		// are applied again afterwards rather than lost
+		if err := r.InitialSync(ctx); err != nil {
+			return err
+		}
+		if err := r.saveResumeToken(ctx, stream.ResumeToken()); err != nil {
+			return err
+		}
+	}
+
+	recorded := time.Now()
+	for {
+		if stream.TryNext(ctx) {
+			if err := r.applyChange(ctx, stream); err != nil {
+				return err
+			}
+			recorded = time.Now()
+			continue
+		}
+		if err := stream.Err(); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Caught up; TryNext waits up to the max await time for the next
+		// change. The status is recorded as soon as the lag clears, then on
+		// the heartbeat.
+		caughtUp := syncLagSeconds.Value() != 0
+		syncLagSeconds.Set(0)
+		if caughtUp || time.Since(recorded) >= syncHeartbeatInterval {
+			r.recordSync(ctx)
+			recorded = time.Now()
+		}
+	}
+}
+
+// openChangeStream watches inserts, updates and replacements in the roles
+// and role bindings collections, starting after token when it is set.
+// Only the project of each changed document is read back.
+func (r *redis_roles_dal) openChangeStream(ctx context.Context, token bson.Raw) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"ns.coll":       bson.M{"$in": []string{r.collectionName, r.bindingsCollectionName}},
+			"operationType": bson.M{"$in": []string{"insert", "update", "replace"}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"ns":                     1,
+			"clusterTime":            1,
+			"fullDocument.ProjectID": 1,
+		}}},
+	}
+
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetMaxAwaitTime(changeStreamMaxAwait)
+	if token != nil {
+		opts.SetStartAfter(token)
+	}
+
+	return r.mongo.Watch(ctx, pipeline, opts)
+}
+
+// applyChange rebuilds the snapshot of the project whose role or binding
+// changed, then records how far the stream has been applied
+func (r *redis_roles_dal) applyChange(ctx context.Context, stream *mongo.ChangeStream) error {
+	var event roleChangeEvent
+	if err := stream.Decode(&event); err != nil {
+		return fmt.Errorf("failed to decode role change: %w", err)
+	}
+
+	applyCtx, cancel := context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+	defer cancel()
+
+	applyCtx, span := tracing.Start(applyCtx, "rolesync.apply_change",
+		attribute.String("collection", event.Namespace.Collection))
+	defer span.End()
+
+	// The document is missing when it was removed before the lookup; roles
+	// and bindings are soft-deleted, so a later event covers it
+	if event.FullDocument != nil {
+		projectID := event.FullDocument.ProjectID
+
+		var err error
+		switch event.Namespace.Collection {
+		case r.collectionName:
+			err = r.storeProjectRoles(applyCtx, projectID, false)
+		case r.bindingsCollectionName:
+			err = r.storeProjectBindings(applyCtx, projectID, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := r.saveResumeToken(applyCtx, stream.ResumeToken()); err != nil {
+		return err
+	}
+
+	syncEvents.Add(1)
+	syncLagSeconds.Set(time.Since(time.Unix(int64(event.ClusterTime.T), 0)).Seconds())
+	r.recordSync(ctx)
+	return nil
+}
+
+func (r *redis_roles_dal) loadResumeToken(ctx context.Context) (bson.Raw, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.timeoutSeconds)*time.Second)
+	defer cancel()
+
+	token, err := r.redis.Get(ctx, roleSyncResumeTokenKey).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role sync resume token: %w", err)
+	}
+	return bson.Raw(token), nil
+}
+
+func (r *redis_roles_dal) saveResumeToken(ctx context.Context, token bson.Raw) error {
+	if token == nil {
+		return nil
+	}
+	if err := r.redis.Set(ctx, roleSyncResumeTokenKey, []byte(token), 0).Err(); err != nil {
+		return fmt.Errorf("failed to save role sync resume token: %w", err)
+	}
+	return nil
+}
+
+// resumeHistoryLost reports whether err means the oplog no longer reaches
+// back to the resume token
+func resumeHistoryLost(err error) bool {
+	return hasErrorCode(err, errCodeChangeStreamHistoryLost) ||
+		hasErrorCode(err, errCodeChangeStreamFatal) ||
+		hasErrorCode(err, errCodeInvalidResumeToken)
+}
+
+func hasErrorCode(err error, code int) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(code)
+}
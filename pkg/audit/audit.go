@@ -0,0 +1,189 @@
+// Package audit records who changed what. Handlers call a Recorder after each
+// successful mutation and it stores an immutable Event describing the actor,
+// the target and the fields that changed.
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/go-chi/chi/middleware"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// Principal types
+const (
+	PrincipalUser  = "user"
+	PrincipalAgent = "agent"
+)
+
+// Target types
+const (
+	TargetWorkspace  = "workspace"
+	TargetProject    = "project"
+	TargetRole       = "role"
+	TargetPermission = "permission"
+	TargetResource   = "resource"
+	TargetWebhook    = "webhook"
+)
+
+// Actions
+const (
+	ActionWorkspaceCreate       = "workspace.create"
+	ActionWorkspaceUpdate       = "workspace.update"
+	ActionWorkspaceDelete       = "workspace.delete"
+	ActionWorkspaceMemberAdd    = "workspace.member.add"
+	ActionWorkspaceMemberRemove = "workspace.member.remove"
+	ActionWorkspaceTransfer     = "workspace.transfer"
+	ActionProjectCreate         = "project.create"
+	ActionProjectUpdate         = "project.update"
+	ActionProjectDelete         = "project.delete"
+	ActionProjectMemberAdd      = "project.member.add"
+	ActionProjectMemberRemove   = "project.member.remove"
+	ActionProjectTransfer       = "project.transfer"
+	ActionRoleCreate            = "role.create"
+	ActionRoleDelete            = "role.delete"
+	ActionRoleAssign            = "role.assign"
+	ActionRoleUnassign          = "role.unassign"
+	ActionPermissionUpdate      = "permission.update"
+	ActionResourceCreate        = "resource.create"
+	ActionResourceUpdate        = "resource.update"
+	ActionResourceDelete        = "resource.delete"
+	ActionWebhookCreate         = "webhook.create"
+	ActionWebhookUpdate         = "webhook.update"
+	ActionWebhookDelete         = "webhook.delete"
+)
+
+// Actions lists every action an event can record
+var Actions = []string{
+	ActionWorkspaceCreate, ActionWorkspaceUpdate, ActionWorkspaceDelete,
+	ActionWorkspaceMemberAdd, ActionWorkspaceMemberRemove, ActionWorkspaceTransfer,
+	ActionProjectCreate, ActionProjectUpdate, ActionProjectDelete,
+	ActionProjectMemberAdd, ActionProjectMemberRemove, ActionProjectTransfer,
+	ActionRoleCreate, ActionRoleDelete, ActionRoleAssign, ActionRoleUnassign,
+	ActionPermissionUpdate,
+	ActionResourceCreate, ActionResourceUpdate, ActionResourceDelete,
+	ActionWebhookCreate, ActionWebhookUpdate, ActionWebhookDelete,
+}
+
+// Event is the record of one mutation. Events are only ever appended, and
+// each is chained to the previous event of its workspace by Hash.
+type Event struct {
+	ID            bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	Sequence      int64         `json:"sequence" bson:"Sequence"`
+	PrevHash      string        `json:"prev_hash" bson:"PrevHash"`
+	Hash          string        `json:"hash" bson:"Hash"`
+	WorkspaceID   bson.ObjectID `json:"workspace_id" bson:"WorkspaceID"`
+	ProjectID     bson.ObjectID `json:"project_id,omitempty" bson:"ProjectID,omitempty"`
+	Actor         string        `json:"actor" bson:"Actor"`
+	PrincipalType string        `json:"principal_type" bson:"PrincipalType"`
+	Action        string        `json:"action" bson:"Action"`
+	TargetType    string        `json:"target_type" bson:"TargetType"`
+	TargetID      string        `json:"target_id" bson:"TargetID"`
+	Changes       []Change      `json:"changes" bson:"Changes"`
+	RequestID     string        `json:"request_id,omitempty" bson:"RequestID"`
+	SourceIP      string        `json:"source_ip,omitempty" bson:"SourceIP"`
+	TimestampUTC  time.Time     `json:"timestamp_utc" bson:"TimestampUTC"`
+}
+
+// Target identifies what a mutation changed. WorkspaceID defaults to the
+// workspace the request is scoped to.
+type Target struct {
+	Type        string
+	ID          string
+	WorkspaceID bson.ObjectID
+	ProjectID   bson.ObjectID
+}
+
+// Store persists events. It must Seal each event to the head of its
+// workspace chain.
+type Store interface {
+	Append(ctx context.Context, event *Event) error
+}
+
+// Listener is told about each event once it is stored. It is called on the
+// request path, so it must hand slow work off rather than do it inline.
+type Listener interface {
+	EventRecorded(event *Event)
+}
+
+// Recorder builds events from requests and appends them to a Store
+type Recorder struct {
+	store     Store
+	logger    *zap.Logger
+	listeners []Listener
+}
+
+// NewRecorder returns a Recorder writing to store and then telling listeners
+func NewRecorder(store Store, logger *zap.Logger, listeners ...Listener) *Recorder {
+	return &Recorder{store: store, logger: logger, listeners: listeners}
+}
+
+// Record appends an event for a mutation made by r. before and after are the
+// target's state around the change; pass nil for a creation or a deletion.
+// The mutation has already happened by the time it is recorded, so a failure
+// to store the event is logged rather than returned.
+func (rec *Recorder) Record(r *http.Request, action string, target Target, before, after interface{}) {
+	changes, err := Diff(before, after)
+	if err != nil {
+		rec.logger.Error("failed to diff audit event", zap.String("action", action), zap.Error(err))
+	}
+
+	event := &Event{
+		WorkspaceID:  target.WorkspaceID,
+		ProjectID:    target.ProjectID,
+		Action:       action,
+		TargetType:   target.Type,
+		TargetID:     target.ID,
+		Changes:      changes,
+		RequestID:    middleware.GetReqID(r.Context()),
+		SourceIP:     sourceIP(r),
+		TimestampUTC: time.Now().UTC().Truncate(time.Millisecond), // the precision stored
+	}
+	event.Actor, event.PrincipalType = actor(r)
+
+	if event.WorkspaceID.IsZero() {
+		if scope, ok := authz.WorkspaceScopeFromContext(r.Context()); ok {
+			event.WorkspaceID, _ = bson.ObjectIDFromHex(scope.WorkspaceID)
+		}
+	}
+
+	// The mutation is done, so the event is stored even if the caller has
+	// gone away in the meantime
+	if err := rec.store.Append(context.WithoutCancel(r.Context()), event); err != nil {
+		rec.logger.Error("failed to record audit event",
+			zap.String("action", action),
+			zap.String("target", target.ID),
+			zap.Error(err))
+		return
+	}
+
+	for _, listener := range rec.listeners {
+		listener.EventRecorded(event)
+	}
+}
+
+// actor identifies the caller: users by email, agents by token subject
+func actor(r *http.Request) (string, string) {
+	principal, ok := authz.PrincipalFromContext(r.Context())
+	if !ok {
+		return "", ""
+	}
+	if principal.Email != "" {
+		return principal.Email, PrincipalUser
+	}
+	return principal.Subject, PrincipalAgent
+}
+
+// sourceIP returns the client address without its port
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
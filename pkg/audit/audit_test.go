@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/go-chi/chi/middleware"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+type memoryStore struct {
+	events []*Event
+}
+
+func (m *memoryStore) Append(ctx context.Context, event *Event) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+type item struct {
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags"`
+	Secret string   `json:"-"`
+}
+
+func TestDiffReportsChangedFields(t *testing.T) {
+	changes, err := Diff(
+		&item{Name: "old", Tags: []string{"a"}, Secret: "x"},
+		&item{Name: "new", Tags: []string{"a"}, Secret: "y"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1: %+v", len(changes), changes)
+	}
+	if changes[0].Field != "name" || string(changes[0].Before) != `"old"` || string(changes[0].After) != `"new"` {
+		t.Fatalf("unexpected change %+v", changes[0])
+	}
+}
+
+func TestDiffOfCreationListsEveryField(t *testing.T) {
+	var none *item
+	changes, err := Diff(none, &item{Name: "new", Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Field != "name" || changes[1].Field != "tags" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	if changes[0].Before != nil {
+		t.Fatalf("creation should have no before value, got %s", changes[0].Before)
+	}
+}
+
+func TestDiffOfScalarValues(t *testing.T) {
+	changes, err := Diff([]string{"read"}, []string{"read", "write"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Field != "value" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+}
+
+func TestRecordCapturesRequestContext(t *testing.T) {
+	store := &memoryStore{}
+	rec := NewRecorder(store, zap.NewNop())
+
+	workspaceID := bson.NewObjectID()
+	r := httptest.NewRequest("PUT", "/projects/1", nil)
+	r.RemoteAddr = "203.0.113.7:51234"
+	ctx := context.WithValue(r.Context(), authz.PrincipalContextKey, &authz.Principal{Subject: "agent-1"})
+	ctx = context.WithValue(ctx, authz.WorkspaceScopeContextKey, &authz.WorkspaceScope{WorkspaceID: workspaceID.Hex()})
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "req-1")
+	r = r.WithContext(ctx)
+
+	rec.Record(r, ActionProjectUpdate, Target{Type: TargetProject, ID: "1"}, &item{Name: "a"}, &item{Name: "b"})
+
+	if len(store.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(store.events))
+	}
+	event := store.events[0]
+	if event.Actor != "agent-1" || event.PrincipalType != PrincipalAgent {
+		t.Fatalf("unexpected actor %q (%s)", event.Actor, event.PrincipalType)
+	}
+	if event.WorkspaceID != workspaceID {
+		t.Fatalf("workspace %s not taken from scope", event.WorkspaceID.Hex())
+	}
+	if event.RequestID != "req-1" || event.SourceIP != "203.0.113.7" {
+		t.Fatalf("unexpected request metadata %q %q", event.RequestID, event.SourceIP)
+	}
+	if len(event.Changes) != 1 {
+		t.Fatalf("unexpected changes %+v", event.Changes)
+	}
+}
+
+type listenerFunc func(event *Event)
+
+func (f listenerFunc) EventRecorded(event *Event) {
+	f(event)
+}
+
+type failingStore struct{}
+
+func (failingStore) Append(ctx context.Context, event *Event) error {
+	return errors.New("unavailable")
+}
+
+func TestRecordNotifiesListenersOfStoredEvents(t *testing.T) {
+	var heard []*Event
+	listener := listenerFunc(func(event *Event) { heard = append(heard, event) })
+	r := httptest.NewRequest("POST", "/roles", nil)
+
+	NewRecorder(&memoryStore{}, zap.NewNop(), listener).
+		Record(r, ActionRoleCreate, Target{Type: TargetRole, ID: "1"}, nil, &item{Name: "a"})
+	if len(heard) != 1 || heard[0].Action != ActionRoleCreate {
+		t.Fatalf("listener heard %+v", heard)
+	}
+
+	NewRecorder(failingStore{}, zap.NewNop(), listener).
+		Record(r, ActionRoleCreate, Target{Type: TargetRole, ID: "2"}, nil, &item{Name: "b"})
+	if len(heard) != 1 {
+		t.Fatalf("listener heard an event that was not stored")
+	}
+}
@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Reasons a chain fails verification
+const (
+	BreakHashMismatch   = "hash_mismatch"      // the event was changed after it was written
+	BreakLinkMismatch   = "prev_hash_mismatch" // an event before it was changed, removed or reordered
+	BreakSequenceGap    = "sequence_gap"       // events are missing
+	BreakWrongWorkspace = "wrong_workspace"    // the event belongs to another chain
+)
+
+// canonicalEvent is the form of an Event that is hashed. Field order is
+// fixed by the struct and every value has a single textual encoding.
+type canonicalEvent struct {
+	Sequence      int64    `json:"sequence"`
+	PrevHash      string   `json:"prev_hash"`
+	WorkspaceID   string   `json:"workspace_id"`
+	ProjectID     string   `json:"project_id"`
+	Actor         string   `json:"actor"`
+	PrincipalType string   `json:"principal_type"`
+	Action        string   `json:"action"`
+	TargetType    string   `json:"target_type"`
+	TargetID      string   `json:"target_id"`
+	Changes       []Change `json:"changes"`
+	RequestID     string   `json:"request_id"`
+	SourceIP      string   `json:"source_ip"`
+	Timestamp     string   `json:"timestamp"`
+}
+
+// ComputeHash returns the SHA-256 of the event's content and its link to the
+// previous event. The stored ID and Hash are not part of it.
+func (e *Event) ComputeHash() (string, error) {
+	changes := e.Changes
+	if changes == nil {
+		changes = []Change{}
+	}
+
+	data, err := json.Marshal(canonicalEvent{
+		Sequence:      e.Sequence,
+		PrevHash:      e.PrevHash,
+		WorkspaceID:   e.WorkspaceID.Hex(),
+		ProjectID:     e.ProjectID.Hex(),
+		Actor:         e.Actor,
+		PrincipalType: e.PrincipalType,
+		Action:        e.Action,
+		TargetType:    e.TargetType,
+		TargetID:      e.TargetID,
+		Changes:       changes,
+		RequestID:     e.RequestID,
+		SourceIP:      e.SourceIP,
+		Timestamp:     e.TimestampUTC.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Seal links event to previous, the current head of its workspace chain, and
+// sets its hash. previous is nil for the first event of a workspace.
+func Seal(event, previous *Event) error {
+	event.Sequence = 1
+	event.PrevHash = ""
+	if previous != nil {
+		event.Sequence = previous.Sequence + 1
+		event.PrevHash = previous.Hash
+	}
+
+	hash, err := event.ComputeHash()
+	if err != nil {
+		return err
+	}
+	event.Hash = hash
+	return nil
+}
+
+// Break is a point where a chain fails verification
+type Break struct {
+	Sequence int64  `json:"sequence"`
+	EventID  string `json:"event_id"`
+	Reason   string `json:"reason"`
+}
+
+// Report is the outcome of walking a chain
+type Report struct {
+	Checked      int64   `json:"checked"`
+	HeadSequence int64   `json:"head_sequence"`
+	HeadHash     string  `json:"head_hash"`
+	Valid        bool    `json:"valid"`
+	Breaks       []Break `json:"breaks"`
+}
+
+// maxReportedBreaks bounds the breaks kept in a Report; one edit early in a
+// chain should not produce a report the size of the chain
+const maxReportedBreaks = 100
+
+// ChainVerifier checks the events of one workspace, fed in sequence order
+type ChainVerifier struct {
+	workspaceID string
+	sequence    int64
+	hash        string
+	started     bool
+	anchored    bool
+	report      Report
+}
+
+// NewChainVerifier returns a verifier for a whole chain, which must start at
+// the first event of the workspace
+func NewChainVerifier() *ChainVerifier {
+	return &ChainVerifier{report: Report{Breaks: []Break{}}}
+}
+
+// newRangeVerifier returns a verifier for part of a chain, which trusts the
+// link of its first event to events outside the range
+func newRangeVerifier() *ChainVerifier {
+	v := NewChainVerifier()
+	v.anchored = true
+	return v
+}
+
+// Check verifies the next event of the chain
+func (v *ChainVerifier) Check(event *Event) {
+	if !v.started {
+		v.started = true
+		v.workspaceID = event.WorkspaceID.Hex()
+		if v.anchored {
+			v.sequence, v.hash = event.Sequence-1, event.PrevHash
+		}
+	}
+
+	v.report.Checked++
+
+	if event.WorkspaceID.Hex() != v.workspaceID {
+		v.fail(event, BreakWrongWorkspace)
+	}
+	if event.Sequence != v.sequence+1 {
+		v.fail(event, BreakSequenceGap)
+	}
+	if event.PrevHash != v.hash {
+		v.fail(event, BreakLinkMismatch)
+	}
+	if hash, err := event.ComputeHash(); err != nil || hash != event.Hash {
+		v.fail(event, BreakHashMismatch)
+	}
+
+	// Continue from the event as stored so one break is reported once
+	v.sequence, v.hash = event.Sequence, event.Hash
+}
+
+func (v *ChainVerifier) fail(event *Event, reason string) {
+	if len(v.report.Breaks) < maxReportedBreaks {
+		v.report.Breaks = append(v.report.Breaks, Break{
+			Sequence: event.Sequence,
+			EventID:  event.ID.Hex(),
+			Reason:   reason,
+		})
+	}
+}
+
+// Report returns the outcome of the events checked so far
+func (v *ChainVerifier) Report() *Report {
+	report := v.report
+	report.HeadSequence, report.HeadHash = v.sequence, v.hash
+	report.Valid = len(report.Breaks) == 0
+	return &report
+}
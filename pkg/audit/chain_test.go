@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func sealedChain(t *testing.T, n int) []*Event {
+	t.Helper()
+
+	workspaceID := bson.NewObjectID()
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	var events []*Event
+	var previous *Event
+	for i := 0; i < n; i++ {
+		changes, err := Diff(nil, map[string]interface{}{"name": "role", "index": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		event := &Event{
+			ID:            bson.NewObjectID(),
+			WorkspaceID:   workspaceID,
+			Actor:         "owner@example.com",
+			PrincipalType: PrincipalUser,
+			Action:        ActionRoleCreate,
+			TargetType:    TargetRole,
+			TargetID:      bson.NewObjectID().Hex(),
+			Changes:       changes,
+			TimestampUTC:  start.Add(time.Duration(i) * time.Minute),
+		}
+		if err := Seal(event, previous); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+		previous = event
+	}
+	return events
+}
+
+func verify(events []*Event) *Report {
+	v := NewChainVerifier()
+	for _, event := range events {
+		v.Check(event)
+	}
+	return v.Report()
+}
+
+func TestIntactChainVerifies(t *testing.T) {
+	events := sealedChain(t, 5)
+
+	report := verify(events)
+	if !report.Valid || report.Checked != 5 || report.HeadSequence != 5 || report.HeadHash != events[4].Hash {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestEditedEventBreaksChain(t *testing.T) {
+	events := sealedChain(t, 5)
+	events[2].Actor = "intruder@example.com"
+
+	report := verify(events)
+	if report.Valid || len(report.Breaks) != 1 {
+		t.Fatalf("want one break, got %+v", report.Breaks)
+	}
+	if report.Breaks[0].Sequence != 3 || report.Breaks[0].Reason != BreakHashMismatch {
+		t.Fatalf("unexpected break %+v", report.Breaks[0])
+	}
+}
+
+func TestRemovedEventBreaksChain(t *testing.T) {
+	events := sealedChain(t, 5)
+	events = append(events[:2], events[3:]...)
+
+	report := verify(events)
+	if report.Valid || len(report.Breaks) != 2 {
+		t.Fatalf("want two breaks, got %+v", report.Breaks)
+	}
+	for _, b := range report.Breaks {
+		if b.Sequence != 4 {
+			t.Fatalf("unexpected break %+v", b)
+		}
+	}
+}
+
+func TestRehashedEventStillBreaksLink(t *testing.T) {
+	events := sealedChain(t, 3)
+	events[1].Actor = "intruder@example.com"
+	events[1].Hash, _ = events[1].ComputeHash()
+
+	report := verify(events)
+	if report.Valid || report.Breaks[0].Sequence != 3 || report.Breaks[0].Reason != BreakLinkMismatch {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func testSigner(t *testing.T) *Signer {
+	t.Helper()
+	seed := bytes.Repeat([]byte{7}, ed25519.SeedSize)
+	signer, err := NewSigner(base64.StdEncoding.EncodeToString(seed), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func export(t *testing.T, signer *Signer, events []*Event) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	x := NewExportWriter(&buf, signer, events[0].WorkspaceID.Hex(), events[0].TimestampUTC, time.Now())
+	for _, event := range events {
+		if err := x.Write(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	signer := testSigner(t)
+	events := sealedChain(t, 4)
+
+	// A range starting mid chain is anchored on its first event
+	data := export(t, signer, events[1:])
+
+	publicKey, err := ParsePublicKey(signer.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, report, err := VerifyExport(bytes.NewReader(data), publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Count != 3 || manifest.FirstSequence != 2 || manifest.HeadHash != events[3].Hash {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	if !report.Valid {
+		t.Fatalf("unexpected breaks %+v", report.Breaks)
+	}
+}
+
+func TestTamperedExportIsRejected(t *testing.T) {
+	signer := testSigner(t)
+	data := export(t, signer, sealedChain(t, 3))
+	publicKey, _ := ParsePublicKey(signer.PublicKey())
+
+	edited := bytes.Replace(data, []byte("owner@example.com"), []byte("other@example.com"), 1)
+	if _, _, err := VerifyExport(bytes.NewReader(edited), publicKey); err != ErrDigestMismatch {
+		t.Fatalf("want digest mismatch, got %v", err)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	var trailer manifestLine
+	if err := json.Unmarshal([]byte(lines[len(lines)-2]), &trailer); err != nil {
+		t.Fatal(err)
+	}
+	trailer.Manifest.Count = 2
+	forged, _ := json.Marshal(trailer)
+	lines[len(lines)-2] = string(forged) + "\n"
+	if _, _, err := VerifyExport(strings.NewReader(strings.Join(lines, "")), publicKey); err != ErrInvalidSignature {
+		t.Fatalf("want invalid signature, got %v", err)
+	}
+
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	if _, _, err := VerifyExport(bytes.NewReader(data), otherKey); err != ErrInvalidSignature {
+		t.Fatalf("want invalid signature for another key, got %v", err)
+	}
+}
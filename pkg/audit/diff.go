@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Change is one field that differs between the before and after state. The
+// values are kept as compact JSON so an event hashes the same after it is
+// read back from storage.
+type Change struct {
+	Field  string          `json:"field" bson:"Field"`
+	Before json.RawMessage `json:"before,omitempty" bson:"Before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty" bson:"After,omitempty"`
+}
+
+// Diff compares two states field by field through their JSON form, so fields
+// hidden from the API are also kept out of the audit trail. Values that are
+// not JSON objects are compared as a whole under the field "value".
+func Diff(before, after interface{}) ([]Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(b)+len(a))
+	for name := range b {
+		names = append(names, name)
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []Change{}
+	for _, name := range names {
+		if reflect.DeepEqual(b[name], a[name]) {
+			continue
+		}
+		change := Change{Field: name}
+		if change.Before, err = raw(b, name); err != nil {
+			return nil, err
+		}
+		if change.After, err = raw(a, name); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+func fields(v interface{}) (map[string]interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return map[string]interface{}{}, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers are kept as written so large integers survive the round trip
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	if object, ok := decoded.(map[string]interface{}); ok {
+		return object, nil
+	}
+	return map[string]interface{}{"value": decoded}, nil
+}
+
+// raw encodes the named field, or returns nil when it is absent
+func raw(object map[string]interface{}, name string) (json.RawMessage, error) {
+	value, ok := object[name]
+	if !ok {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,225 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// SignatureAlgorithm is the algorithm exports are signed with
+const SignatureAlgorithm = "ed25519"
+
+// Errors returned when reading an export
+var (
+	ErrNoManifest       = errors.New("export has no signed manifest")
+	ErrDigestMismatch   = errors.New("export events do not match the manifest digest")
+	ErrCountMismatch    = errors.New("export event count does not match the manifest")
+	ErrInvalidSignature = errors.New("export manifest signature is invalid")
+)
+
+// Manifest is the last line of an export. It covers every event line before
+// it by digest, and is itself covered by Signature.
+type Manifest struct {
+	WorkspaceID   string    `json:"workspace_id"`
+	Since         time.Time `json:"since"`
+	Until         time.Time `json:"until"`
+	Count         int64     `json:"count"`
+	FirstSequence int64     `json:"first_sequence"`
+	LastSequence  int64     `json:"last_sequence"`
+	HeadHash      string    `json:"head_hash"`
+	Digest        string    `json:"digest"` // SHA-256 of the event lines
+	ExportedAt    time.Time `json:"exported_at"`
+	Algorithm     string    `json:"algorithm"`
+	KeyID         string    `json:"key_id,omitempty"`
+	Signature     string    `json:"signature"`
+}
+
+// manifestLine wraps the manifest so it cannot be mistaken for an event
+type manifestLine struct {
+	Manifest *Manifest `json:"manifest"`
+}
+
+// signedBytes is the content the signature covers: the manifest without it
+func (m *Manifest) signedBytes() ([]byte, error) {
+	unsigned := *m
+	unsigned.Signature = ""
+	return json.Marshal(unsigned)
+}
+
+// Signer signs exports with an Ed25519 key
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner returns a Signer for a base64 encoded Ed25519 private key or
+// 32 byte seed. keyID is written to manifests to tell keys apart.
+func NewSigner(encodedKey, keyID string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signing key: %w", err)
+	}
+
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return &Signer{key: ed25519.NewKeyFromSeed(raw), keyID: keyID}, nil
+	case ed25519.PrivateKeySize:
+		return &Signer{key: ed25519.PrivateKey(raw), keyID: keyID}, nil
+	}
+	return nil, fmt.Errorf("signing key must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+}
+
+// PublicKey returns the base64 encoded key verifiers need
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// ParsePublicKey decodes a base64 encoded Ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// ExportWriter writes events as JSON lines followed by a signed Manifest.
+// Events must be written in sequence order.
+type ExportWriter struct {
+	w        io.Writer
+	signer   *Signer
+	digest   hash.Hash
+	manifest Manifest
+}
+
+// NewExportWriter starts an export of the events of workspaceID between since
+// and until
+func NewExportWriter(w io.Writer, signer *Signer, workspaceID string, since, until time.Time) *ExportWriter {
+	return &ExportWriter{
+		w:      w,
+		signer: signer,
+		digest: sha256.New(),
+		manifest: Manifest{
+			WorkspaceID: workspaceID,
+			Since:       since,
+			Until:       until,
+			Algorithm:   SignatureAlgorithm,
+			KeyID:       signer.keyID,
+		},
+	}
+}
+
+// Write appends one event line
+func (x *ExportWriter) Write(event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := x.w.Write(line); err != nil {
+		return err
+	}
+	x.digest.Write(line)
+
+	if x.manifest.Count == 0 {
+		x.manifest.FirstSequence = event.Sequence
+	}
+	x.manifest.Count++
+	x.manifest.LastSequence = event.Sequence
+	x.manifest.HeadHash = event.Hash
+	return nil
+}
+
+// Close signs and writes the manifest line
+func (x *ExportWriter) Close() error {
+	x.manifest.Digest = hex.EncodeToString(x.digest.Sum(nil))
+	x.manifest.ExportedAt = time.Now().UTC()
+
+	signed, err := x.manifest.signedBytes()
+	if err != nil {
+		return fmt.Errorf("failed to encode export manifest: %w", err)
+	}
+	x.manifest.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(x.signer.key, signed))
+
+	line, err := json.Marshal(manifestLine{Manifest: &x.manifest})
+	if err != nil {
+		return fmt.Errorf("failed to encode export manifest: %w", err)
+	}
+	_, err = x.w.Write(append(line, '\n'))
+	return err
+}
+
+// VerifyExport checks an export against publicKey: the manifest signature,
+// that the event lines are exactly those the manifest covers, and that the
+// events chain together. Chain breaks are returned in the report; an error
+// means the export itself cannot be trusted.
+func VerifyExport(r io.Reader, publicKey ed25519.PublicKey) (*Manifest, *Report, error) {
+	reader := bufio.NewReader(r)
+	digest := sha256.New()
+	verifier := newRangeVerifier()
+
+	var manifest *Manifest
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if manifest != nil {
+				return nil, nil, fmt.Errorf("export has content after its manifest")
+			}
+
+			var trailer manifestLine
+			if json.Unmarshal(line, &trailer) == nil && trailer.Manifest != nil {
+				manifest = trailer.Manifest
+			} else {
+				var event Event
+				if err := json.Unmarshal(line, &event); err != nil {
+					return nil, nil, fmt.Errorf("failed to decode export line: %w", err)
+				}
+				digest.Write(line)
+				verifier.Check(&event)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if manifest == nil {
+		return nil, nil, ErrNoManifest
+	}
+
+	signed, err := manifest.signedBytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(manifest.Signature)
+	if err != nil || !ed25519.Verify(publicKey, signed, signature) {
+		return manifest, nil, ErrInvalidSignature
+	}
+
+	if hex.EncodeToString(digest.Sum(nil)) != manifest.Digest {
+		return manifest, nil, ErrDigestMismatch
+	}
+
+	report := verifier.Report()
+	if report.Checked != manifest.Count {
+		return manifest, report, ErrCountMismatch
+	}
+
+	return manifest, report, nil
+}
@@ -0,0 +1,237 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClaimMapping lists, for each Principal field, the claim paths it is read
+// from. Paths use a JSONPath-like syntax: "$.realm_access.roles",
+// "resource_access['agent-auth-api'].roles" or "resource_access.*.roles".
+// Single valued fields take the first path holding a non-empty string, roles
+// and scopes are the union of every path. Roles paths may not use "*": a
+// wildcard over resource_access would grant this API the roles of every
+// other client in the realm.
+type ClaimMapping struct {
+	Subject []string `mapstructure:"subject"`
+	Email   []string `mapstructure:"email"`
+	Name    []string `mapstructure:"name"`
+	Roles   []string `mapstructure:"roles"`
+	Scopes  []string `mapstructure:"scopes"`
+}
+
+// DefaultClientID is the Keycloak client whose client roles are this API's
+const DefaultClientID = "agent-auth-api"
+
+// DefaultClaimMapping covers standard OIDC claims, Keycloak's realm roles and
+// the client roles of DefaultClientID
+func DefaultClaimMapping() ClaimMapping {
+	return ClaimMapping{
+		Subject: []string{"$.sub"},
+		Email:   []string{"$.email"},
+		Name:    []string{"$.name", "$.preferred_username"},
+		Roles:   []string{"$.roles", "$.realm_access.roles", "$.resource_access['" + DefaultClientID + "'].roles"},
+		Scopes:  []string{"$.scope", "$.scp"},
+	}
+}
+
+// claimPath is a parsed claim path, "*" segments match every key of an object
+type claimPath []string
+
+// ClaimMapper turns validated claims into a Principal
+type ClaimMapper struct {
+	subject []claimPath
+	email   []claimPath
+	name    []claimPath
+	roles   []claimPath
+	scopes  []claimPath
+}
+
+// NewClaimMapper compiles a claim mapping. Fields left empty use the paths of
+// DefaultClaimMapping.
+func NewClaimMapper(mapping ClaimMapping) (*ClaimMapper, error) {
+	defaults := DefaultClaimMapping()
+	mapper := &ClaimMapper{}
+
+	fields := []struct {
+		name     string
+		paths    []string
+		fallback []string
+		target   *[]claimPath
+	}{
+		{"subject", mapping.Subject, defaults.Subject, &mapper.subject},
+		{"email", mapping.Email, defaults.Email, &mapper.email},
+		{"name", mapping.Name, defaults.Name, &mapper.name},
+		{"roles", mapping.Roles, defaults.Roles, &mapper.roles},
+		{"scopes", mapping.Scopes, defaults.Scopes, &mapper.scopes},
+	}
+
+	for _, field := range fields {
+		paths := field.paths
+		if len(paths) == 0 {
+			paths = field.fallback
+		}
+		for _, path := range paths {
+			parsed, err := parseClaimPath(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s claim path %q: %w", field.name, path, err)
+			}
+			if field.target == &mapper.roles && parsed.hasWildcard() {
+				return nil, fmt.Errorf("invalid roles claim path %q: wildcards would accept roles meant for other clients, name the client instead", path)
+			}
+			*field.target = append(*field.target, parsed)
+		}
+	}
+
+	return mapper, nil
+}
+
+// Map builds the Principal described by claims
+func (m *ClaimMapper) Map(claims map[string]interface{}) *Principal {
+	return &Principal{
+		Subject: firstString(claims, m.subject),
+		Email:   firstString(claims, m.email),
+		Name:    firstString(claims, m.name),
+		Roles:   collectStrings(claims, m.roles),
+		Scopes:  collectStrings(claims, m.scopes),
+		Claims:  claims,
+	}
+}
+
+// parseClaimPath splits a path into segments. The leading "$" is optional,
+// keys are separated by dots and may be quoted in brackets when they contain
+// dots themselves.
+func parseClaimPath(path string) (claimPath, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	rest = strings.TrimPrefix(rest, ".")
+	if rest == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+	if strings.HasPrefix(rest, ".") {
+		return nil, fmt.Errorf("path has an empty segment")
+	}
+
+	var segments claimPath
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated bracket")
+			}
+			key := rest[1:end]
+			if key != "*" {
+				if len(key) < 2 || (key[0] != '\'' && key[0] != '"') || key[len(key)-1] != key[0] {
+					return nil, fmt.Errorf("bracket keys must be quoted")
+				}
+				key = key[1 : len(key)-1]
+			}
+			segments = append(segments, key)
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			if rest == "" || strings.HasPrefix(rest, ".") {
+				// Recursive descent is not supported
+				return nil, fmt.Errorf("path has an empty segment")
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		}
+	}
+
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("path has an empty segment")
+		}
+	}
+
+	return segments, nil
+}
+
+func (p claimPath) hasWildcard() bool {
+	for _, segment := range p {
+		if segment == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns every value found at path
+func (p claimPath) resolve(value interface{}) []interface{} {
+	if len(p) == 0 {
+		return []interface{}{value}
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if p[0] != "*" {
+		child, ok := object[p[0]]
+		if !ok {
+			return nil
+		}
+		return p[1:].resolve(child)
+	}
+
+	var values []interface{}
+	for _, child := range object {
+		values = append(values, p[1:].resolve(child)...)
+	}
+	return values
+}
+
+func firstString(claims map[string]interface{}, paths []claimPath) string {
+	for _, path := range paths {
+		for _, value := range path.resolve(claims) {
+			if str, ok := value.(string); ok && str != "" {
+				return str
+			}
+		}
+	}
+	return ""
+}
+
+// collectStrings unions the string values found at paths. Strings are split
+// on whitespace since scopes are commonly a space separated list.
+func collectStrings(claims map[string]interface{}, paths []claimPath) []string {
+	var values []string
+	seen := make(map[string]bool)
+
+	add := func(value string) {
+		for _, field := range strings.Fields(value) {
+			if !seen[field] {
+				seen[field] = true
+				values = append(values, field)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		for _, value := range path.resolve(claims) {
+			switch v := value.(type) {
+			case string:
+				add(v)
+			case []interface{}:
+				for _, item := range v {
+					if str, ok := item.(string); ok {
+						add(str)
+					}
+				}
+			case []string:
+				for _, str := range v {
+					add(str)
+				}
+			}
+		}
+	}
+
+	return values
+}
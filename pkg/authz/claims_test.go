@@ -0,0 +1,92 @@
+package authz
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestClaimMapperKeycloakRoles(t *testing.T) {
+	mapper, err := NewClaimMapper(ClaimMapping{})
+	if err != nil {
+		t.Fatalf("NewClaimMapper() error = %v", err)
+	}
+
+	principal := mapper.Map(map[string]interface{}{
+		"sub":                "service-account-1",
+		"preferred_username": "ci-agent",
+		"scope":              "openid profile",
+		"realm_access": map[string]interface{}{
+			"roles": []interface{}{"system_admin", "offline_access"},
+		},
+		"resource_access": map[string]interface{}{
+			"agent-auth-api": map[string]interface{}{
+				"roles": []interface{}{"app_viewer", "system_admin"},
+			},
+			"other-client": map[string]interface{}{
+				"roles": []interface{}{"workspace_admin"},
+			},
+		},
+	})
+
+	if principal.Subject != "service-account-1" {
+		t.Errorf("Subject = %q, want service-account-1", principal.Subject)
+	}
+	if principal.Email != "" {
+		t.Errorf("Email = %q, want empty for a service account", principal.Email)
+	}
+	if principal.Name != "ci-agent" {
+		t.Errorf("Name = %q, want ci-agent", principal.Name)
+	}
+
+	roles := append([]string(nil), principal.Roles...)
+	sort.Strings(roles)
+	want := []string{"app_viewer", "offline_access", "system_admin"}
+	if len(roles) != len(want) {
+		t.Fatalf("Roles = %v, want %v", roles, want)
+	}
+	for i := range want {
+		if roles[i] != want[i] {
+			t.Fatalf("Roles = %v, want %v", roles, want)
+		}
+	}
+
+	if !principal.HasScope("profile") || principal.HasScope("email") {
+		t.Errorf("Scopes = %v, want openid and profile", principal.Scopes)
+	}
+}
+
+func TestClaimMapperCustomPaths(t *testing.T) {
+	mapper, err := NewClaimMapper(ClaimMapping{
+		Email: []string{"$['https://example.com/email']", "$.email"},
+		Roles: []string{"$.resource_access['agent-auth-api'].roles"},
+	})
+	if err != nil {
+		t.Fatalf("NewClaimMapper() error = %v", err)
+	}
+
+	principal := mapper.Map(map[string]interface{}{
+		"sub":                       "user-1",
+		"email":                     "fallback@example.com",
+		"https://example.com/email": "user@example.com",
+		"roles":                     []interface{}{"system_admin"},
+		"resource_access": map[string]interface{}{
+			"agent-auth-api": map[string]interface{}{"roles": []interface{}{"app_admin"}},
+			"other-client":   map[string]interface{}{"roles": []interface{}{"workspace_admin"}},
+		},
+	})
+
+	if principal.Email != "user@example.com" {
+		t.Errorf("Email = %q, want user@example.com", principal.Email)
+	}
+	if !principal.HasRole(AppAdmin) || principal.HasRole(SystemAdmin) || principal.HasRole(WorkspaceAdmin) {
+		t.Errorf("Roles = %v, want only app_admin", principal.Roles)
+	}
+}
+
+func TestNewClaimMapperRejectsInvalidPaths(t *testing.T) {
+	for _, path := range []string{"$", "$.roles[unquoted]", "$.roles['open", "$..roles", "$.resource_access.*.roles", "$.resource_access[*].roles"} {
+		if _, err := NewClaimMapper(ClaimMapping{Roles: []string{path}}); err == nil {
+			t.Errorf("NewClaimMapper(%q) expected an error", path)
+		}
+	}
+}
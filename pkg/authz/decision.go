@@ -0,0 +1,148 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+)
+
+// Decision outcomes
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+// Kinds of check a decision was made by
+const (
+	CheckAuthenticate = "authenticate" // token validation and revocation
+	CheckWorkspace    = "workspace"    // workspace membership
+	CheckRole         = "role"         // JWT roles
+	CheckPermission   = "permission"   // route permissions
+	CheckScope        = "scope"        // token scopes
+	CheckResource     = "resource"     // project role permissions on a resource URN
+)
+
+// Decision is the record of one authorization evaluation: who asked for what,
+// the outcome, and the role and permission that granted it or why it was
+// refused
+type Decision struct {
+	TimestampUTC      time.Time `json:"timestamp_utc" bson:"TimestampUTC"`
+	RequestID         string    `json:"request_id,omitempty" bson:"RequestID,omitempty"`
+	Subject           string    `json:"subject,omitempty" bson:"Subject,omitempty"`
+	Email             string    `json:"email,omitempty" bson:"Email,omitempty"`
+	Route             string    `json:"route,omitempty" bson:"Route,omitempty"`
+	Resource          string    `json:"resource,omitempty" bson:"Resource,omitempty"`
+	Check             string    `json:"check" bson:"Check"`
+	Action            string    `json:"action,omitempty" bson:"Action,omitempty"`
+	WorkspaceID       string    `json:"workspace_id,omitempty" bson:"WorkspaceID,omitempty"`
+	ProjectID         string    `json:"project_id,omitempty" bson:"ProjectID,omitempty"`
+	Decision          string    `json:"decision" bson:"Decision"`
+	MatchedRole       string    `json:"matched_role,omitempty" bson:"MatchedRole,omitempty"`
+	MatchedPermission string    `json:"matched_permission,omitempty" bson:"MatchedPermission,omitempty"`
+	Reason            string    `json:"reason,omitempty" bson:"Reason,omitempty"`
+}
+
+// DecisionLogger receives every decision made while serving a request. It is
+// called on the request path, so it must not block.
+type DecisionLogger interface {
+	LogDecision(ctx context.Context, decision *Decision)
+}
+
+const DecisionLoggerContextKey = contextKey("decision_logger")
+
+// LogDecisions creates middleware that sends the decisions made by the
+// middleware and handlers after it to logger. It must run before
+// AuthMiddleware so failed authentication is logged too.
+func LogDecisions(logger DecisionLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), DecisionLoggerContextKey, logger)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// LogDecision completes decision with the details of r that were left empty
+// and passes it to the logger installed by LogDecisions, if any
+func LogDecision(r *http.Request, decision *Decision) {
+	logger, ok := r.Context().Value(DecisionLoggerContextKey).(DecisionLogger)
+	if !ok || logger == nil {
+		return
+	}
+
+	decision.TimestampUTC = time.Now().UTC()
+	decision.RequestID = middleware.GetReqID(r.Context())
+
+	// Decisions about another subject, such as those of /v1/authorize, name it
+	if principal, ok := PrincipalFromContext(r.Context()); ok && decision.Subject == "" {
+		decision.Subject = principal.Subject
+		decision.Email = principal.Email
+	}
+
+	if decision.Route == "" {
+		decision.Route = route(r)
+	}
+	if decision.WorkspaceID == "" {
+		if scope, ok := WorkspaceScopeFromContext(r.Context()); ok {
+			decision.WorkspaceID = scope.WorkspaceID
+		} else {
+			decision.WorkspaceID = chi.URLParam(r, "workspace_id")
+		}
+	}
+	if decision.ProjectID == "" {
+		decision.ProjectID = chi.URLParam(r, "project_id")
+	}
+
+	logger.LogDecision(r.Context(), decision)
+}
+
+// route returns the method and the route pattern matched so far, falling
+// back to the path before routing
+func route(r *http.Request) string {
+	path := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			path = pattern
+		}
+	}
+	return r.Method + " " + path
+}
+
+// deny logs a refusal and writes the matching error response
+func deny(w http.ResponseWriter, r *http.Request, decision *Decision, message string, status int) {
+	decision.Decision = DecisionDeny
+	if decision.Reason == "" {
+		decision.Reason = message
+	}
+	LogDecision(r, decision)
+	http.Error(w, message, status)
+}
+
+// allow logs a decision to let the request through
+func allow(r *http.Request, decision *Decision) {
+	decision.Decision = DecisionAllow
+	LogDecision(r, decision)
+}
+
+// grantingRole returns the first JWT role of principal that grants permission
+func grantingRole(principal *Principal, permission Permission) string {
+	for _, role := range principal.Roles {
+		if Role(role).HasPermission(permission) {
+			return role
+		}
+	}
+	return ""
+}
+
+// joinRoles lists roles for a decision's action
+func joinRoles(roles []Role) string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return strings.Join(names, ",")
+}
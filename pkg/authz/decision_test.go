@@ -0,0 +1,88 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordedDecisions []*Decision
+
+func (d *recordedDecisions) LogDecision(ctx context.Context, decision *Decision) {
+	*d = append(*d, decision)
+}
+
+func TestRequirePermissionLogsDecisions(t *testing.T) {
+	resolver := staticWorkspaceRoles{
+		"ws-1/owner@example.com":  WorkspaceRoleOwner,
+		"ws-1/viewer@example.com": WorkspaceRoleViewer,
+	}
+	workspaceID := func(r *http.Request) (string, error) {
+		return r.URL.Query().Get("workspace"), nil
+	}
+
+	var decisions recordedDecisions
+	handler := LogDecisions(&decisions)(
+		ResolveWorkspace(resolver, workspaceID)(
+			RequirePermission(WorkspaceUpdate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})),
+		),
+	)
+
+	serve := func(principal *Principal) {
+		req := httptest.NewRequest(http.MethodPut, "/?workspace=ws-1", nil)
+		req = req.WithContext(context.WithValue(req.Context(), PrincipalContextKey, principal))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(&Principal{Subject: "u-1", Email: "owner@example.com"})
+	if len(decisions) != 2 {
+		t.Fatalf("want membership and permission decisions, got %d", len(decisions))
+	}
+	granted := decisions[1]
+	if granted.Decision != DecisionAllow || granted.Check != CheckPermission ||
+		granted.MatchedRole != string(WorkspaceRoleOwner) || granted.MatchedPermission != string(WorkspaceUpdate) {
+		t.Errorf("unexpected allow %+v", granted)
+	}
+	if granted.Subject != "u-1" || granted.WorkspaceID != "ws-1" || granted.Route != "PUT /" {
+		t.Errorf("request details missing from %+v", granted)
+	}
+
+	decisions = nil
+	serve(&Principal{Subject: "u-2", Email: "viewer@example.com"})
+	denied := decisions[len(decisions)-1]
+	if denied.Decision != DecisionDeny || denied.Action != string(WorkspaceUpdate) ||
+		denied.Reason != "workspace role viewer does not grant workspace:update" {
+		t.Errorf("unexpected deny %+v", denied)
+	}
+
+	decisions = nil
+	serve(&Principal{Subject: "u-3", Email: "stranger@example.com"})
+	if len(decisions) != 1 || decisions[0].Check != CheckWorkspace || decisions[0].Decision != DecisionDeny {
+		t.Errorf("want a single membership denial, got %+v", decisions)
+	}
+}
+
+func TestRequireScopesLogsMissingScope(t *testing.T) {
+	var decisions recordedDecisions
+	handler := LogDecisions(&decisions)(
+		RequireScopes("roles:read", "roles:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/roles", nil)
+	req = req.WithContext(context.WithValue(req.Context(), PrincipalContextKey, &Principal{
+		Subject: "agent-1",
+		Scopes:  []string{"roles:read"},
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if len(decisions) != 1 || decisions[0].Reason != "missing scope roles:write" {
+		t.Errorf("unexpected decisions %+v", decisions)
+	}
+}
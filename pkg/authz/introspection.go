@@ -0,0 +1,227 @@
+package authz
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+)
+
+const (
+	defaultIntrospectionCacheTTL    = 5 * time.Minute
+	defaultIntrospectionInactiveTTL = 30 * time.Second
+	defaultIntrospectionCacheSize   = 10000
+)
+
+// ErrInactiveToken is returned when the introspection endpoint reports active=false
+var ErrInactiveToken = errors.New("token is not active")
+
+// IntrospectionConfig configures OAuth 2.0 token introspection (RFC 7662) for
+// opaque bearer tokens
+type IntrospectionConfig struct {
+	// Endpoint is the authorization server's introspection endpoint
+	Endpoint string `mapstructure:"endpoint"`
+
+	// ClientID and ClientSecret authenticate this API to the endpoint
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+
+	// Audiences lists the accepted aud values, empty accepts any audience
+	Audiences []string `mapstructure:"audiences"`
+
+	// MaxCacheTTLSeconds caps how long an active response is cached, responses
+	// are never cached past the token's exp
+	MaxCacheTTLSeconds int `mapstructure:"max_cache_ttl_seconds"`
+
+	// HTTPClient is used for introspection requests, it should carry a timeout
+	HTTPClient *http.Client `mapstructure:"-"`
+}
+
+type introspectionEntry struct {
+	key       string
+	claims    map[string]interface{}
+	active    bool
+	expiresAt time.Time
+}
+
+// Introspector validates opaque tokens against an introspection endpoint and
+// caches responses, keyed by a hash of the token, until the token expires.
+// Once the cache is full the least recently used response is evicted.
+type Introspector struct {
+	config      IntrospectionConfig
+	maxCacheTTL time.Duration
+
+	mu        sync.Mutex
+	cacheSize int
+	order     *list.List
+	cache     map[string]*list.Element
+}
+
+// NewIntrospector creates an introspector for the given configuration
+func NewIntrospector(config IntrospectionConfig) *Introspector {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Transport: tracing.NewTransport(nil), Timeout: defaultJWKSHTTPTimeout}
+	}
+
+	maxCacheTTL := time.Duration(config.MaxCacheTTLSeconds) * time.Second
+	if maxCacheTTL <= 0 {
+		maxCacheTTL = defaultIntrospectionCacheTTL
+	}
+
+	return &Introspector{
+		config:      config,
+		maxCacheTTL: maxCacheTTL,
+		cacheSize:   defaultIntrospectionCacheSize,
+		order:       list.New(),
+		cache:       make(map[string]*list.Element),
+	}
+}
+
+// Introspect returns the claims of an active token. Inactive tokens yield
+// ErrInactiveToken and are remembered briefly so replays stay cheap.
+func (i *Introspector) Introspect(ctx context.Context, token string) (map[string]interface{}, error) {
+	sum := sha256.Sum256([]byte(token))
+	cacheKey := hex.EncodeToString(sum[:])
+
+	if entry, ok := i.cached(cacheKey); ok {
+		if !entry.active {
+			return nil, ErrInactiveToken
+		}
+		return entry.claims, nil
+	}
+
+	claims, err := i.introspect(ctx, token)
+	if errors.Is(err, ErrInactiveToken) {
+		i.store(cacheKey, introspectionEntry{
+			expiresAt: time.Now().Add(defaultIntrospectionInactiveTTL),
+		})
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !i.acceptsAudience(claims["aud"]) {
+		return nil, fmt.Errorf("invalid audience")
+	}
+
+	expiresAt := time.Now().Add(i.maxCacheTTL)
+	if exp, ok := claims["exp"].(float64); ok {
+		tokenExpiry := time.Unix(int64(exp), 0)
+		if !tokenExpiry.After(time.Now()) {
+			return nil, ErrInactiveToken
+		}
+		if tokenExpiry.Before(expiresAt) {
+			expiresAt = tokenExpiry
+		}
+	}
+
+	i.store(cacheKey, introspectionEntry{
+		claims:    claims,
+		active:    true,
+		expiresAt: expiresAt,
+	})
+
+	return claims, nil
+}
+
+// introspect calls the introspection endpoint for token
+func (i *Introspector) introspect(ctx context.Context, token string) (map[string]interface{}, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.config.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(i.config.ClientID), url.QueryEscape(i.config.ClientSecret))
+
+	resp, err := i.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected introspection status code: %d", resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSResponseBytes)).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+
+	if active, _ := claims["active"].(bool); !active {
+		return nil, ErrInactiveToken
+	}
+
+	// Present the same claims shape as a validated JWT
+	delete(claims, "active")
+	return claims, nil
+}
+
+func (i *Introspector) acceptsAudience(aud interface{}) bool {
+	if len(i.config.Audiences) == 0 {
+		return true
+	}
+	for _, expected := range i.config.Audiences {
+		if hasAudience(aud, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Introspector) cached(key string) (introspectionEntry, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	element, ok := i.cache[key]
+	if !ok {
+		return introspectionEntry{}, false
+	}
+
+	entry := element.Value.(*introspectionEntry)
+	if time.Now().After(entry.expiresAt) {
+		i.order.Remove(element)
+		delete(i.cache, key)
+		return introspectionEntry{}, false
+	}
+
+	i.order.MoveToFront(element)
+	return *entry, true
+}
+
+func (i *Introspector) store(key string, entry introspectionEntry) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	entry.key = key
+	if element, ok := i.cache[key]; ok {
+		element.Value = &entry
+		i.order.MoveToFront(element)
+		return
+	}
+
+	i.cache[key] = i.order.PushFront(&entry)
+
+	if i.order.Len() > i.cacheSize {
+		oldest := i.order.Back()
+		i.order.Remove(oldest)
+		delete(i.cache, oldest.Value.(*introspectionEntry).key)
+	}
+}
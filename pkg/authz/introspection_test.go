@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateTokenIntrospection(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+
+		if id, secret, ok := r.BasicAuth(); !ok || id != "api" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.FormValue("token") {
+		case "active-token":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"active": true,
+				"sub":    "agent-1",
+				"aud":    "agent-auth-api",
+				"scope":  "read write",
+				"exp":    time.Now().Add(time.Hour).Unix(),
+			})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"active": false})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	provider := NewTokenProviderWithConfig(TokenProviderConfig{
+		Introspection: &IntrospectionConfig{
+			Endpoint:     server.URL,
+			ClientID:     "api",
+			ClientSecret: "secret",
+			Audiences:    []string{"agent-auth-api"},
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		claims, err := provider.ValidateToken("active-token", "", "")
+		if err != nil {
+			t.Fatalf("ValidateToken() error = %v", err)
+		}
+		if claims["sub"] != "agent-1" {
+			t.Errorf("sub = %v, want agent-1", claims["sub"])
+		}
+		if _, ok := claims["active"]; ok {
+			t.Error("active should not be exposed as a claim")
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("introspection calls = %d, want 1 for a cached token", got)
+	}
+
+	if _, err := provider.ValidateToken("revoked-token", "", ""); err == nil {
+		t.Error("expected inactive token to be rejected")
+	}
+}
+
+func TestValidateTokenOpaqueWithoutIntrospection(t *testing.T) {
+	provider := NewTokenProviderWithConfig(TokenProviderConfig{})
+
+	if _, err := provider.ValidateToken("opaque-token", "", ""); err == nil {
+		t.Error("expected opaque token to be rejected without introspection")
+	}
+}
+
+func TestIntrospectorEvictsLeastRecentlyUsed(t *testing.T) {
+	calls := map[string]int{}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.FormValue("token")]++
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"active": true,
+			"sub":    r.FormValue("token"),
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	introspector := NewIntrospector(IntrospectionConfig{Endpoint: server.URL})
+	introspector.cacheSize = 2
+
+	for _, token := range []string{"a", "b", "a", "c", "a", "c", "b"} {
+		if _, err := introspector.Introspect(context.Background(), token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// b was least recently used when c was stored, so only b is fetched again
+	want := map[string]int{"a": 1, "b": 2, "c": 1}
+	for token, n := range want {
+		if calls[token] != n {
+			t.Errorf("token %s introspected %d times, want %d", token, calls[token], n)
+		}
+	}
+	if n := introspector.order.Len(); n != 2 {
+		t.Errorf("cache holds %d entries, want 2", n)
+	}
+}
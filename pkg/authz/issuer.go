@@ -0,0 +1,245 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+)
+
+// IssuerConfig describes a trusted token issuer. When JWKSURL is empty the key
+// set location is discovered from the issuer's OpenID configuration.
+type IssuerConfig struct {
+	// Issuer must match the token's iss claim, an empty issuer accepts any
+	// iss that no other issuer claims
+	Issuer string `mapstructure:"issuer"`
+
+	// JWKSURL is the endpoint publishing the issuer's signing keys
+	JWKSURL string `mapstructure:"jwks_url"`
+
+	// DiscoveryURL overrides <issuer>/.well-known/openid-configuration
+	DiscoveryURL string `mapstructure:"discovery_url"`
+
+	// Audiences lists the accepted aud values, empty accepts any audience
+	Audiences []string `mapstructure:"audiences"`
+
+	// AllowedAlgorithms lists the signing algorithms accepted from the issuer
+	AllowedAlgorithms []string `mapstructure:"allowed_algorithms"`
+
+	// Users marks an issuer of user tokens. Only these are trusted for the
+	// caller's email, which workspace membership, invitations and ownership
+	// transfers are keyed on, and only when the token's email_verified claim
+	// is true; principals of other issuers, such as the agents' IdP, have
+	// none.
+	Users bool `mapstructure:"users"`
+
+	// AllowedRoles lists the system roles the issuer's tokens may grant,
+	// other roles are dropped. Empty accepts every role from user issuers
+	// and none from the others.
+	AllowedRoles []string `mapstructure:"allowed_roles"`
+}
+
+// openIDConfiguration holds the discovery document fields we rely on
+type openIDConfiguration struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// trustedIssuer verifies tokens for one IssuerConfig. Its key cache is created
+// on first use so an unreachable IdP does not prevent the service from starting.
+type trustedIssuer struct {
+	config      IssuerConfig
+	cacheConfig JWKSCacheConfig
+
+	mu            sync.Mutex
+	jwksCache     *JWKSCache
+	lastDiscovery time.Time
+}
+
+func newTrustedIssuer(config IssuerConfig, cacheConfig JWKSCacheConfig) *trustedIssuer {
+	config.Issuer = strings.TrimSpace(config.Issuer)
+	if len(config.AllowedAlgorithms) == 0 {
+		config.AllowedAlgorithms = []string{"RS256"}
+	}
+	if cacheConfig.HTTPClient == nil {
+		cacheConfig.HTTPClient = &http.Client{Transport: tracing.NewTransport(nil), Timeout: defaultJWKSHTTPTimeout}
+	}
+
+	return &trustedIssuer{
+		config:      config,
+		cacheConfig: cacheConfig,
+	}
+}
+
+// keys returns the issuer's key cache, running discovery first if needed
+func (i *trustedIssuer) keys(ctx context.Context) (*JWKSCache, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.jwksCache != nil {
+		return i.jwksCache, nil
+	}
+
+	jwksURL := i.config.JWKSURL
+	if jwksURL == "" {
+		// Failed discoveries are rate limited like unknown kids
+		if time.Since(i.lastDiscovery) < i.cacheConfig.MinRefreshInterval {
+			return nil, fmt.Errorf("openid discovery for %s recently failed", i.config.Issuer)
+		}
+		i.lastDiscovery = time.Now()
+
+		discovered, err := i.discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jwksURL = discovered
+	}
+
+	i.jwksCache = NewJWKSCacheWithConfig(jwksURL, i.cacheConfig)
+	return i.jwksCache, nil
+}
+
+// discover fetches the OpenID configuration and returns its jwks_uri
+func (i *trustedIssuer) discover(ctx context.Context) (string, error) {
+	discoveryURL := i.config.DiscoveryURL
+	if discoveryURL == "" {
+		if i.config.Issuer == "" {
+			return "", fmt.Errorf("issuer without jwks_url requires an issuer or discovery_url")
+		}
+		discoveryURL = strings.TrimSuffix(i.config.Issuer, "/") + "/.well-known/openid-configuration"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create discovery request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := i.cacheConfig.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch openid configuration: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected openid configuration status code: %d", resp.StatusCode)
+	}
+
+	var config openIDConfiguration
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSResponseBytes)).Decode(&config); err != nil {
+		return "", fmt.Errorf("failed to decode openid configuration: %w", err)
+	}
+
+	// OpenID Connect Discovery 1.0 section 4.3: the issuer must match exactly
+	if i.config.Issuer != "" && config.Issuer != i.config.Issuer {
+		return "", fmt.Errorf("openid configuration issuer %q does not match %q", config.Issuer, i.config.Issuer)
+	}
+	if config.JWKSURI == "" {
+		return "", fmt.Errorf("openid configuration for %s has no jwks_uri", i.config.Issuer)
+	}
+
+	return config.JWKSURI, nil
+}
+
+// publicKey returns the key with the given kid for verifying a signature made with alg
+func (i *trustedIssuer) publicKey(ctx context.Context, kid, alg string) (interface{}, error) {
+	cache, err := i.keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := cache.FetchKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	keyData, ok := key.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid key format")
+	}
+
+	return parsePublicKey(keyData, alg)
+}
+
+// acceptsAudience reports whether aud satisfies the issuer's audience rules
+func (i *trustedIssuer) acceptsAudience(aud interface{}) bool {
+	if len(i.config.Audiences) == 0 {
+		return true
+	}
+	for _, expected := range i.config.Audiences {
+		if hasAudience(aud, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+// scope limits principal to what the issuer is trusted to assert
+func (i *trustedIssuer) scope(principal *Principal) *Principal {
+	if !i.config.Users || !emailVerified(principal.Claims) {
+		principal.Email = ""
+	}
+	if i.config.Users && len(i.config.AllowedRoles) == 0 {
+		return principal
+	}
+
+	var roles []string
+	for _, role := range principal.Roles {
+		for _, allowed := range i.config.AllowedRoles {
+			if role == allowed {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	principal.Roles = roles
+	return principal
+}
+
+func (i *trustedIssuer) close() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.jwksCache != nil {
+		i.jwksCache.Close()
+	}
+}
+
+// emailVerified reports whether the email_verified claim is true. Some IdPs
+// send it as a string.
+func emailVerified(claims map[string]interface{}) bool {
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// hasAudience reports whether the aud claim, a string or an array, contains expected
+func hasAudience(aud interface{}, expected string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == expected
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == expected {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range v {
+			if s == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
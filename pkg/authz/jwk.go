@@ -0,0 +1,192 @@
+package authz
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Signing algorithms supported by TokenProvider, grouped by the JWK key type they require
+var algorithmKeyTypes = map[string]string{
+	"RS256": "RSA", "RS384": "RSA", "RS512": "RSA",
+	"PS256": "RSA", "PS384": "RSA", "PS512": "RSA",
+	"ES256": "EC", "ES384": "EC", "ES512": "EC",
+	"EdDSA": "OKP",
+}
+
+// Curves required by each ECDSA algorithm
+var algorithmCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+// parsePublicKey converts a JWK into a public key usable to verify a token signed with alg
+func parsePublicKey(jwk map[string]interface{}, alg string) (crypto.PublicKey, error) {
+	kty, _ := jwk["kty"].(string)
+
+	expectedKty, ok := algorithmKeyTypes[alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm %s", alg)
+	}
+	if kty != "" && kty != expectedKty {
+		return nil, fmt.Errorf("key type %s cannot verify %s signatures", kty, alg)
+	}
+
+	// A key pinned to one algorithm must not be used with another
+	if keyAlg, ok := jwk["alg"].(string); ok && keyAlg != "" && keyAlg != alg {
+		return nil, fmt.Errorf("key is restricted to %s", keyAlg)
+	}
+	if use, ok := jwk["use"].(string); ok && use != "" && use != "sig" {
+		return nil, fmt.Errorf("key is not a signing key")
+	}
+
+	switch expectedKty {
+	case "RSA":
+		return parseRSAPublicKey(jwk)
+	case "EC":
+		return parseECPublicKey(jwk, algorithmCurves[alg])
+	default:
+		return parseEdDSAPublicKey(jwk)
+	}
+}
+
+// parseRSAPublicKey converts a JWK into an RSA public key, using the n/e
+// parameters when present and the x5c certificate chain otherwise
+func parseRSAPublicKey(jwk map[string]interface{}) (*rsa.PublicKey, error) {
+	if _, ok := jwk["n"]; !ok {
+		key, err := parseX5CPublicKey(jwk)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("certificate does not contain an RSA public key")
+		}
+		return rsaKey, nil
+	}
+
+	n, err := decodeJWKInt(jwk, "n")
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeJWKInt(jwk, "e")
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid RSA exponent")
+	}
+	if n.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA key is too small: %d bits", n.BitLen())
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+// parseECPublicKey converts an EC JWK into an ECDSA public key on the expected curve
+func parseECPublicKey(jwk map[string]interface{}, expectedCurve string) (*ecdsa.PublicKey, error) {
+	crv, _ := jwk["crv"].(string)
+	if crv != expectedCurve {
+		return nil, fmt.Errorf("expected curve %s, got %q", expectedCurve, crv)
+	}
+
+	var curve elliptic.Curve
+	switch crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", crv)
+	}
+
+	x, err := decodeJWKInt(jwk, "x")
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeJWKInt(jwk, "y")
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("point is not on curve %s", crv)
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// parseEdDSAPublicKey converts an OKP JWK into an Ed25519 public key
+func parseEdDSAPublicKey(jwk map[string]interface{}) (ed25519.PublicKey, error) {
+	if crv, _ := jwk["crv"].(string); crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported OKP curve %q", crv)
+	}
+
+	x, err := decodeJWKBytes(jwk, "x")
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 key length: %d", len(x))
+	}
+
+	return ed25519.PublicKey(x), nil
+}
+
+// parseX5CPublicKey extracts the public key from the first certificate of the
+// "x5c" (X.509 Certificate Chain) field, which holds base64-encoded DER
+func parseX5CPublicKey(jwk map[string]interface{}) (crypto.PublicKey, error) {
+	x5c, ok := jwk["x5c"].([]interface{})
+	if !ok || len(x5c) == 0 {
+		return nil, fmt.Errorf("x5c field is missing or invalid")
+	}
+
+	encoded, ok := x5c[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("x5c field is missing or invalid")
+	}
+
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	return cert.PublicKey, nil
+}
+
+func decodeJWKInt(jwk map[string]interface{}, name string) (*big.Int, error) {
+	b, err := decodeJWKBytes(jwk, name)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// decodeJWKBytes decodes a base64url member, tolerating padded input
+func decodeJWKBytes(jwk map[string]interface{}, name string) ([]byte, error) {
+	value, ok := jwk[name].(string)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("%s field is missing or invalid", name)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+
+	return b, nil
+}
@@ -1,56 +1,301 @@
 package authz
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// maxJWKSResponseBytes caps how much of a JWKS response is read
+	maxJWKSResponseBytes = 1 << 20
+
+	defaultJWKSCacheTTL           = 15 * time.Minute
+	defaultJWKSMinTTL             = 1 * time.Minute
+	defaultJWKSMaxTTL             = 24 * time.Hour
+	defaultJWKSMinRefreshInterval = 30 * time.Second
+	defaultJWKSHTTPTimeout        = 10 * time.Second
 )
 
+// JWKSCacheConfig controls how a JWKSCache fetches and retains keys
+type JWKSCacheConfig struct {
+	// HTTPClient is used for all JWKS requests, it should carry a timeout
+	HTTPClient *http.Client
+
+	// DefaultTTL is used when the JWKS response carries no caching headers
+	DefaultTTL time.Duration
+
+	// MinTTL and MaxTTL clamp the TTL advertised by the identity provider
+	MinTTL time.Duration
+	MaxTTL time.Duration
+
+	// MinRefreshInterval is the minimum time between two JWKS fetches, it
+	// bounds how often tokens with unknown kids can make us hit the IdP
+	MinRefreshInterval time.Duration
+
+	// BackgroundRefresh refreshes the key set shortly before it expires so
+	// requests on the hot path never wait on the IdP
+	BackgroundRefresh bool
+}
+
+// DefaultJWKSCacheConfig returns the cache configuration read from environment variables
+func DefaultJWKSCacheConfig() JWKSCacheConfig {
+	return JWKSCacheConfig{
+		HTTPClient: &http.Client{
+			Transport: tracing.NewTransport(nil),
+			Timeout:   getEnvDuration("API_AUTH_JWKS_HTTP_TIMEOUT_SECONDS", defaultJWKSHTTPTimeout),
+		},
+		DefaultTTL:         getEnvDuration("API_AUTH_JWKS_CACHE_TTL_SECONDS", defaultJWKSCacheTTL),
+		MinTTL:             defaultJWKSMinTTL,
+		MaxTTL:             defaultJWKSMaxTTL,
+		MinRefreshInterval: getEnvDuration("API_AUTH_JWKS_MIN_REFRESH_INTERVAL_SECONDS", defaultJWKSMinRefreshInterval),
+		BackgroundRefresh:  os.Getenv("API_AUTH_JWKS_BACKGROUND_REFRESH") != "false",
+	}
+}
+
+// JWKSCache caches the keys published at a JWKS endpoint. Keys are kept until
+// the TTL advertised by the endpoint expires, keys removed from the endpoint
+// are evicted on the next refresh, and refetches triggered by unknown kids are
+// rate limited by MinRefreshInterval.
 type JWKSCache struct {
-	mu      sync.RWMutex
-	keys    map[string]interface{}
-	jwksURL string
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	jwksURL   string
+	expiresAt time.Time
+
+	// refreshMu serializes fetches so concurrent misses share one request
+	refreshMu   sync.Mutex
+	lastAttempt time.Time
+
+	config JWKSCacheConfig
+	cancel context.CancelFunc
 }
 
+// NewJWKSCache creates a cache for the given JWKS URL using DefaultJWKSCacheConfig
 func NewJWKSCache(jwksURL string) *JWKSCache {
-	return &JWKSCache{
+	return NewJWKSCacheWithConfig(jwksURL, DefaultJWKSCacheConfig())
+}
+
+// NewJWKSCacheWithConfig creates a cache for the given JWKS URL. When background
+// refresh is enabled the cache owns a goroutine that is stopped by Close.
+func NewJWKSCacheWithConfig(jwksURL string, config JWKSCacheConfig) *JWKSCache {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Transport: tracing.NewTransport(nil), Timeout: defaultJWKSHTTPTimeout}
+	}
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = defaultJWKSCacheTTL
+	}
+	if config.MinTTL <= 0 {
+		config.MinTTL = defaultJWKSMinTTL
+	}
+	if config.MaxTTL < config.MinTTL {
+		config.MaxTTL = defaultJWKSMaxTTL
+	}
+	if config.MinRefreshInterval < 0 {
+		config.MinRefreshInterval = 0
+	}
+
+	j := &JWKSCache{
 		keys:    make(map[string]interface{}),
 		jwksURL: jwksURL,
+		config:  config,
+	}
+
+	if config.BackgroundRefresh {
+		ctx, cancel := context.WithCancel(context.Background())
+		j.cancel = cancel
+		go j.refreshLoop(ctx)
 	}
+
+	return j
 }
 
+// Close stops the background refresh goroutine, if any
+func (j *JWKSCache) Close() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+}
+
+// FetchKey returns the JWK with the given kid. A cached key is returned while
+// the key set is fresh; otherwise the key set is refetched, subject to the
+// refresh rate limit. If a refresh fails, a previously cached key is still
+// served so an IdP outage does not reject tokens signed with a known key.
 func (j *JWKSCache) FetchKey(kid string) (interface{}, error) {
-	j.mu.RLock()
-	if key, found := j.keys[kid]; found {
-		j.mu.RUnlock()
+	key, found, fresh := j.lookup(kid)
+	if found && fresh {
 		return key, nil
 	}
-	j.mu.RUnlock()
 
-	// Fetch and parse JWKS
-	resp, err := http.Get(j.jwksURL)
-	if err != nil {
+	if err := j.refresh(context.Background(), false); err != nil {
+		if found {
+			return key, nil
+		}
 		return nil, err
 	}
+
+	if key, found, _ = j.lookup(kid); found {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("key with kid %s not found", kid)
+}
+
+// lookup reports whether kid is cached and whether the key set is still fresh
+func (j *JWKSCache) lookup(kid string) (interface{}, bool, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	key, found := j.keys[kid]
+	return key, found, time.Now().Before(j.expiresAt)
+}
+
+// refresh fetches the key set unless another fetch happened within
+// MinRefreshInterval. force skips the rate limit and is used by the background
+// loop, which schedules its own fetches.
+func (j *JWKSCache) refresh(ctx context.Context, force bool) error {
+	j.refreshMu.Lock()
+	defer j.refreshMu.Unlock()
+
+	// Another caller may have refreshed while we were waiting for the lock
+	if !force && time.Since(j.lastAttempt) < j.config.MinRefreshInterval {
+		return nil
+	}
+	j.lastAttempt = time.Now()
+
+	ctx, span := tracing.Start(ctx, "jwks.fetch", attribute.String("jwks.url", j.jwksURL))
+	defer span.End()
+
+	keys, ttl, err := j.fetch(ctx)
+	metrics.RecordJWKSFetch(j.jwksURL, err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.SetAttributes(attribute.Int("jwks.keys", len(keys)))
+
+	// Replace the whole set so keys rotated out by the IdP are evicted
+	j.mu.Lock()
+	j.keys = keys
+	j.expiresAt = time.Now().Add(ttl)
+	j.mu.Unlock()
+
+	return nil
+}
+
+// fetch downloads and decodes the key set and returns it with its TTL
+func (j *JWKSCache) fetch(ctx context.Context) (map[string]interface{}, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.jwksURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected JWKS status code: %d", resp.StatusCode)
+	}
+
 	var jwks struct {
 		Keys []map[string]interface{} `json:"keys"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
-		return nil, err
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSResponseBytes)).Decode(&jwks); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode JWKS: %w", err)
 	}
 
-	// Update cache
-	j.mu.Lock()
-	defer j.mu.Unlock()
+	keys := make(map[string]interface{}, len(jwks.Keys))
 	for _, key := range jwks.Keys {
-		if key["kid"] == kid {
-			j.keys[kid] = key
-			return key, nil
+		kid, ok := key["kid"].(string)
+		if !ok || kid == "" {
+			continue
 		}
+		keys[kid] = key
 	}
 
-	return nil, fmt.Errorf("key with kid %s not found", kid)
+	return keys, j.ttlFromHeaders(resp.Header), nil
+}
+
+// ttlFromHeaders derives the TTL from Cache-Control max-age or Expires
+func (j *JWKSCache) ttlFromHeaders(header http.Header) time.Duration {
+	ttl := j.config.DefaultTTL
+
+	if cacheControl := header.Get("Cache-Control"); cacheControl != "" {
+		for _, directive := range strings.Split(cacheControl, ",") {
+			directive = strings.TrimSpace(strings.ToLower(directive))
+			if directive == "no-cache" || directive == "no-store" {
+				return j.config.MinTTL
+			}
+			if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+				if seconds, err := strconv.Atoi(value); err == nil {
+					ttl = time.Duration(seconds) * time.Second
+				}
+			}
+		}
+	} else if expires := header.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			ttl = time.Until(t)
+		}
+	}
+
+	if ttl < j.config.MinTTL {
+		ttl = j.config.MinTTL
+	}
+	if ttl > j.config.MaxTTL {
+		ttl = j.config.MaxTTL
+	}
+
+	return ttl
+}
+
+// refreshLoop keeps the key set warm by refetching it before it expires
+func (j *JWKSCache) refreshLoop(ctx context.Context) {
+	for {
+		wait := j.config.MinRefreshInterval
+		if err := j.refresh(ctx, true); err == nil {
+			j.mu.RLock()
+			ttl := time.Until(j.expiresAt)
+			j.mu.RUnlock()
+
+			// Refresh once 90% of the TTL has elapsed
+			wait = ttl - ttl/10
+		}
+		if wait < time.Second {
+			wait = time.Second
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return fallback
 }
@@ -0,0 +1,203 @@
+package authz
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// jwksServer serves a key set that tests can rotate or break between fetches
+type jwksServer struct {
+	mu       sync.Mutex
+	kids     []string
+	header   http.Header
+	failing  bool
+	requests int
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if s.failing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	keys := []map[string]interface{}{}
+	for _, kid := range s.kids {
+		keys = append(keys, map[string]interface{}{"kid": kid, "kty": "RSA"})
+	}
+	for name, values := range s.header {
+		w.Header()[name] = values
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+func (s *jwksServer) set(failing bool, kids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+	s.kids = kids
+}
+
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func newTestJWKSCache(t *testing.T, minRefreshInterval time.Duration, kids ...string) (*JWKSCache, *jwksServer) {
+	t.Helper()
+	upstream := &jwksServer{kids: kids}
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+
+	cache := NewJWKSCacheWithConfig(server.URL, JWKSCacheConfig{
+		HTTPClient:         &http.Client{Timeout: time.Second},
+		MinRefreshInterval: minRefreshInterval,
+	})
+	t.Cleanup(cache.Close)
+	return cache, upstream
+}
+
+func TestJWKSCacheTTLFromHeaders(t *testing.T) {
+	cache := NewJWKSCacheWithConfig("", JWKSCacheConfig{
+		DefaultTTL: 15 * time.Minute,
+		MinTTL:     time.Minute,
+		MaxTTL:     24 * time.Hour,
+	})
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"no caching headers", http.Header{}, 15 * time.Minute},
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=3600"}}, time.Hour},
+		{"max-age below minimum", http.Header{"Cache-Control": {"max-age=5"}}, time.Minute},
+		{"max-age above maximum", http.Header{"Cache-Control": {"max-age=604800"}}, 24 * time.Hour},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}, time.Minute},
+		{"invalid max-age", http.Header{"Cache-Control": {"max-age=soon"}}, 15 * time.Minute},
+		{"max-age wins over expires", http.Header{
+			"Cache-Control": {"max-age=7200"},
+			"Expires":       {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+		}, 2 * time.Hour},
+		{"expired expires", http.Header{"Expires": {time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)}}, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cache.ttlFromHeaders(tt.header); got != tt.want {
+				t.Errorf("TTL is %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("expires", func(t *testing.T) {
+		header := http.Header{"Expires": {time.Now().Add(2 * time.Hour).UTC().Format(http.TimeFormat)}}
+		if got := cache.ttlFromHeaders(header); got < 2*time.Hour-5*time.Second || got > 2*time.Hour {
+			t.Errorf("TTL is %v, want about 2h", got)
+		}
+	})
+}
+
+func TestJWKSCacheFetchUsesAdvertisedTTL(t *testing.T) {
+	cache, upstream := newTestJWKSCache(t, 0, "a")
+	upstream.header = http.Header{"Cache-Control": {"max-age=7200"}}
+
+	if _, err := cache.FetchKey("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	cache.mu.RLock()
+	ttl := time.Until(cache.expiresAt)
+	cache.mu.RUnlock()
+	if ttl < 2*time.Hour-5*time.Second || ttl > 2*time.Hour {
+		t.Errorf("key set expires in %v, want about 2h", ttl)
+	}
+
+	if _, err := cache.FetchKey("a"); err != nil {
+		t.Fatal(err)
+	}
+	if n := upstream.count(); n != 1 {
+		t.Errorf("fresh key set fetched %d times, want 1", n)
+	}
+}
+
+func TestJWKSCacheRateLimitsUnknownKidRefetch(t *testing.T) {
+	cache, upstream := newTestJWKSCache(t, time.Hour, "a")
+
+	if _, err := cache.FetchKey("a"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := cache.FetchKey("unknown"); err == nil {
+			t.Fatal("unknown kid found")
+		}
+	}
+	if n := upstream.count(); n != 1 {
+		t.Errorf("JWKS fetched %d times within the refresh interval, want 1", n)
+	}
+
+	// Once the interval has passed a miss may fetch again
+	cache.refreshMu.Lock()
+	cache.lastAttempt = time.Now().Add(-time.Hour)
+	cache.refreshMu.Unlock()
+
+	upstream.set(false, "a", "b")
+	if _, err := cache.FetchKey("b"); err != nil {
+		t.Errorf("rotated in key not found: %v", err)
+	}
+	if n := upstream.count(); n != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", n)
+	}
+}
+
+func TestJWKSCacheEvictsRemovedKeys(t *testing.T) {
+	cache, upstream := newTestJWKSCache(t, 0, "a", "b")
+
+	if _, err := cache.FetchKey("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The IdP rotates a out; looking up c refetches the key set
+	upstream.set(false, "b", "c")
+	if _, err := cache.FetchKey("c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.FetchKey("a"); err == nil {
+		t.Error("removed key is still served")
+	}
+	if _, err := cache.FetchKey("b"); err != nil {
+		t.Errorf("retained key not found: %v", err)
+	}
+}
+
+func TestJWKSCacheServesStaleKeysWhenRefreshFails(t *testing.T) {
+	cache, upstream := newTestJWKSCache(t, 0, "a")
+
+	if _, err := cache.FetchKey("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	upstream.set(true)
+	cache.mu.Lock()
+	cache.expiresAt = time.Now().Add(-time.Second)
+	cache.mu.Unlock()
+
+	if _, err := cache.FetchKey("a"); err != nil {
+		t.Errorf("cached key not served while the IdP fails: %v", err)
+	}
+	if n := upstream.count(); n != 2 {
+		t.Errorf("JWKS fetched %d times, want a refresh attempt", n)
+	}
+	if _, err := cache.FetchKey("unknown"); err == nil {
+		t.Error("unknown kid found while the IdP fails")
+	}
+}
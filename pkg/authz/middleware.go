@@ -2,9 +2,13 @@ package authz
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 // Add this at the package level
@@ -19,131 +23,217 @@ func AuthMiddleware(provider *TokenProvider, audience, issuer string) func(http.
 			// Extract token from Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				metrics.RecordAuthFailure(metrics.AuthMissingHeader)
+				deny(w, r, &Decision{Check: CheckAuthenticate}, "Authorization header required", http.StatusUnauthorized)
 				return
 			}
 
 			// Remove "Bearer " prefix
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 			if tokenString == authHeader || tokenString == "" {
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+				metrics.RecordAuthFailure(metrics.AuthMalformedHeader)
+				deny(w, r, &Decision{Check: CheckAuthenticate}, "Invalid authorization header format", http.StatusUnauthorized)
 				return
 			}
 
 			// Validate the token
 			claims, err := provider.ValidateToken(tokenString, audience, issuer)
 			if err != nil {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				metrics.RecordAuthFailure(failureReason(err))
+				deny(w, r, &Decision{Check: CheckAuthenticate, Reason: err.Error()}, "Invalid token", http.StatusUnauthorized)
 				return
 			}
 
-			// Store claims in context for later use
+			// Store claims and the principal they map to in context for later use
 			ctx := context.WithValue(r.Context(), ClaimsContextKey, claims)
+			ctx = context.WithValue(ctx, PrincipalContextKey, provider.Principal(claims))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// RequireRoles creates middleware that checks if the user has any of the specified roles
-func RequireRoles(roles ...Role) func(http.Handler) http.Handler {
+// failureReason classifies a token validation error for the auth failure
+// metric
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidAudience), errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return metrics.AuthBadAudience
+	case errors.Is(err, ErrInvalidIssuer), errors.Is(err, ErrUntrustedIssuer), errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return metrics.AuthBadIssuer
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return metrics.AuthBadSignature
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return metrics.AuthExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return metrics.AuthNotYetValid
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		return metrics.AuthKeyUnavailable
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return metrics.AuthMalformedToken
+	case errors.Is(err, ErrInactiveToken):
+		return metrics.AuthInactive
+	default:
+		return metrics.AuthInvalid
+	}
+}
+
+// RevocationChecker reports whether a validated token has been revoked
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims map[string]interface{}) (bool, error)
+}
+
+// RejectRevoked creates middleware that rejects tokens revoked after issuance.
+// It must run after AuthMiddleware. Tokens are rejected when the check fails
+// so an unavailable denylist cannot let a revoked token through.
+func RejectRevoked(checker RevocationChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			claims, ok := r.Context().Value(ClaimsContextKey).(map[string]interface{})
 			if !ok {
-				http.Error(w, "Unauthorized: no claims found", http.StatusUnauthorized)
+				deny(w, r, &Decision{Check: CheckAuthenticate}, "Unauthorized: no claims found", http.StatusUnauthorized)
+				return
+			}
+
+			revoked, err := checker.IsRevoked(r.Context(), claims)
+			if err != nil {
+				metrics.RecordAuthFailure(metrics.AuthRevocationUnavailable)
+				deny(w, r, &Decision{Check: CheckAuthenticate, Reason: err.Error()}, "Unable to verify token revocation", http.StatusServiceUnavailable)
+				return
+			}
+			if revoked {
+				metrics.RecordAuthFailure(metrics.AuthRevoked)
+				deny(w, r, &Decision{Check: CheckAuthenticate}, "Token has been revoked", http.StatusUnauthorized)
 				return
 			}
 
-			// Get roles from claims (adjust the claim key based on your JWT structure)
-			userRoles, ok := claims["roles"].([]interface{})
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRoles creates middleware that checks if the user has any of the specified roles
+func RequireRoles(roles ...Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decision := &Decision{Check: CheckRole, Action: joinRoles(roles)}
+
+			principal, ok := PrincipalFromContext(r.Context())
 			if !ok {
-				http.Error(w, "Unauthorized: no roles found", http.StatusForbidden)
+				deny(w, r, decision, "Unauthorized: no claims found", http.StatusUnauthorized)
+				return
+			}
+
+			if len(principal.Roles) == 0 {
+				deny(w, r, decision, "Unauthorized: no roles found", http.StatusForbidden)
 				return
 			}
 
 			// Check if user has any of the required roles
-			hasRole := false
-			for _, userRole := range userRoles {
-				roleStr, ok := userRole.(string)
-				if !ok {
-					continue
-				}
-				for _, requiredRole := range roles {
-					if Role(roleStr) == requiredRole {
-						hasRole = true
-						break
-					}
+			for _, requiredRole := range roles {
+				if principal.HasRole(requiredRole) {
+					decision.MatchedRole = string(requiredRole)
+					break
 				}
 			}
 
-			if !hasRole {
-				http.Error(w, "Forbidden: insufficient roles", http.StatusForbidden)
+			if decision.MatchedRole == "" {
+				decision.Reason = "holds none of the roles " + decision.Action
+				deny(w, r, decision, "Forbidden: insufficient roles", http.StatusForbidden)
 				return
 			}
 
+			allow(r, decision)
+
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// RequireScopes creates middleware that checks if the user has all the specified scopes
-func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+// RequirePermission creates middleware that checks if the user is granted the
+// permission. Within a workspace resolved by ResolveWorkspace it is granted by
+// the caller's workspace role, or by the system administrator JWT role, so
+// other JWT roles cannot reach into workspaces the caller does not belong to.
+// Elsewhere the user's JWT roles are resolved through RolePermissions.
+func RequirePermission(permission Permission) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims, ok := r.Context().Value(ClaimsContextKey).(map[string]interface{})
+			decision := &Decision{Check: CheckPermission, Action: string(permission)}
+
+			principal, ok := PrincipalFromContext(r.Context())
 			if !ok {
-				http.Error(w, "Unauthorized: no claims found", http.StatusUnauthorized)
+				deny(w, r, decision, "Unauthorized: no claims found", http.StatusUnauthorized)
 				return
 			}
 
-			// Get scopes from claims (adjust based on your JWT structure)
-			// Some providers use space-separated string, others use array
-			var userScopes []string
-			switch scopeClaim := claims["scope"].(type) {
-			case string:
-				userScopes = strings.Split(scopeClaim, " ")
-			case []interface{}:
-				for _, s := range scopeClaim {
-					if str, ok := s.(string); ok {
-						userScopes = append(userScopes, str)
-					}
+			if scope, ok := WorkspaceScopeFromContext(r.Context()); ok {
+				switch {
+				case scope.Role.HasPermission(permission):
+					decision.MatchedRole = string(scope.Role)
+				case principal.HasRole(SystemAdmin) && SystemAdmin.HasPermission(permission):
+					decision.MatchedRole = string(SystemAdmin)
+				default:
+					decision.Reason = "workspace role " + string(scope.Role) + " does not grant " + string(permission)
+				}
+			} else {
+				decision.MatchedRole = grantingRole(principal, permission)
+				if decision.MatchedRole == "" {
+					decision.Reason = "no role grants " + string(permission)
 				}
-			default:
-				http.Error(w, "Unauthorized: invalid scope format", http.StatusForbidden)
+			}
+
+			if decision.MatchedRole == "" {
+				deny(w, r, decision, "Forbidden: insufficient permissions", http.StatusForbidden)
+				return
+			}
+
+			decision.MatchedPermission = string(permission)
+			allow(r, decision)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScopes creates middleware that checks if the user has all the specified scopes
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decision := &Decision{Check: CheckScope, Action: strings.Join(scopes, " ")}
+
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				deny(w, r, decision, "Unauthorized: no claims found", http.StatusUnauthorized)
 				return
 			}
 
 			// Check if user has all required scopes
 			for _, requiredScope := range scopes {
-				hasScope := false
-				for _, userScope := range userScopes {
-					if userScope == requiredScope {
-						hasScope = true
-						break
-					}
-				}
-				if !hasScope {
-					http.Error(w, "Forbidden: missing required scope", http.StatusForbidden)
+				if !principal.HasScope(requiredScope) {
+					decision.Reason = "missing scope " + requiredScope
+					deny(w, r, decision, "Forbidden: missing required scope", http.StatusForbidden)
 					return
 				}
 			}
 
+			decision.MatchedPermission = decision.Action
+			allow(r, decision)
+
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// GetEmailFromClaims extracts email from JWT claims
+// GetEmailFromClaims returns the email of the authenticated principal, as
+// mapped from the token claims
 func GetEmailFromClaims(r *http.Request) (string, error) {
-	claims, ok := r.Context().Value(ClaimsContextKey).(map[string]interface{})
-	if !ok {
+	principal, err := GetPrincipal(r)
+	if err != nil {
 		return "", fmt.Errorf("no claims found")
 	}
 
-	email, ok := claims["email"].(string)
-	if !ok {
+	if principal.Email == "" {
 		return "", fmt.Errorf("no email found in claims")
 	}
 
-	return email, nil
+	return principal.Email, nil
 }
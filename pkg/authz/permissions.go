@@ -29,28 +29,128 @@ const (
 	AppDelete Permission = "app:delete"
 	AppList   Permission = "app:list"
 	AppDeploy Permission = "app:deploy"
+
+	// Project permissions
+	ProjectCreate Permission = "project:create"
+	ProjectRead   Permission = "project:read"
+	ProjectUpdate Permission = "project:update"
+	ProjectDelete Permission = "project:delete"
+	ProjectList   Permission = "project:list"
+	ProjectWatch  Permission = "project:watch"
+
+	// Ownership transfer permissions
+	WorkspaceTransfer Permission = "workspace:transfer"
+	ProjectTransfer   Permission = "project:transfer"
+
+	// Project membership permissions
+	ProjectMemberList   Permission = "project_member:list"
+	ProjectMemberAdd    Permission = "project_member:add"
+	ProjectMemberRemove Permission = "project_member:remove"
+
+	// Project role permissions
+	RoleCreate Permission = "role:create"
+	RoleRead   Permission = "role:read"
+	RoleUpdate Permission = "role:update"
+	RoleDelete Permission = "role:delete"
+	RoleList   Permission = "role:list"
+	RoleAssign Permission = "role:assign"
+
+	// Resource permissions
+	ResourceCreate Permission = "resource:create"
+	ResourceRead   Permission = "resource:read"
+	ResourceUpdate Permission = "resource:update"
+	ResourceDelete Permission = "resource:delete"
+	ResourceList   Permission = "resource:list"
+
+	// Workspace membership permissions
+	MemberList       Permission = "member:list"
+	MemberAdd        Permission = "member:add"
+	MemberRemove     Permission = "member:remove"
+	InvitationCreate Permission = "invitation:create"
+	InvitationList   Permission = "invitation:list"
+	InvitationRevoke Permission = "invitation:revoke"
+
+	// Audit log permissions
+	AuditRead Permission = "audit:read"
+
+	// Webhook permissions
+	WebhookCreate Permission = "webhook:create"
+	WebhookRead   Permission = "webhook:read"
+	WebhookUpdate Permission = "webhook:update"
+	WebhookDelete Permission = "webhook:delete"
+	WebhookList   Permission = "webhook:list"
+
+	// Token revocation permissions
+	TokenRevoke Permission = "token:revoke"
+
+	// Authorization decision permissions
+	AuthorizeCheck Permission = "authorize:check"
+
+	// Role sync permissions
+	SyncRead   Permission = "sync:read"
+	SyncResync Permission = "sync:resync"
+
+	// Runtime diagnostics permissions
+	DebugRead Permission = "debug:read"
 )
 
-// RolePermissions maps roles to their allowed permissions
+// RolePermissions maps roles to their allowed permissions. Inside a workspace
+// only SystemAdmin is honoured, other roles get access from their workspace
+// membership through WorkspaceRolePermissions.
 var RolePermissions = map[Role][]Permission{
 	SystemAdmin: {
-		WorkspaceCreate, WorkspaceRead, WorkspaceUpdate, WorkspaceDelete, WorkspaceList,
+		WorkspaceCreate, WorkspaceRead, WorkspaceUpdate, WorkspaceDelete, WorkspaceList, WorkspaceTransfer,
 		AppCreate, AppRead, AppUpdate, AppDelete, AppList, AppDeploy,
+		ProjectCreate, ProjectRead, ProjectWatch, ProjectUpdate, ProjectDelete, ProjectList, ProjectTransfer,
+		ProjectMemberList, ProjectMemberAdd, ProjectMemberRemove,
+		RoleCreate, RoleRead, RoleUpdate, RoleDelete, RoleList, RoleAssign,
+		ResourceCreate, ResourceRead, ResourceUpdate, ResourceDelete, ResourceList,
+		MemberList, MemberAdd, MemberRemove, InvitationCreate, InvitationList, InvitationRevoke,
+		AuditRead, TokenRevoke, AuthorizeCheck, SyncRead, SyncResync, DebugRead,
+		WebhookCreate, WebhookRead, WebhookUpdate, WebhookDelete, WebhookList,
 	},
 	WorkspaceAdmin: {
-		WorkspaceRead, WorkspaceUpdate,
+		WorkspaceCreate, WorkspaceRead, WorkspaceUpdate, WorkspaceDelete, WorkspaceList,
 		AppCreate, AppRead, AppUpdate, AppDelete, AppList, AppDeploy,
+		ProjectCreate, ProjectRead, ProjectWatch, ProjectUpdate, ProjectDelete, ProjectList, ProjectTransfer,
+		ProjectMemberList, ProjectMemberAdd, ProjectMemberRemove,
+		RoleCreate, RoleRead, RoleUpdate, RoleDelete, RoleList, RoleAssign,
+		ResourceCreate, ResourceRead, ResourceUpdate, ResourceDelete, ResourceList,
+		MemberList, MemberAdd, MemberRemove, InvitationCreate, InvitationList, InvitationRevoke,
+		AuditRead, AuthorizeCheck,
+		WebhookCreate, WebhookRead, WebhookUpdate, WebhookDelete, WebhookList,
 	},
 	AppAdmin: {
-		WorkspaceRead,
+		WorkspaceRead, WorkspaceList,
 		AppRead, AppUpdate, AppDelete, AppList, AppDeploy,
+		ProjectCreate, ProjectRead, ProjectWatch, ProjectUpdate, ProjectDelete, ProjectList, ProjectTransfer,
+		ProjectMemberList, ProjectMemberAdd, ProjectMemberRemove,
+		RoleCreate, RoleRead, RoleUpdate, RoleDelete, RoleList, RoleAssign,
+		ResourceCreate, ResourceRead, ResourceUpdate, ResourceDelete, ResourceList,
+		AuthorizeCheck,
 	},
 	AppDeveloper: {
-		WorkspaceRead,
+		WorkspaceRead, WorkspaceList,
 		AppRead, AppUpdate, AppList, AppDeploy,
+		ProjectRead, ProjectWatch, ProjectList, ProjectMemberList,
+		RoleCreate, RoleRead, RoleUpdate, RoleDelete, RoleList, RoleAssign,
+		ResourceCreate, ResourceRead, ResourceUpdate, ResourceDelete, ResourceList,
 	},
 	AppViewer: {
-		WorkspaceRead,
+		WorkspaceRead, WorkspaceList,
 		AppRead, AppList,
+		ProjectRead, ProjectWatch, ProjectList, ProjectMemberList,
+		RoleRead, RoleList,
+		ResourceRead, ResourceList,
 	},
 }
+
+// HasPermission reports whether role grants permission
+func (role Role) HasPermission(permission Permission) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
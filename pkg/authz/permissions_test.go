@@ -0,0 +1,42 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequirePermission(t *testing.T) {
+	handler := RequirePermission(ProjectUpdate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		want      int
+	}{
+		{"granted by role", &Principal{Roles: []string{string(AppAdmin)}}, http.StatusOK},
+		{"granted by any role", &Principal{Roles: []string{string(AppViewer), string(WorkspaceAdmin)}}, http.StatusOK},
+		{"not granted", &Principal{Roles: []string{string(AppDeveloper)}}, http.StatusForbidden},
+		{"unknown role", &Principal{Roles: []string{"offline_access"}}, http.StatusForbidden},
+		{"unauthenticated", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/projects/1", nil)
+			if tt.principal != nil {
+				req = req.WithContext(context.WithValue(req.Context(), PrincipalContextKey, tt.principal))
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
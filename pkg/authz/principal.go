@@ -0,0 +1,68 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const PrincipalContextKey = contextKey("principal")
+
+// Principal is the authenticated caller, normalized from token claims by the
+// provider's ClaimMapper
+type Principal struct {
+	Subject string
+	Email   string
+	Name    string
+	Roles   []string
+	Scopes  []string
+
+	// Claims holds the validated claims the principal was mapped from
+	Claims map[string]interface{}
+}
+
+// HasRole reports whether the principal holds role
+func (p *Principal) HasRole(role Role) bool {
+	for _, r := range p.Roles {
+		if Role(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether any role of the principal grants permission
+// through RolePermissions
+func (p *Principal) HasPermission(permission Permission) bool {
+	for _, r := range p.Roles {
+		if Role(r).HasPermission(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFromContext returns the principal stored by AuthMiddleware
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(PrincipalContextKey).(*Principal)
+	return principal, ok
+}
+
+// GetPrincipal returns the authenticated principal of a request
+func GetPrincipal(r *http.Request) (*Principal, error) {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, fmt.Errorf("no principal found")
+	}
+	return principal, nil
+}
@@ -1,78 +1,174 @@
 package authz
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// TODO: validate audience and issuer
+// ErrUntrustedIssuer is returned for tokens whose iss claim is not configured
+var ErrUntrustedIssuer = errors.New("untrusted token issuer")
 
-// TokenProvider represents a JWT token provider (Auth0, Keycloak, etc.)
+// Errors returned for tokens issued by or for someone else than expected
+var (
+	ErrInvalidIssuer   = errors.New("invalid issuer")
+	ErrInvalidAudience = errors.New("invalid audience")
+)
+
+// TokenProviderConfig configures a TokenProvider
+type TokenProviderConfig struct {
+	// Issuers lists the trusted issuers, selected by the token's iss claim
+	Issuers []IssuerConfig
+
+	// JWKSCache configures how signing keys are fetched and cached
+	JWKSCache JWKSCacheConfig
+
+	// Introspection enables opaque bearer tokens, nil accepts only JWTs
+	Introspection *IntrospectionConfig
+
+	// ClaimMapper builds principals from claims, nil uses DefaultClaimMapping
+	ClaimMapper *ClaimMapper
+}
+
+// TokenProvider represents a JWT token provider (Auth0, Keycloak, etc.). It
+// trusts a set of issuers and verifies each token against the keys, audiences
+// and algorithms of the issuer named in its iss claim. Opaque tokens are
+// validated through introspection when it is configured.
 type TokenProvider struct {
-	jwksCache *JWKSCache
+	issuers      map[string]*trustedIssuer
+	introspector *Introspector
+	claimMapper  *ClaimMapper
 }
 
-// NewTokenProvider creates a new token provider instance
+// NewTokenProvider creates a token provider trusting any issuer whose keys are
+// published at jwksURL for user tokens. The accepted signing algorithms are
+// read from API_AUTH_ALLOWED_ALGORITHMS and default to RS256.
 func NewTokenProvider(jwksURL string) *TokenProvider {
-	return &TokenProvider{
-		jwksCache: NewJWKSCache(jwksURL),
+	return NewTokenProviderWithConfig(TokenProviderConfig{
+		Issuers: []IssuerConfig{{
+			JWKSURL:           jwksURL,
+			AllowedAlgorithms: parseAlgorithms(os.Getenv("API_AUTH_ALLOWED_ALGORITHMS")),
+			Users:             true,
+		}},
+		JWKSCache: DefaultJWKSCacheConfig(),
+	})
+}
+
+// NewTokenProviderWithConfig creates a new token provider instance from config
+func NewTokenProviderWithConfig(config TokenProviderConfig) *TokenProvider {
+	issuers := make(map[string]*trustedIssuer, len(config.Issuers))
+	for _, issuerConfig := range config.Issuers {
+		issuer := newTrustedIssuer(issuerConfig, config.JWKSCache)
+		issuers[issuer.config.Issuer] = issuer
+	}
+
+	claimMapper := config.ClaimMapper
+	if claimMapper == nil {
+		// The default paths are known to be valid
+		claimMapper, _ = NewClaimMapper(DefaultClaimMapping())
+	}
+
+	provider := &TokenProvider{
+		issuers:     issuers,
+		claimMapper: claimMapper,
+	}
+	if config.Introspection != nil {
+		provider.introspector = NewIntrospector(*config.Introspection)
+	}
+
+	return provider
+}
+
+// Close stops the background key refresh of every issuer
+func (p *TokenProvider) Close() {
+	for _, issuer := range p.issuers {
+		issuer.close()
 	}
 }
 
-// FetchPublicKey returns a function that fetches and parses public keys
-func (tp *TokenProvider) FetchPublicKey() func(string) (interface{}, error) {
-	return func(kid string) (interface{}, error) {
-		key, err := tp.jwksCache.FetchKey(kid)
+// Principal maps validated claims to the caller they describe, keeping only
+// the email and roles their issuer is trusted to assert
+func (p *TokenProvider) Principal(claims map[string]interface{}) *Principal {
+	principal := p.claimMapper.Map(claims)
+
+	iss, _ := claims["iss"].(string)
+	issuer, err := p.issuer(iss)
+	if err != nil {
+		// Only introspected tokens get here, their issuer is trusted for
+		// nothing beyond the subject and scopes
+		principal.Email, principal.Roles = "", nil
+		return principal
+	}
+	return issuer.scope(principal)
+}
+
+// FetchPublicKey returns a function that fetches and parses the public key
+// with the given kid, published by iss, for verifying a signature made with alg
+func (p *TokenProvider) FetchPublicKey() func(iss, kid, alg string) (interface{}, error) {
+	return func(iss, kid, alg string) (interface{}, error) {
+		issuer, err := p.issuer(iss)
 		if err != nil {
 			return nil, err
 		}
+		return issuer.publicKey(context.Background(), kid, alg)
+	}
+}
 
-		keyData, ok := key.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid key format")
-		}
-
-		return parseRSAPublicKey(keyData)
+// issuer selects the trusted issuer for an iss claim, falling back to the
+// issuer configured without an iss value
+func (p *TokenProvider) issuer(iss string) (*trustedIssuer, error) {
+	if issuer, ok := p.issuers[iss]; ok {
+		return issuer, nil
 	}
+	if issuer, ok := p.issuers[""]; ok {
+		return issuer, nil
+	}
+	return nil, ErrUntrustedIssuer
 }
 
-// parseRSAPublicKey converts a JWKS key into an RSA public key
-func parseRSAPublicKey(jwk map[string]interface{}) (*rsa.PublicKey, error) {
-	// The "x5c" (X.509 Certificate Chain) contains the public key in base64-encoded DER format
-	x5c, ok := jwk["x5c"].([]interface{})
-	if !ok || len(x5c) == 0 {
-		return nil, fmt.Errorf("x5c field is missing or invalid")
+// parseAlgorithms splits a comma separated algorithm list, ignoring blanks
+func parseAlgorithms(value string) []string {
+	var algorithms []string
+	for _, alg := range strings.Split(value, ",") {
+		if alg = strings.TrimSpace(alg); alg != "" {
+			algorithms = append(algorithms, alg)
+		}
 	}
+	return algorithms
+}
+
+// isJWT reports whether the token has the three-part compact JWS shape
+func isJWT(tokenString string) bool {
+	return strings.Count(tokenString, ".") == 2
+}
 
-	// Decode the first certificate in the chain
-	certPEM := "-----BEGIN CERTIFICATE-----\n" + x5c[0].(string) + "\n-----END CERTIFICATE-----"
-	block, _ := pem.Decode([]byte(certPEM))
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block")
+// ValidateToken verifies a JWT against the issuer named in its iss claim, or
+// introspects an opaque token. expectedAudience and expectedIssuer are
+// optional additional constraints on top of the configured rules.
+func (p *TokenProvider) ValidateToken(tokenString string, expectedAudience, expectedIssuer string) (map[string]interface{}, error) {
+	if !isJWT(tokenString) {
+		return p.validateOpaqueToken(tokenString, expectedAudience, expectedIssuer)
 	}
 
-	// Parse the certificate to extract the public key
-	cert, err := x509.ParseCertificate(block.Bytes)
+	// Read the issuer before verification to select keys and rules
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		return nil, fmt.Errorf("token parsing failed: %w", err)
 	}
+	iss, _ := unverified.Claims.(jwt.MapClaims)["iss"].(string)
 
-	rsaKey, ok := cert.PublicKey.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("certificate does not contain an RSA public key")
+	issuer, err := p.issuer(iss)
+	if err != nil {
+		return nil, err
 	}
 
-	return rsaKey, nil
-}
-
-func (p *TokenProvider) ValidateToken(tokenString string, expectedAudience, expectedIssuer string) (map[string]interface{}, error) {
 	parser := jwt.NewParser(
-		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithValidMethods(issuer.config.AllowedAlgorithms),
 		jwt.WithIssuer(expectedIssuer),
 		jwt.WithLeeway(30*time.Second), // Add some leeway for clock skew
 	)
@@ -84,18 +180,13 @@ func (p *TokenProvider) ValidateToken(tokenString string, expectedAudience, expe
 			return nil, fmt.Errorf("kid header not found")
 		}
 
-		// Get the public key from JWKS
-		key, err := p.jwksCache.FetchKey(kid)
+		// Get the public key from JWKS, checked against the token's algorithm
+		key, err := issuer.publicKey(context.Background(), kid, token.Method.Alg())
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch public key: %w", err)
 		}
 
-		keyData, ok := key.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid key format")
-		}
-
-		return parseRSAPublicKey(keyData)
+		return key, nil
 	})
 
 	if err != nil {
@@ -108,9 +199,8 @@ func (p *TokenProvider) ValidateToken(tokenString string, expectedAudience, expe
 	}
 
 	// Validate issuer explicitly
-	iss, _ := claims["iss"].(string)
 	if expectedIssuer != "" && iss != expectedIssuer {
-		return nil, fmt.Errorf("invalid issuer")
+		return nil, ErrInvalidIssuer
 	}
 
 	// Validate token type in header (optional but recommended)
@@ -118,32 +208,17 @@ func (p *TokenProvider) ValidateToken(tokenString string, expectedAudience, expe
 		return nil, fmt.Errorf("invalid token type")
 	}
 
-	// Validate audience if provided
+	// Validate audience against the issuer's rules and the caller's, if provided
+	aud, hasAud := claims["aud"]
+	if !issuer.acceptsAudience(aud) {
+		return nil, ErrInvalidAudience
+	}
 	if expectedAudience != "" {
-		aud, ok := claims["aud"]
-		if !ok {
-			return nil, fmt.Errorf("audience claim missing")
+		if !hasAud {
+			return nil, fmt.Errorf("%w: audience claim missing", ErrInvalidAudience)
 		}
-
-		// Handle both string and array audience formats
-		switch v := aud.(type) {
-		case string:
-			if v != expectedAudience {
-				return nil, fmt.Errorf("invalid audience")
-			}
-		case []interface{}:
-			valid := false
-			for _, a := range v {
-				if a.(string) == expectedAudience {
-					valid = true
-					break
-				}
-			}
-			if !valid {
-				return nil, fmt.Errorf("invalid audience")
-			}
-		default:
-			return nil, fmt.Errorf("invalid audience format")
+		if !hasAudience(aud, expectedAudience) {
+			return nil, ErrInvalidAudience
 		}
 	}
 
@@ -154,3 +229,27 @@ func (p *TokenProvider) ValidateToken(tokenString string, expectedAudience, expe
 
 	return claims, nil
 }
+
+// validateOpaqueToken introspects a reference token and applies the same
+// issuer and audience constraints as for JWTs
+func (p *TokenProvider) validateOpaqueToken(tokenString string, expectedAudience, expectedIssuer string) (map[string]interface{}, error) {
+	if p.introspector == nil {
+		return nil, fmt.Errorf("opaque tokens are not accepted")
+	}
+
+	claims, err := p.introspector.Introspect(context.Background(), tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if expectedIssuer != "" {
+		if iss, _ := claims["iss"].(string); iss != expectedIssuer {
+			return nil, ErrInvalidIssuer
+		}
+	}
+	if expectedAudience != "" && !hasAudience(claims["aud"], expectedAudience) {
+		return nil, ErrInvalidAudience
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,372 @@
+package authz
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "https://issuer.example.com"
+
+type testKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	jwk     map[string]interface{}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newRSAKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{
+		kid:     kid,
+		method:  jwt.SigningMethodRS256,
+		private: key,
+		jwk: map[string]interface{}{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   b64(key.N.Bytes()),
+			"e":   b64(big.NewInt(int64(key.E)).Bytes()),
+		},
+	}
+}
+
+func newECKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{
+		kid:     kid,
+		method:  jwt.SigningMethodES256,
+		private: key,
+		jwk: map[string]interface{}{
+			"kid": kid,
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   b64(key.X.FillBytes(make([]byte, 32))),
+			"y":   b64(key.Y.FillBytes(make([]byte, 32))),
+		},
+	}
+}
+
+func newEd25519Key(t *testing.T, kid string) testKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{
+		kid:     kid,
+		method:  jwt.SigningMethodEdDSA,
+		private: private,
+		jwk: map[string]interface{}{
+			"kid": kid,
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   b64(public),
+		},
+	}
+}
+
+func serveJWKS(t *testing.T, keys ...testKey) *httptest.Server {
+	t.Helper()
+	jwks := map[string]interface{}{"keys": []map[string]interface{}{}}
+	for _, k := range keys {
+		jwks["keys"] = append(jwks["keys"].([]map[string]interface{}), k.jwk)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestProvider(jwksURL string, algorithms ...string) *TokenProvider {
+	return NewTokenProviderWithConfig(TokenProviderConfig{
+		Issuers: []IssuerConfig{{
+			JWKSURL:           jwksURL,
+			AllowedAlgorithms: algorithms,
+			Users:             true,
+		}},
+		JWKSCache: JWKSCacheConfig{
+			HTTPClient: &http.Client{Timeout: time.Second},
+		},
+	})
+}
+
+func sign(t *testing.T, k testKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   "agent-auth-api",
+		"sub":   "user-1",
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestValidateTokenKeyTypes(t *testing.T) {
+	keys := []testKey{
+		newRSAKey(t, "rsa"),
+		newECKey(t, "ec"),
+		newEd25519Key(t, "okp"),
+	}
+	server := serveJWKS(t, keys...)
+	provider := newTestProvider(server.URL, "RS256", "ES256", "EdDSA")
+
+	for _, k := range keys {
+		t.Run(k.method.Alg(), func(t *testing.T) {
+			claims, err := provider.ValidateToken(sign(t, k, validClaims()), "agent-auth-api", testIssuer)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims["sub"] != "user-1" {
+				t.Errorf("sub = %v, want user-1", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestValidateTokenRejectsDisallowedAlgorithm(t *testing.T) {
+	k := newECKey(t, "ec")
+	server := serveJWKS(t, k)
+	provider := newTestProvider(server.URL, "RS256")
+
+	if _, err := provider.ValidateToken(sign(t, k, validClaims()), "", testIssuer); err == nil {
+		t.Fatal("expected ES256 token to be rejected when only RS256 is allowed")
+	}
+}
+
+func TestValidateTokenRejectsKeyTypeMismatch(t *testing.T) {
+	rsaKey := newRSAKey(t, "shared")
+	ecKey := newECKey(t, "shared")
+	// The JWKS publishes the RSA key under the kid the EC token refers to
+	server := serveJWKS(t, rsaKey)
+	provider := newTestProvider(server.URL, "RS256", "ES256")
+
+	if _, err := provider.ValidateToken(sign(t, ecKey, validClaims()), "", testIssuer); err == nil {
+		t.Fatal("expected token to be rejected when the JWK type does not match the algorithm")
+	}
+}
+
+func TestValidateTokenRejectsWrongAudienceAndIssuer(t *testing.T) {
+	k := newRSAKey(t, "rsa")
+	server := serveJWKS(t, k)
+	provider := newTestProvider(server.URL)
+	token := sign(t, k, validClaims())
+
+	if _, err := provider.ValidateToken(token, "other-audience", testIssuer); err == nil {
+		t.Error("expected token with wrong audience to be rejected")
+	}
+	if _, err := provider.ValidateToken(token, "", "https://other.example.com"); err == nil {
+		t.Error("expected token with wrong issuer to be rejected")
+	}
+}
+
+func TestValidateTokenUnknownKid(t *testing.T) {
+	published := newRSAKey(t, "published")
+	unpublished := newRSAKey(t, "unpublished")
+	server := serveJWKS(t, published)
+	provider := newTestProvider(server.URL)
+
+	if _, err := provider.ValidateToken(sign(t, unpublished, validClaims()), "", testIssuer); err == nil {
+		t.Fatal("expected token signed with an unknown kid to be rejected")
+	}
+}
+
+func TestFailureReasonClassifiesValidationErrors(t *testing.T) {
+	k := newRSAKey(t, "rsa")
+	// Same kid as the published key, so only the signature is wrong
+	impostor := newRSAKey(t, "rsa")
+	unpublished := newRSAKey(t, "unpublished")
+	server := serveJWKS(t, k)
+	provider := newTestProvider(server.URL)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name     string
+		token    string
+		audience string
+		want     string
+	}{
+		{"wrong audience", sign(t, k, validClaims()), "other-audience", metrics.AuthBadAudience},
+		{"bad signature", sign(t, impostor, validClaims()), "", metrics.AuthBadSignature},
+		{"expired", sign(t, k, expired), "", metrics.AuthExpired},
+		{"unknown kid", sign(t, unpublished, validClaims()), "", metrics.AuthKeyUnavailable},
+		{"malformed", "not.a.jwt", "", metrics.AuthMalformedToken},
+	}
+	for _, tt := range tests {
+		_, err := provider.ValidateToken(tt.token, tt.audience, testIssuer)
+		if err == nil {
+			t.Errorf("%s: token was accepted", tt.name)
+			continue
+		}
+		if got := failureReason(err); got != tt.want {
+			t.Errorf("%s: reason %q, want %q (%v)", tt.name, got, tt.want, err)
+		}
+	}
+
+	if _, err := provider.ValidateToken(sign(t, k, validClaims()), "", "https://other.example.com"); failureReason(err) != metrics.AuthBadIssuer {
+		t.Errorf("wrong issuer: reason %q, want %q (%v)", failureReason(err), metrics.AuthBadIssuer, err)
+	}
+}
+
+func TestValidateTokenMultipleIssuers(t *testing.T) {
+	userKey := newRSAKey(t, "users")
+	agentKey := newECKey(t, "agents")
+	agentJWKS := serveJWKS(t, agentKey)
+
+	// The user IdP is configured through OpenID discovery
+	var userIssuer string
+	userIdP := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":   userIssuer,
+				"jwks_uri": userIssuer + "/certs",
+			})
+		case "/certs":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]interface{}{userKey.jwk},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(userIdP.Close)
+	userIssuer = userIdP.URL
+
+	provider := NewTokenProviderWithConfig(TokenProviderConfig{
+		Issuers: []IssuerConfig{
+			{Issuer: userIssuer, Audiences: []string{"agent-auth-api"}},
+			{Issuer: "https://agents.example.com", JWKSURL: agentJWKS.URL, AllowedAlgorithms: []string{"ES256"}},
+		},
+		JWKSCache: JWKSCacheConfig{
+			HTTPClient: &http.Client{Timeout: time.Second},
+		},
+	})
+
+	userClaims := validClaims()
+	userClaims["iss"] = userIssuer
+	if _, err := provider.ValidateToken(sign(t, userKey, userClaims), "", ""); err != nil {
+		t.Errorf("user token: ValidateToken() error = %v", err)
+	}
+
+	agentClaims := validClaims()
+	agentClaims["iss"] = "https://agents.example.com"
+	agentClaims["aud"] = "anything"
+	if _, err := provider.ValidateToken(sign(t, agentKey, agentClaims), "", ""); err != nil {
+		t.Errorf("agent token: ValidateToken() error = %v", err)
+	}
+
+	// Audience rules are per issuer
+	userClaims["aud"] = "anything"
+	if _, err := provider.ValidateToken(sign(t, userKey, userClaims), "", ""); err == nil {
+		t.Error("expected user token with wrong audience to be rejected")
+	}
+
+	// A key is only trusted for the issuer that publishes it
+	agentClaims["iss"] = userIssuer
+	if _, err := provider.ValidateToken(sign(t, agentKey, agentClaims), "", ""); err == nil {
+		t.Error("expected agent key to be rejected for the user issuer")
+	}
+
+	untrusted := validClaims()
+	untrusted["iss"] = "https://untrusted.example.com"
+	if _, err := provider.ValidateToken(sign(t, userKey, untrusted), "", ""); err == nil {
+		t.Error("expected token from an untrusted issuer to be rejected")
+	}
+}
+
+func TestPrincipalIsScopedToItsIssuer(t *testing.T) {
+	provider := NewTokenProviderWithConfig(TokenProviderConfig{
+		Issuers: []IssuerConfig{
+			{Issuer: testIssuer, JWKSURL: "https://issuer.example.com/certs", Users: true},
+			{Issuer: "https://agents.example.com", JWKSURL: "https://agents.example.com/certs", AllowedRoles: []string{string(AppDeveloper)}},
+		},
+	})
+
+	claims := func(iss string) map[string]interface{} {
+		return map[string]interface{}{
+			"iss":            iss,
+			"sub":            "subject-1",
+			"email":          "owner@example.com",
+			"email_verified": true,
+			"roles":          []interface{}{string(SystemAdmin), string(AppDeveloper)},
+		}
+	}
+
+	user := provider.Principal(claims(testIssuer))
+	if user.Email != "owner@example.com" || !user.HasRole(SystemAdmin) {
+		t.Errorf("user principal = %+v, want its email and every role", user)
+	}
+
+	agent := provider.Principal(claims("https://agents.example.com"))
+	if agent.Email != "" {
+		t.Errorf("agent principal has email %q, want none", agent.Email)
+	}
+	if agent.HasRole(SystemAdmin) || !agent.HasRole(AppDeveloper) {
+		t.Errorf("agent principal has roles %v, want only the allowed %s", agent.Roles, AppDeveloper)
+	}
+
+	unknown := provider.Principal(claims("https://introspected.example.com"))
+	if unknown.Email != "" || len(unknown.Roles) != 0 || unknown.Subject != "subject-1" {
+		t.Errorf("principal of an unconfigured issuer = %+v, want only its subject", unknown)
+	}
+}
+
+func TestPrincipalRequiresVerifiedEmail(t *testing.T) {
+	provider := newTestProvider("https://issuer.example.com/certs")
+
+	for _, verified := range []interface{}{nil, false, "false"} {
+		claims := map[string]interface{}{"iss": testIssuer, "sub": "user-1", "email": "owner@example.com"}
+		if verified != nil {
+			claims["email_verified"] = verified
+		}
+		if email := provider.Principal(claims).Email; email != "" {
+			t.Errorf("email_verified %v: Email = %q, want empty", verified, email)
+		}
+	}
+
+	claims := map[string]interface{}{"iss": testIssuer, "email": "owner@example.com", "email_verified": "true"}
+	if email := provider.Principal(claims).Email; email != "owner@example.com" {
+		t.Errorf("Email = %q, want the verified email", email)
+	}
+}
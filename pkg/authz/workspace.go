@@ -0,0 +1,163 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+)
+
+// WorkspaceRole is the role a member holds within a single workspace
+type WorkspaceRole string
+
+const (
+	WorkspaceRoleOwner  WorkspaceRole = "owner"  // Full control, including deleting the workspace
+	WorkspaceRoleAdmin  WorkspaceRole = "admin"  // Manages the workspace and all of its projects
+	WorkspaceRoleMember WorkspaceRole = "member" // Creates projects and works on resources
+	WorkspaceRoleViewer WorkspaceRole = "viewer" // Read-only access
+)
+
+// WorkspaceRolePermissions maps workspace roles to the permissions they grant
+// within their workspace
+var WorkspaceRolePermissions = map[WorkspaceRole][]Permission{
+	WorkspaceRoleOwner: {
+		WorkspaceRead, WorkspaceUpdate, WorkspaceDelete, WorkspaceTransfer,
+		ProjectCreate, ProjectRead, ProjectWatch, ProjectUpdate, ProjectDelete, ProjectList, ProjectTransfer,
+		ProjectMemberList, ProjectMemberAdd, ProjectMemberRemove,
+		RoleCreate, RoleRead, RoleUpdate, RoleDelete, RoleList, RoleAssign,
+		ResourceCreate, ResourceRead, ResourceUpdate, ResourceDelete, ResourceList,
+		MemberList, MemberAdd, MemberRemove, InvitationCreate, InvitationList, InvitationRevoke,
+		AuditRead, AuthorizeCheck,
+		WebhookCreate, WebhookRead, WebhookUpdate, WebhookDelete, WebhookList,
+	},
+	WorkspaceRoleAdmin: {
+		WorkspaceRead, WorkspaceUpdate,
+		ProjectCreate, ProjectRead, ProjectWatch, ProjectUpdate, ProjectDelete, ProjectList, ProjectTransfer,
+		ProjectMemberList, ProjectMemberAdd, ProjectMemberRemove,
+		RoleCreate, RoleRead, RoleUpdate, RoleDelete, RoleList, RoleAssign,
+		ResourceCreate, ResourceRead, ResourceUpdate, ResourceDelete, ResourceList,
+		MemberList, MemberAdd, MemberRemove, InvitationCreate, InvitationList, InvitationRevoke,
+		AuditRead, AuthorizeCheck,
+		WebhookCreate, WebhookRead, WebhookUpdate, WebhookDelete, WebhookList,
+	},
+	// Members work on the projects they belong to; the project handlers
+	// check that membership, and ownership for deleting a project or
+	// managing its members
+	WorkspaceRoleMember: {
+		WorkspaceRead,
+		ProjectCreate, ProjectRead, ProjectWatch, ProjectUpdate, ProjectDelete, ProjectList, ProjectTransfer,
+		ProjectMemberList, ProjectMemberAdd, ProjectMemberRemove,
+		RoleCreate, RoleRead, RoleUpdate, RoleDelete, RoleList, RoleAssign,
+		ResourceCreate, ResourceRead, ResourceUpdate, ResourceDelete, ResourceList,
+		MemberList, AuthorizeCheck,
+	},
+	WorkspaceRoleViewer: {
+		WorkspaceRead,
+		ProjectRead, ProjectWatch, ProjectList, ProjectMemberList,
+		RoleRead, RoleList,
+		ResourceRead, ResourceList,
+		MemberList,
+	},
+}
+
+// Valid reports whether role is a known workspace role
+func (role WorkspaceRole) Valid() bool {
+	_, ok := WorkspaceRolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether the workspace role grants permission
+func (role WorkspaceRole) HasPermission(permission Permission) bool {
+	for _, p := range WorkspaceRolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+const WorkspaceScopeContextKey = contextKey("workspace_scope")
+
+// WorkspaceScope is the workspace a request operates on and the caller's role
+// in it. Role is empty for system administrators who are not members.
+type WorkspaceScope struct {
+	WorkspaceID string
+	Role        WorkspaceRole
+}
+
+// WorkspaceScopeFromContext returns the scope stored by ResolveWorkspace
+func WorkspaceScopeFromContext(ctx context.Context) (*WorkspaceScope, bool) {
+	scope, ok := ctx.Value(WorkspaceScopeContextKey).(*WorkspaceScope)
+	return scope, ok
+}
+
+// ManagesWorkspace reports whether the caller administers the workspace the
+// request is scoped to, as its owner or admin or as a system administrator
+func ManagesWorkspace(r *http.Request) bool {
+	if principal, ok := PrincipalFromContext(r.Context()); ok && principal.HasRole(SystemAdmin) {
+		return true
+	}
+
+	scope, ok := WorkspaceScopeFromContext(r.Context())
+	return ok && (scope.Role == WorkspaceRoleOwner || scope.Role == WorkspaceRoleAdmin)
+}
+
+// WorkspaceRoleResolver looks up the role a user holds in a workspace. It
+// returns an empty role when the user is not a member.
+type WorkspaceRoleResolver interface {
+	WorkspaceRole(ctx context.Context, workspaceID, email string) (WorkspaceRole, error)
+}
+
+// WorkspaceIDFunc extracts the ID of the workspace a request operates on
+type WorkspaceIDFunc func(r *http.Request) (string, error)
+
+// ResolveWorkspace creates middleware that scopes a request to a workspace
+// and stores the caller's role in it for RequirePermission. Callers who are
+// neither members nor system administrators are rejected. It must run after
+// AuthMiddleware.
+func ResolveWorkspace(resolver WorkspaceRoleResolver, workspaceID WorkspaceIDFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decision := &Decision{Check: CheckWorkspace}
+
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				deny(w, r, decision, "Unauthorized: no claims found", http.StatusUnauthorized)
+				return
+			}
+
+			id, err := workspaceID(r)
+			if err != nil {
+				decision.Reason = err.Error()
+				deny(w, r, decision, "Not found", http.StatusNotFound)
+				return
+			}
+			decision.WorkspaceID = id
+
+			var role WorkspaceRole
+			if principal.Email != "" {
+				role, err = resolver.WorkspaceRole(r.Context(), id, principal.Email)
+				if err != nil {
+					decision.Reason = err.Error()
+					deny(w, r, decision, "Unable to resolve workspace membership", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if role == "" && !principal.HasRole(SystemAdmin) {
+				deny(w, r, decision, "Forbidden: not a workspace member", http.StatusForbidden)
+				return
+			}
+
+			decision.MatchedRole = string(role)
+			if role == "" {
+				decision.MatchedRole = string(SystemAdmin)
+			}
+			allow(r, decision)
+
+			ctx := context.WithValue(r.Context(), WorkspaceScopeContextKey, &WorkspaceScope{
+				WorkspaceID: id,
+				Role:        role,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type staticWorkspaceRoles map[string]WorkspaceRole
+
+func (s staticWorkspaceRoles) WorkspaceRole(ctx context.Context, workspaceID, email string) (WorkspaceRole, error) {
+	return s[workspaceID+"/"+email], nil
+}
+
+func TestResolveWorkspaceScopesPermissions(t *testing.T) {
+	resolver := staticWorkspaceRoles{
+		"ws-1/owner@example.com":  WorkspaceRoleOwner,
+		"ws-1/viewer@example.com": WorkspaceRoleViewer,
+	}
+	workspaceID := func(r *http.Request) (string, error) {
+		return r.URL.Query().Get("workspace"), nil
+	}
+
+	handler := ResolveWorkspace(resolver, workspaceID)(
+		RequirePermission(WorkspaceUpdate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})),
+	)
+
+	tests := []struct {
+		name      string
+		workspace string
+		principal *Principal
+		want      int
+	}{
+		{"owner", "ws-1", &Principal{Email: "owner@example.com"}, http.StatusOK},
+		{"viewer", "ws-1", &Principal{Email: "viewer@example.com"}, http.StatusForbidden},
+		{"owner of another workspace", "ws-2", &Principal{Email: "owner@example.com"}, http.StatusForbidden},
+		{"jwt workspace admin", "ws-2", &Principal{Email: "admin@example.com", Roles: []string{string(WorkspaceAdmin)}}, http.StatusForbidden},
+		{"jwt system admin", "ws-2", &Principal{Subject: "ops", Roles: []string{string(SystemAdmin)}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/?workspace="+tt.workspace, nil)
+			req = req.WithContext(context.WithValue(req.Context(), PrincipalContextKey, tt.principal))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
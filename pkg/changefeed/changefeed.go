@@ -0,0 +1,197 @@
+// Package changefeed streams the policy changes of a project to watchers such
+// as authorization sidecars. Role, permission and resource audit events are
+// appended to a per-project history in the store, which assigns each an
+// ordered ID, and announced to every API replica so any of them can serve a
+// watcher. A watcher that reconnects with the last ID it saw first replays
+// what it missed from the history.
+package changefeed
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultPublishTimeout bounds the store round trip made for each event
+	defaultPublishTimeout = 5 * time.Second
+
+	// defaultQueueSize bounds the events waiting to be appended
+	defaultQueueSize = 1000
+)
+
+// Event is one policy change of a project. ID is assigned by the store and
+// orders the events of a project.
+type Event struct {
+	ID          string         `json:"id"`
+	Type        string         `json:"type"`
+	ProjectID   string         `json:"project_id"`
+	WorkspaceID string         `json:"workspace_id"`
+	Actor       string         `json:"actor"`
+	TargetType  string         `json:"target_type"`
+	TargetID    string         `json:"target_id"`
+	Changes     []audit.Change `json:"changes"`
+	OccurredAt  time.Time      `json:"occurred_at"`
+}
+
+// Store keeps a bounded history of each project's events and relays new ones
+// to every replica. It is implemented on top of Redis by redis_dal.
+type Store interface {
+	// Append assigns event an ID, adds it to its project's history and
+	// announces it to subscribers
+	Append(ctx context.Context, event *Event) error
+	// Replay returns the events of a project after the given ID, oldest first.
+	// complete is false when events after that ID have already been trimmed
+	// from the history.
+	Replay(ctx context.Context, projectID, after string) (events []*Event, complete bool, err error)
+	// Latest returns the ID of the newest event in a project's history, or
+	// an empty string when it has none
+	Latest(ctx context.Context, projectID string) (string, error)
+	// Subscribe streams the events announced for every project. The channel is
+	// closed when ctx is done or the subscription fails.
+	Subscribe(ctx context.Context) (<-chan *Event, error)
+}
+
+// Watched reports whether events of action are streamed
+func Watched(action string) bool {
+	for _, prefix := range []string{"role.", "permission.", "resource."} {
+		if strings.HasPrefix(action, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Publisher feeds the audit events of watched actions into the store. It is
+// an audit.Listener; events are handed to a background goroutine that
+// appends them, so recording an event never waits on the store.
+type Publisher struct {
+	store  Store
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	events   chan *audit.Event
+	closed   bool
+	appended chan struct{}
+}
+
+// NewPublisher returns a publisher appending to store
+func NewPublisher(store Store, logger *zap.Logger) *Publisher {
+	p := &Publisher{
+		store:    store,
+		logger:   logger,
+		events:   make(chan *audit.Event, defaultQueueSize),
+		appended: make(chan struct{}),
+	}
+	go p.appendEvents()
+
+	return p
+}
+
+// EventRecorded hands event over to be appended to its project's change feed
+// when it is a policy change. It never blocks: when the queue is full the
+// event is dropped and logged.
+func (p *Publisher) EventRecorded(event *audit.Event) {
+	if !Watched(event.Action) || event.ProjectID.IsZero() {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return
+	}
+
+	select {
+	case p.events <- event:
+	default:
+		// Watchers still converge when they next resync from the API
+		p.logger.Error("Project change queue is full, dropping change",
+			zap.String("projectID", event.ProjectID.Hex()),
+			zap.String("eventID", event.ID.Hex()))
+	}
+}
+
+// Close stops accepting events and returns once those already handed over
+// have been appended
+func (p *Publisher) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.events)
+	}
+	p.mu.Unlock()
+
+	<-p.appended
+}
+
+// appendEvents appends each event handed over, until the events channel is
+// closed
+func (p *Publisher) appendEvents() {
+	defer close(p.appended)
+	for event := range p.events {
+		p.append(event)
+	}
+}
+
+func (p *Publisher) append(event *audit.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultPublishTimeout)
+	defer cancel()
+
+	err := p.store.Append(ctx, &Event{
+		Type:        event.Action,
+		ProjectID:   event.ProjectID.Hex(),
+		WorkspaceID: event.WorkspaceID.Hex(),
+		Actor:       event.Actor,
+		TargetType:  event.TargetType,
+		TargetID:    event.TargetID,
+		Changes:     event.Changes,
+		OccurredAt:  event.TimestampUTC,
+	})
+	if err != nil {
+		// Watchers still converge when they next resync from the API
+		p.logger.Error("Failed to publish project change",
+			zap.String("projectID", event.ProjectID.Hex()),
+			zap.String("eventID", event.ID.Hex()),
+			zap.Error(err))
+	}
+}
+
+// CompareIDs orders two event IDs of the form "<milliseconds>-<sequence>",
+// returning -1, 0 or 1. An empty ID sorts before every other.
+func CompareIDs(a, b string) int {
+	aMs, aSeq := splitID(a)
+	bMs, bSeq := splitID(b)
+	switch {
+	case aMs < bMs, aMs == bMs && aSeq < bSeq:
+		return -1
+	case aMs == bMs && aSeq == bSeq:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// ValidID reports whether id is a well-formed event ID
+func ValidID(id string) bool {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return false
+	}
+	_, msErr := strconv.ParseUint(ms, 10, 64)
+	_, seqErr := strconv.ParseUint(seq, 10, 64)
+	return msErr == nil && seqErr == nil
+}
+
+func splitID(id string) (uint64, uint64) {
+	ms, seq, _ := strings.Cut(id, "-")
+	msValue, _ := strconv.ParseUint(ms, 10, 64)
+	seqValue, _ := strconv.ParseUint(seq, 10, 64)
+	return msValue, seqValue
+}
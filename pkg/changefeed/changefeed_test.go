@@ -0,0 +1,326 @@
+package changefeed
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// memoryStore keeps the history of every project and relays appended events
+// to the latest subscription
+type memoryStore struct {
+	mu      sync.Mutex
+	next    int
+	history map[string][]*Event
+	// trimmed is the number of oldest events dropped from each history
+	trimmed map[string]int
+	live    chan *Event
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{history: make(map[string][]*Event), trimmed: make(map[string]int)}
+}
+
+func (m *memoryStore) Append(ctx context.Context, event *Event) error {
+	m.record(event)
+
+	m.mu.Lock()
+	live := m.live
+	m.mu.Unlock()
+
+	if live != nil {
+		live <- event
+	}
+	return nil
+}
+
+// record assigns event an ID and adds it to the history without announcing it
+func (m *memoryStore) record(event *Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	event.ID = fmt.Sprintf("%d-0", m.next)
+	m.history[event.ProjectID] = append(m.history[event.ProjectID], event)
+}
+
+func (m *memoryStore) Replay(ctx context.Context, projectID, after string) ([]*Event, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history := m.history[projectID][m.trimmed[projectID]:]
+	if len(history) == 0 {
+		return nil, false, nil
+	}
+
+	var events []*Event
+	for _, event := range history {
+		if CompareIDs(event.ID, after) > 0 {
+			events = append(events, event)
+		}
+	}
+	return events, CompareIDs(history[0].ID, after) <= 0, nil
+}
+
+func (m *memoryStore) Latest(ctx context.Context, projectID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history := m.history[projectID]
+	if len(history) == 0 {
+		return "", nil
+	}
+	return history[len(history)-1].ID, nil
+}
+
+func (m *memoryStore) Subscribe(ctx context.Context) (<-chan *Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.live = make(chan *Event, 10)
+	return m.live, nil
+}
+
+// announce relays event to the subscription without appending it, as a
+// replica announcing an event it appended earlier would
+func (m *memoryStore) announce(event *Event) {
+	m.mu.Lock()
+	live := m.live
+	m.mu.Unlock()
+	live <- event
+}
+
+// dropSubscription closes the live channel as a failed subscription would
+func (m *memoryStore) dropSubscription() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	close(m.live)
+	m.live = nil
+}
+
+// watch retries until the hub has subscribed
+func watch(t *testing.T, hub *Hub, ctx context.Context, projectID, lastEventID string) *Watch {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		w, err := hub.Watch(ctx, projectID, lastEventID)
+		if err == nil {
+			return w
+		}
+		if err != ErrUnavailable || time.Now().After(deadline) {
+			t.Fatalf("Watch: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, w *Watch) *Event {
+	t.Helper()
+	select {
+	case event, ok := <-w.Events:
+		if !ok {
+			t.Fatal("watch ended")
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1-0", "1-0", 0},
+		{"1-0", "1-1", -1},
+		{"2-0", "1-9", 1},
+		{"10-0", "9-0", 1},
+		{"", "1-0", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareIDs(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareIDs(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	for _, id := range []string{"", "1", "a-0", "1-b", "1-0-0"} {
+		if ValidID(id) {
+			t.Errorf("%q should be invalid", id)
+		}
+	}
+	if !ValidID("1700000000000-3") {
+		t.Error("stream ID should be valid")
+	}
+}
+
+func TestPublisherAppendsPolicyChanges(t *testing.T) {
+	store := newMemoryStore()
+	publisher := NewPublisher(store, zap.NewNop())
+	projectID := bson.NewObjectID()
+
+	publisher.EventRecorded(&audit.Event{Action: audit.ActionRoleAssign, ProjectID: projectID, TargetID: "reader"})
+	publisher.EventRecorded(&audit.Event{Action: audit.ActionProjectUpdate, ProjectID: projectID})
+	publisher.EventRecorded(&audit.Event{Action: audit.ActionRoleCreate})
+	publisher.Close()
+
+	history := store.history[projectID.Hex()]
+	if len(history) != 1 {
+		t.Fatalf("appended %d events, want 1", len(history))
+	}
+	if history[0].Type != audit.ActionRoleAssign || history[0].TargetID != "reader" {
+		t.Errorf("unexpected event %+v", history[0])
+	}
+}
+
+// slowStore holds every append until release is closed
+type slowStore struct {
+	*memoryStore
+	release chan struct{}
+}
+
+func (s slowStore) Append(ctx context.Context, event *Event) error {
+	<-s.release
+	return s.memoryStore.Append(ctx, event)
+}
+
+func TestEventRecordedDoesNotWaitForTheStore(t *testing.T) {
+	store := slowStore{memoryStore: newMemoryStore(), release: make(chan struct{})}
+	publisher := NewPublisher(store, zap.NewNop())
+	projectID := bson.NewObjectID()
+
+	recorded := make(chan struct{})
+	go func() {
+		defer close(recorded)
+		for i := 0; i < 3; i++ {
+			publisher.EventRecorded(&audit.Event{Action: audit.ActionRoleAssign, ProjectID: projectID})
+		}
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		t.Fatal("EventRecorded waited for the store")
+	}
+
+	// Closing appends the events already queued
+	close(store.release)
+	publisher.Close()
+
+	if n := len(store.history[projectID.Hex()]); n != 3 {
+		t.Errorf("appended %d events, want 3", n)
+	}
+}
+
+func TestWatchReplaysMissedEventsThenStreams(t *testing.T) {
+	store := newMemoryStore()
+	hub := NewHub(store, zap.NewNop())
+	defer hub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Subscribe first so appends below are also relayed live
+	watch(t, hub, ctx, "other", "")
+	for i := 0; i < 3; i++ {
+		store.Append(ctx, &Event{ProjectID: "p1", Type: audit.ActionRoleCreate})
+	}
+
+	w := watch(t, hub, ctx, "p1", "1-0")
+	if w.Reset {
+		t.Error("complete history reported as reset")
+	}
+	for _, want := range []string{"2-0", "3-0"} {
+		if got := receive(t, w); got.ID != want {
+			t.Fatalf("received %s, want %s", got.ID, want)
+		}
+	}
+
+	store.Append(ctx, &Event{ProjectID: "p2", Type: audit.ActionRoleCreate})
+	store.Append(ctx, &Event{ProjectID: "p1", Type: audit.ActionResourceUpdate})
+	if got := receive(t, w); got.ID != "5-0" || got.Type != audit.ActionResourceUpdate {
+		t.Errorf("received %+v, want live event 5-0", got)
+	}
+}
+
+func TestWatchSendsEventsAnnouncedOutOfOrder(t *testing.T) {
+	store := newMemoryStore()
+	hub := NewHub(store, zap.NewNop())
+	defer hub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store.Append(ctx, &Event{ProjectID: "p1"})
+	w := watch(t, hub, ctx, "p1", "")
+
+	// Two replicas append, then announce in the opposite order
+	first, second := &Event{ProjectID: "p1"}, &Event{ProjectID: "p1"}
+	store.record(first)
+	store.record(second)
+	store.announce(second)
+	store.announce(first)
+
+	for _, want := range []string{first.ID, second.ID} {
+		if got := receive(t, w); got.ID != want {
+			t.Fatalf("received %s, want %s", got.ID, want)
+		}
+	}
+	select {
+	case event := <-w.Events:
+		t.Errorf("received %+v again", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatchResetsWhenHistoryWasTrimmed(t *testing.T) {
+	store := newMemoryStore()
+	hub := NewHub(store, zap.NewNop())
+	defer hub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watch(t, hub, ctx, "other", "")
+	for i := 0; i < 3; i++ {
+		store.Append(ctx, &Event{ProjectID: "p1"})
+	}
+	store.trimmed["p1"] = 2
+
+	w := watch(t, hub, ctx, "p1", "1-0")
+	if !w.Reset {
+		t.Error("trimmed history should reset the watcher")
+	}
+	if got := receive(t, w); got.ID != "3-0" {
+		t.Errorf("received %s, want 3-0", got.ID)
+	}
+}
+
+func TestWatchEndsWhenSubscriptionIsLost(t *testing.T) {
+	store := newMemoryStore()
+	hub := NewHub(store, zap.NewNop())
+	defer hub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := watch(t, hub, ctx, "p1", "")
+	store.dropSubscription()
+
+	select {
+	case _, ok := <-w.Events:
+		if ok {
+			t.Fatal("unexpected event")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch was not ended")
+	}
+
+	if _, err := hub.Watch(ctx, "p1", ""); err != ErrUnavailable {
+		t.Errorf("Watch while unsubscribed: got %v, want ErrUnavailable", err)
+	}
+}
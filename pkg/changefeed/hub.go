@@ -0,0 +1,283 @@
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRetryInterval = 5 * time.Second
+
+	// watcherBuffer is how far a watcher may fall behind before it is ended
+	watcherBuffer = 256
+)
+
+// ErrUnavailable is returned by Watch while the hub has no subscription to
+// the store, since live events would be missed
+var ErrUnavailable = errors.New("change feed is unavailable")
+
+// Hub holds a single subscription to the store and fans its events out to
+// the watchers of each project on this replica
+type Hub struct {
+	store  Store
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	watchers map[string]map[*watcher]struct{}
+	ready    bool
+
+	cancel context.CancelFunc
+}
+
+// watcher is the live side of a Watch, fed by the hub
+type watcher struct {
+	projectID string
+	live      chan *Event
+}
+
+// Watch is one stream of a project's events
+type Watch struct {
+	// Events delivers replayed then live events in ID order. It is closed
+	// when the watch's context is done, or when the watcher falls behind or
+	// the hub loses its subscription; the watcher should then reconnect from
+	// the last ID it received.
+	Events <-chan *Event
+	// Reset is set when events after the requested ID were already trimmed
+	// from the history, so the watcher must resync its state from the API
+	Reset bool
+}
+
+// NewHub creates a hub and starts subscribing to the store in the background
+func NewHub(store Store, logger *zap.Logger) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h := &Hub{
+		store:    store,
+		logger:   logger,
+		watchers: make(map[string]map[*watcher]struct{}),
+		cancel:   cancel,
+	}
+	go h.run(ctx)
+
+	return h
+}
+
+// Close stops the subscription and ends every watch
+func (h *Hub) Close() {
+	h.cancel()
+}
+
+// Watch streams the events of projectID, starting after lastEventID when it
+// is set and with new events otherwise. The watch ends when ctx is done.
+//
+// Events are read from the store's history rather than taken from the
+// announcements, which only wake the watch: IDs are assigned by separate
+// appends that announce them separately, so a lower ID can be announced
+// after a higher one. Reading the history after the last ID sent returns
+// both, in order.
+func (h *Hub) Watch(ctx context.Context, projectID, lastEventID string) (*Watch, error) {
+	// Registering before reading the history ensures nothing falls between
+	// the two; events seen in both are skipped by ID
+	w, err := h.register(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var backlog []*Event
+	complete := true
+	if lastEventID != "" {
+		backlog, complete, err = h.store.Replay(ctx, projectID, lastEventID)
+	} else {
+		lastEventID, err = h.store.Latest(ctx, projectID)
+	}
+	if err != nil {
+		h.unregister(w)
+		return nil, err
+	}
+
+	events := make(chan *Event)
+	go func() {
+		defer close(events)
+		defer h.unregister(w)
+
+		last := lastEventID
+		send := func(event *Event) bool {
+			if CompareIDs(event.ID, last) <= 0 {
+				return true
+			}
+			select {
+			case events <- event:
+				last = event.ID
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, event := range backlog {
+			if !send(event) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-w.live:
+				if !ok {
+					return
+				}
+				if CompareIDs(event.ID, last) <= 0 {
+					continue
+				}
+				if !h.catchUp(ctx, w, last, send) {
+					return
+				}
+			}
+		}
+	}()
+
+	return &Watch{Events: events, Reset: !complete}, nil
+}
+
+// catchUp sends the events of w's project after last from the history,
+// first dropping the announcements already queued since they are covered by
+// it. It returns false when the watch must end, including when events after
+// last were trimmed meanwhile; the watcher then reconnects and is reset.
+func (h *Hub) catchUp(ctx context.Context, w *watcher, last string, send func(*Event) bool) bool {
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-w.live:
+			if !ok {
+				return false
+			}
+		default:
+			drained = true
+		}
+	}
+
+	after := last
+	if after == "" {
+		// The history was empty when the watch started, so all of it is new
+		after = "0-0"
+	}
+	events, complete, err := h.store.Replay(ctx, w.projectID, after)
+	if err != nil {
+		if ctx.Err() == nil {
+			h.logger.Error("Failed to read project change history", zap.String("projectID", w.projectID), zap.Error(err))
+		}
+		return false
+	}
+	if !complete && last != "" {
+		return false
+	}
+
+	for _, event := range events {
+		if !send(event) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Hub) register(projectID string) (*watcher, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.ready {
+		return nil, ErrUnavailable
+	}
+
+	w := &watcher{projectID: projectID, live: make(chan *Event, watcherBuffer)}
+	if h.watchers[projectID] == nil {
+		h.watchers[projectID] = make(map[*watcher]struct{})
+	}
+	h.watchers[projectID][w] = struct{}{}
+	return w, nil
+}
+
+func (h *Hub) unregister(w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(w)
+}
+
+// remove forgets w; the caller holds mu
+func (h *Hub) remove(w *watcher) {
+	delete(h.watchers[w.projectID], w)
+	if len(h.watchers[w.projectID]) == 0 {
+		delete(h.watchers, w.projectID)
+	}
+}
+
+// dispatch hands event to the watchers of its project. A watcher whose buffer
+// is full is ended rather than allowed to hold the others back.
+func (h *Hub) dispatch(event *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for w := range h.watchers[event.ProjectID] {
+		select {
+		case w.live <- event:
+		default:
+			h.logger.Warn("Ending change feed watcher that fell behind", zap.String("projectID", event.ProjectID))
+			close(w.live)
+			h.remove(w)
+		}
+	}
+}
+
+// setReady records whether the hub is subscribed. Losing the subscription
+// ends every watch, since events published meanwhile would be missed.
+func (h *Hub) setReady(ready bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.ready = ready
+	if ready {
+		return
+	}
+	for _, watchers := range h.watchers {
+		for w := range watchers {
+			close(w.live)
+		}
+	}
+	h.watchers = make(map[string]map[*watcher]struct{})
+}
+
+// run keeps the hub subscribed to the store, resubscribing after failures
+func (h *Hub) run(ctx context.Context) {
+	var events <-chan *Event
+
+	retry := time.NewTimer(0)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.setReady(false)
+			return
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				h.setReady(false)
+				retry.Reset(defaultRetryInterval)
+				continue
+			}
+			h.dispatch(event)
+		case <-retry.C:
+			subscription, err := h.store.Subscribe(ctx)
+			if err != nil {
+				h.logger.Error("Failed to subscribe to project changes", zap.Error(err))
+				retry.Reset(defaultRetryInterval)
+				continue
+			}
+			events = subscription
+			h.setReady(true)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package decisionlog ships authorization decisions to a Sink. Decisions are
+// sampled on the request path and written in batches by a background worker,
+// so a slow sink never delays a request; when it falls behind decisions are
+// dropped and counted rather than queued without bound.
+package decisionlog
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAllowSampleRate = 0.1
+	defaultDenySampleRate  = 1.0
+	defaultBufferSize      = 10000
+	defaultBatchSize       = 100
+	defaultFlushInterval   = time.Second
+)
+
+// Record is a decision as it is stored. ExpiresAt is when the retention of
+// its project lapses; it is nil when the decision is kept indefinitely.
+type Record struct {
+	authz.Decision `bson:",inline"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" bson:"ExpiresAt,omitempty"`
+}
+
+// Sink stores batches of records
+type Sink interface {
+	Write(ctx context.Context, records []*Record) error
+}
+
+// Config tunes a Logger. Unset sample rates select the defaults, which keep
+// every denial and one allow in ten.
+type Config struct {
+	AllowSampleRate *float64      `mapstructure:"allow_sample_rate"`
+	DenySampleRate  *float64      `mapstructure:"deny_sample_rate"`
+	BufferSize      int           `mapstructure:"buffer_size"`
+	BatchSize       int           `mapstructure:"batch_size"`
+	FlushInterval   time.Duration `mapstructure:"-"`
+	Retention       Retention     `mapstructure:"retention"`
+}
+
+// Retention is how many days decisions are kept, per project ID with a
+// default for the rest. Zero days keeps decisions indefinitely.
+type Retention struct {
+	DefaultDays int            `mapstructure:"default_days"`
+	Projects    map[string]int `mapstructure:"projects"`
+}
+
+// expiry returns when a decision made at t for projectID expires
+func (r Retention) expiry(projectID string, t time.Time) *time.Time {
+	days, ok := r.Projects[projectID]
+	if !ok {
+		days = r.DefaultDays
+	}
+	if days <= 0 {
+		return nil
+	}
+	expiresAt := t.AddDate(0, 0, days)
+	return &expiresAt
+}
+
+// Logger is an authz.DecisionLogger writing sampled decisions to a Sink
+type Logger struct {
+	sink   Sink
+	logger *zap.Logger
+	config Config
+	random func() float64
+
+	mu      sync.RWMutex
+	records chan *Record
+	closed  bool
+	dropped int64 // accessed atomically
+
+	wg sync.WaitGroup
+}
+
+// NewLogger creates a logger writing to sink and starts its worker
+func NewLogger(sink Sink, logger *zap.Logger, config Config) *Logger {
+	if config.AllowSampleRate == nil {
+		rate := defaultAllowSampleRate
+		config.AllowSampleRate = &rate
+	}
+	if config.DenySampleRate == nil {
+		rate := defaultDenySampleRate
+		config.DenySampleRate = &rate
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultBufferSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlushInterval
+	}
+
+	l := &Logger{
+		sink:    sink,
+		logger:  logger,
+		config:  config,
+		random:  rand.Float64,
+		records: make(chan *Record, config.BufferSize),
+	}
+
+	l.wg.Add(1)
+	go l.work()
+
+	return l
+}
+
+// LogDecision samples decision and queues it for the sink. It never blocks.
+func (l *Logger) LogDecision(ctx context.Context, decision *authz.Decision) {
+	rate := *l.config.AllowSampleRate
+	if decision.Decision == authz.DecisionDeny {
+		rate = *l.config.DenySampleRate
+	}
+	if rate <= 0 || (rate < 1 && l.random() >= rate) {
+		return
+	}
+
+	record := &Record{
+		Decision:  *decision,
+		ExpiresAt: l.config.Retention.expiry(decision.ProjectID, decision.TimestampUTC),
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		return
+	}
+
+	select {
+	case l.records <- record:
+	default:
+		atomic.AddInt64(&l.dropped, 1)
+	}
+}
+
+// Close stops accepting decisions and waits for the queued ones to be written
+func (l *Logger) Close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.records)
+	l.mu.Unlock()
+
+	l.wg.Wait()
+}
+
+// work writes records in batches, when a batch fills up or on each tick
+func (l *Logger) work() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Record, 0, l.config.BatchSize)
+	for {
+		select {
+		case record, ok := <-l.records:
+			if !ok {
+				l.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= l.config.BatchSize {
+				batch = l.flush(batch)
+			}
+
+		case <-ticker.C:
+			batch = l.flush(batch)
+		}
+	}
+}
+
+// flush writes batch and returns a new empty one. A failed batch is dropped: the
+// decisions are diagnostics, and retrying would only grow the backlog.
+func (l *Logger) flush(batch []*Record) []*Record {
+	if dropped := atomic.SwapInt64(&l.dropped, 0); dropped > 0 {
+		l.logger.Warn("Dropped authorization decisions, the decision log is falling behind",
+			zap.Int64("dropped", dropped))
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := l.sink.Write(context.Background(), batch); err != nil {
+		l.logger.Error("Failed to write authorization decisions",
+			zap.Int("decisions", len(batch)),
+			zap.Error(err))
+	}
+
+	return make([]*Record, 0, l.config.BatchSize)
+}
@@ -0,0 +1,156 @@
+package decisionlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"go.uber.org/zap"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]*Record
+}
+
+func (m *memorySink) Write(ctx context.Context, records []*Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = append(m.batches, records)
+	return nil
+}
+
+func (m *memorySink) records() []*Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var all []*Record
+	for _, batch := range m.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func rate(r float64) *float64 {
+	return &r
+}
+
+func TestLoggerSamplesAllowsAndKeepsDenials(t *testing.T) {
+	sink := &memorySink{}
+	l := NewLogger(sink, zap.NewNop(), Config{AllowSampleRate: rate(0.5)})
+
+	// Alternate draws above and below the allow rate
+	var draws int
+	l.random = func() float64 {
+		draws++
+		if draws%2 == 0 {
+			return 0.9
+		}
+		return 0.1
+	}
+
+	for i := 0; i < 4; i++ {
+		l.LogDecision(context.Background(), &authz.Decision{Decision: authz.DecisionAllow})
+		l.LogDecision(context.Background(), &authz.Decision{Decision: authz.DecisionDeny})
+	}
+	l.Close()
+
+	var allows, denies int
+	for _, record := range sink.records() {
+		if record.Decision.Decision == authz.DecisionAllow {
+			allows++
+		} else {
+			denies++
+		}
+	}
+	if allows != 2 || denies != 4 {
+		t.Errorf("kept %d allows and %d denies, want 2 and 4", allows, denies)
+	}
+}
+
+func TestLoggerAppliesProjectRetention(t *testing.T) {
+	sink := &memorySink{}
+	l := NewLogger(sink, zap.NewNop(), Config{
+		AllowSampleRate: rate(1),
+		Retention: Retention{
+			DefaultDays: 30,
+			Projects:    map[string]int{"short": 1, "forever": 0},
+		},
+	})
+
+	at := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	for _, project := range []string{"short", "forever", "other"} {
+		l.LogDecision(context.Background(), &authz.Decision{
+			Decision:     authz.DecisionAllow,
+			ProjectID:    project,
+			TimestampUTC: at,
+		})
+	}
+	l.Close()
+
+	expiry := map[string]*time.Time{}
+	for _, record := range sink.records() {
+		expiry[record.ProjectID] = record.ExpiresAt
+	}
+	if expiry["short"] == nil || !expiry["short"].Equal(at.AddDate(0, 0, 1)) {
+		t.Errorf("short retention expires at %v", expiry["short"])
+	}
+	if expiry["forever"] != nil {
+		t.Errorf("unlimited retention expires at %v", expiry["forever"])
+	}
+	if expiry["other"] == nil || !expiry["other"].Equal(at.AddDate(0, 0, 30)) {
+		t.Errorf("default retention expires at %v", expiry["other"])
+	}
+}
+
+func TestLoggerWritesInBatches(t *testing.T) {
+	sink := &memorySink{}
+	l := NewLogger(sink, zap.NewNop(), Config{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+
+	for i := 0; i < 5; i++ {
+		l.LogDecision(context.Background(), &authz.Decision{Decision: authz.DecisionDeny})
+	}
+	l.Close()
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.batches) != 3 || len(sink.batches[2]) != 1 {
+		t.Errorf("want batches of 2, 2 and 1, got %d batches", len(sink.batches))
+	}
+}
+
+func TestWriterSinkWritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	err := sink.Write(context.Background(), []*Record{
+		{Decision: authz.Decision{Subject: "agent-1", Check: authz.CheckScope, Decision: authz.DecisionDeny}},
+		{Decision: authz.Decision{Subject: "agent-2", Check: authz.CheckScope, Decision: authz.DecisionAllow}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %d", len(lines))
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["subject"] != "agent-1" || decoded["decision"] != "deny" {
+		t.Errorf("unexpected line %s", lines[0])
+	}
+	if _, ok := decoded["expires_at"]; ok {
+		t.Errorf("unlimited retention should omit expires_at: %s", lines[0])
+	}
+}
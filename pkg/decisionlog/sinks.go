@@ -0,0 +1,71 @@
+package decisionlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WriterSink writes records as JSON lines to an io.Writer
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a sink writing to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewStdoutSink creates a sink writing to standard output, for collection by
+// the platform's log shipper
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// Write encodes each record on its own line
+func (s *WriterSink) Write(ctx context.Context, records []*Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoder := json.NewEncoder(s.w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write decision: %w", err)
+		}
+	}
+	return nil
+}
+
+// FileSink appends records as JSON lines to a file. Records carry their
+// expiry for whatever rotates the file to act on.
+type FileSink struct {
+	*WriterSink
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it and its directory if
+// needed
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create decision log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open decision log: %w", err)
+	}
+
+	return &FileSink{WriterSink: NewWriterSink(file), file: file}, nil
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
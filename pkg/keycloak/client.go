@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 )
 
 // Client represents a Keycloak API client
@@ -27,7 +29,7 @@ func NewClient(baseURL string, token string) (*Client, error) {
 
 	return &Client{
 		BaseURL:    parsedURL,
-		HTTPClient: &http.Client{},
+		HTTPClient: &http.Client{Transport: tracing.NewTransport(nil)},
 		Token:      token,
 	}, nil
 }
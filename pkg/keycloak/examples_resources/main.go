@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/agent-auth/agent-auth-api/pkg/keycloak"
@@ -13,6 +14,8 @@ func main() {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
+	ctx := context.Background()
+
 	// Create resource service
 	resourceService := keycloak.NewResourceService(client)
 
@@ -21,7 +24,7 @@ func main() {
 	clientID := "0d37d66f-dffd-4c87-9301-28b49abc9c7a"
 
 	// 1. Get all resources
-	resources, err := resourceService.GetAuthzResources(realmName, clientID, &keycloak.ResourceQuery{})
+	resources, err := resourceService.GetAuthzResources(ctx, realmName, clientID, &keycloak.ResourceQuery{})
 	if err != nil {
 		log.Fatalf("Failed to get resources: %v", err)
 	}
@@ -36,14 +39,14 @@ func main() {
 		Scopes:      []keycloak.ScopeRepresentation{},
 	}
 
-	createdResource, err := resourceService.CreateAuthzResource(realmName, clientID, newResource)
+	createdResource, err := resourceService.CreateAuthzResource(ctx, realmName, clientID, newResource)
 	if err != nil {
 		log.Fatalf("Failed to create resource: %v", err)
 	}
 	log.Printf("Created resource: %v\n", createdResource)
 
 	// 3. Get specific resource by ID
-	resource, err := resourceService.GetAuthzResource(realmName, clientID, createdResource.ID)
+	resource, err := resourceService.GetAuthzResource(ctx, realmName, clientID, createdResource.ID)
 	if err != nil {
 		log.Fatalf("Failed to get resource: %v", err)
 	}
@@ -51,14 +54,14 @@ func main() {
 
 	// 4. Update resource
 	resource.DisplayName = "Updated Test Resource"
-	err = resourceService.UpdateAuthzResource(realmName, clientID, resource.ID, resource)
+	err = resourceService.UpdateAuthzResource(ctx, realmName, clientID, resource.ID, resource)
 	if err != nil {
 		log.Fatalf("Failed to update resource: %v", err)
 	}
 	log.Printf("Updated resource: %v\n", resource)
 
 	// 5. Delete resource
-	err = resourceService.DeleteAuthzResource(realmName, clientID, resource.ID)
+	err = resourceService.DeleteAuthzResource(ctx, realmName, clientID, resource.ID)
 	if err != nil {
 		log.Fatalf("Failed to delete resource: %v", err)
 	}
@@ -66,7 +69,7 @@ func main() {
 
 	// 6. Search resources with query parameters
 	query := &keycloak.ResourceQuery{}
-	searchResults, err := resourceService.GetAuthzResources(realmName, clientID, query)
+	searchResults, err := resourceService.GetAuthzResources(ctx, realmName, clientID, query)
 	if err != nil {
 		log.Fatalf("Failed to search resources: %v", err)
 	}
@@ -2,6 +2,7 @@ package keycloak
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -15,7 +16,7 @@ func NewResourceService(client *Client) *ResourceService {
 }
 
 // GetAuthzResources gets all resources for the client's resource server
-func (s *ResourceService) GetAuthzResources(realm, clientUUID string, params *ResourceQuery) ([]ResourceRepresentation, error) {
+func (s *ResourceService) GetAuthzResources(ctx context.Context, realm, clientUUID string, params *ResourceQuery) ([]ResourceRepresentation, error) {
 	url := s.client.buildURL("admin", "realms", realm, "clients", clientUUID, "authz", "resource-server", "resource")
 
 	// Add query parameters if provided
@@ -48,7 +49,7 @@ func (s *ResourceService) GetAuthzResources(realm, clientUUID string, params *Re
 		url.RawQuery = query.Encode()
 	}
 
-	req, err := http.NewRequest("GET", url.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -74,7 +75,7 @@ func (s *ResourceService) GetAuthzResources(realm, clientUUID string, params *Re
 }
 
 // CreateAuthzResource creates a new resource for the client's resource server
-func (s *ResourceService) CreateAuthzResource(realm, clientUUID string, resource *ResourceRepresentation) (*ResourceRepresentation, error) {
+func (s *ResourceService) CreateAuthzResource(ctx context.Context, realm, clientUUID string, resource *ResourceRepresentation) (*ResourceRepresentation, error) {
 	url := s.client.buildURL("admin", "realms", realm, "clients", clientUUID, "authz", "resource-server", "resource")
 
 	body, err := json.Marshal(resource)
@@ -82,7 +83,7 @@ func (s *ResourceService) CreateAuthzResource(realm, clientUUID string, resource
 		return nil, fmt.Errorf("failed to marshal resource: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url.String(), bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url.String(), bytes.NewBuffer(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -109,10 +110,10 @@ func (s *ResourceService) CreateAuthzResource(realm, clientUUID string, resource
 }
 
 // GetAuthzResource gets a specific resource by ID from the client's resource server
-func (s *ResourceService) GetAuthzResource(realm, clientUUID, resourceID string) (*ResourceRepresentation, error) {
+func (s *ResourceService) GetAuthzResource(ctx context.Context, realm, clientUUID, resourceID string) (*ResourceRepresentation, error) {
 	url := s.client.buildURL("admin", "realms", realm, "clients", clientUUID, "authz", "resource-server", "resource", resourceID)
 
-	req, err := http.NewRequest("GET", url.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -141,7 +142,7 @@ func (s *ResourceService) GetAuthzResource(realm, clientUUID, resourceID string)
 }
 
 // UpdateAuthzResource updates a specific resource in the client's resource server
-func (s *ResourceService) UpdateAuthzResource(realm, clientUUID, resourceID string, resource *ResourceRepresentation) error {
+func (s *ResourceService) UpdateAuthzResource(ctx context.Context, realm, clientUUID, resourceID string, resource *ResourceRepresentation) error {
 	url := s.client.buildURL("admin", "realms", realm, "clients", clientUUID, "authz", "resource-server", "resource", resourceID)
 
 	body, err := json.Marshal(resource)
@@ -149,7 +150,7 @@ func (s *ResourceService) UpdateAuthzResource(realm, clientUUID, resourceID stri
 		return fmt.Errorf("failed to marshal resource: %w", err)
 	}
 
-	req, err := http.NewRequest("PUT", url.String(), bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "PUT", url.String(), bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -174,10 +175,10 @@ func (s *ResourceService) UpdateAuthzResource(realm, clientUUID, resourceID stri
 }
 
 // DeleteAuthzResource deletes a specific resource from the client's resource server
-func (s *ResourceService) DeleteAuthzResource(realm, clientUUID, resourceID string) error {
+func (s *ResourceService) DeleteAuthzResource(ctx context.Context, realm, clientUUID, resourceID string) error {
 	url := s.client.buildURL("admin", "realms", realm, "clients", clientUUID, "authz", "resource-server", "resource", resourceID)
 
-	req, err := http.NewRequest("DELETE", url.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url.String(), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
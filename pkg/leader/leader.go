@@ -0,0 +1,210 @@
+// Package leader elects one process among the API replicas to run a singleton
+// task, such as the Redis role syncer. The leader holds a lease in a shared
+// store and keeps renewing it; when it dies the lease expires and another
+// replica takes over.
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLeaseDuration = 15 * time.Second
+	defaultRetryInterval = 5 * time.Second
+
+	// releaseTimeout bounds giving up the lease on the way out
+	releaseTimeout = 5 * time.Second
+)
+
+// instanceID names this process in leases
+var instanceID = newInstanceID()
+
+// InstanceID returns the name this process holds leases under, made of its
+// host name, process ID and a random suffix
+func InstanceID() string {
+	return instanceID
+}
+
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// LeaseStore grants leases that expire unless renewed. Acquire succeeds only
+// when the lease is free, Renew and Release only for its current holder.
+type LeaseStore interface {
+	Acquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error)
+	Renew(ctx context.Context, key, holder string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key, holder string) error
+	// Holder returns the current holder and the remaining lease, or an empty
+	// holder when the lease is free
+	Holder(ctx context.Context, key string) (string, time.Duration, error)
+}
+
+// Config tunes an Elector. Zero values select the defaults. RenewInterval
+// must be well below LeaseDuration so a renewal can fail and be retried
+// before the lease runs out.
+type Config struct {
+	LeaseDuration time.Duration
+	RenewInterval time.Duration
+	RetryInterval time.Duration
+}
+
+// Elector campaigns for one lease on behalf of this process
+type Elector struct {
+	store  LeaseStore
+	key    string
+	logger *zap.Logger
+	config Config
+
+	mu      sync.Mutex
+	leading bool
+}
+
+// NewElector returns an elector for the lease named key
+func NewElector(store LeaseStore, key string, logger *zap.Logger, config Config) *Elector {
+	if config.LeaseDuration <= 0 {
+		config.LeaseDuration = defaultLeaseDuration
+	}
+	if config.RenewInterval <= 0 || config.RenewInterval >= config.LeaseDuration {
+		config.RenewInterval = config.LeaseDuration / 3
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaultRetryInterval
+	}
+
+	return &Elector{
+		store:  store,
+		key:    key,
+		logger: logger.With(zap.String("lease", key), zap.String("instance", instanceID)),
+		config: config,
+	}
+}
+
+// Leading reports whether this process currently holds the lease
+func (e *Elector) Leading() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading
+}
+
+func (e *Elector) setLeading(leading bool) {
+	e.mu.Lock()
+	e.leading = leading
+	e.mu.Unlock()
+}
+
+// Run campaigns for the lease until ctx is done, running fn whenever it is
+// held. The context passed to fn is cancelled when the lease is lost, and
+// the lease is given up when fn returns on its own.
+func (e *Elector) Run(ctx context.Context, fn func(ctx context.Context)) {
+	retry := time.NewTimer(0)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-retry.C:
+		}
+
+		sent := time.Now()
+		acquired, err := e.store.Acquire(ctx, e.key, instanceID, e.config.LeaseDuration)
+		if err != nil {
+			e.logger.Error("Failed to acquire lease", zap.Error(err))
+		}
+		if acquired {
+			e.lead(ctx, fn, sent.Add(e.config.LeaseDuration))
+		}
+		retry.Reset(e.config.RetryInterval)
+	}
+}
+
+// lead runs fn while renewing the lease, which expires at expiresAt unless
+// renewed, and stops it as soon as the lease may have been lost
+func (e *Elector) lead(ctx context.Context, fn func(ctx context.Context), expiresAt time.Time) {
+	e.logger.Info("Acquired lease, now leading")
+	e.setLeading(true)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(leaderCtx)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+		e.setLeading(false)
+
+		releaseCtx, cancelRelease := context.WithTimeout(context.Background(), releaseTimeout)
+		defer cancelRelease()
+		if err := e.store.Release(releaseCtx, e.key, instanceID); err != nil {
+			e.logger.Error("Failed to release lease", zap.Error(err))
+		}
+	}()
+
+	ticker := time.NewTicker(e.config.RenewInterval)
+	defer ticker.Stop()
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			e.logger.Warn("Leader task stopped, giving up lease")
+			return
+		case <-expiry.C:
+			e.logger.Error("Lease expired before it was renewed, stepping down")
+			return
+		case <-ticker.C:
+			// A renewal still pending when the lease runs out is abandoned,
+			// so a slow store cannot keep the task running past it
+			sent := time.Now()
+			renewCtx, cancelRenew := context.WithDeadline(ctx, expiresAt)
+			renewed, err := e.store.Renew(renewCtx, e.key, instanceID, e.config.LeaseDuration)
+			cancelRenew()
+			if err != nil {
+				if !time.Now().Before(expiresAt) {
+					e.logger.Error("Lease expired before it was renewed, stepping down", zap.Error(err))
+					return
+				}
+				// Step down before the lease can expire under a running task
+				if time.Until(expiresAt) < e.config.RenewInterval {
+					e.logger.Error("Failed to renew lease, stepping down", zap.Error(err))
+					return
+				}
+				e.logger.Warn("Failed to renew lease, retrying", zap.Error(err))
+				continue
+			}
+			if !renewed {
+				e.logger.Warn("Lease was taken over, stepping down")
+				return
+			}
+			expiresAt = sent.Add(e.config.LeaseDuration)
+			if !expiry.Stop() {
+				select {
+				case <-expiry.C:
+				default:
+				}
+			}
+			expiry.Reset(time.Until(expiresAt))
+		}
+	}
+}
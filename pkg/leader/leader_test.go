@@ -0,0 +1,264 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// memoryLeases keeps leases in memory and expires them like Redis would
+type memoryLeases struct {
+	mu       sync.Mutex
+	holders  map[string]string
+	expiry   map[string]time.Time
+	renewErr error
+	hang     bool
+}
+
+func newMemoryLeases() *memoryLeases {
+	return &memoryLeases{holders: make(map[string]string), expiry: make(map[string]time.Time)}
+}
+
+func (m *memoryLeases) holder(key string) string {
+	if time.Now().After(m.expiry[key]) {
+		delete(m.holders, key)
+	}
+	return m.holders[key]
+}
+
+func (m *memoryLeases) Acquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holder(key) != "" {
+		return false, nil
+	}
+	m.holders[key] = holder
+	m.expiry[key] = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (m *memoryLeases) Renew(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	if m.hang {
+		m.mu.Unlock()
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	defer m.mu.Unlock()
+	if m.renewErr != nil {
+		return false, m.renewErr
+	}
+	if m.holder(key) != holder {
+		return false, nil
+	}
+	m.expiry[key] = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (m *memoryLeases) Release(ctx context.Context, key, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holder(key) == holder {
+		delete(m.holders, key)
+	}
+	return nil
+}
+
+func (m *memoryLeases) Holder(ctx context.Context, key string) (string, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	holder := m.holder(key)
+	if holder == "" {
+		return "", 0, nil
+	}
+	return holder, time.Until(m.expiry[key]), nil
+}
+
+// steal hands the lease to someone else, as if it had expired and been taken
+func (m *memoryLeases) steal(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.holders[key] = "someone-else"
+	m.expiry[key] = time.Now().Add(time.Hour)
+}
+
+func (m *memoryLeases) failRenewals(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.renewErr = err
+}
+
+// hangRenewals makes renewals block until their context is done, as on a
+// store that stopped answering. It returns when the lease now expires.
+func (m *memoryLeases) hangRenewals(key string) time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hang = true
+	return m.expiry[key]
+}
+
+var testConfig = Config{
+	LeaseDuration: 60 * time.Millisecond,
+	RenewInterval: 10 * time.Millisecond,
+	RetryInterval: 10 * time.Millisecond,
+}
+
+// runElector runs an elector until the returned cancel is called, reporting
+// each time it starts leading
+func runElector(store LeaseStore) (*Elector, <-chan context.Context, context.CancelFunc) {
+	elector := NewElector(store, "sync", zap.NewNop(), testConfig)
+	started := make(chan context.Context, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		elector.Run(ctx, func(ctx context.Context) {
+			started <- ctx
+			<-ctx.Done()
+		})
+	}()
+
+	return elector, started, func() {
+		cancel()
+		<-done
+	}
+}
+
+func waitStarted(t *testing.T, started <-chan context.Context) context.Context {
+	t.Helper()
+	select {
+	case ctx := <-started:
+		return ctx
+	case <-time.After(2 * time.Second):
+		t.Fatal("task was not started")
+		return nil
+	}
+}
+
+func waitStopped(t *testing.T, ctx context.Context) {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("task was not stopped")
+	}
+}
+
+func waitNotLeading(t *testing.T, elector *Elector) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for elector.Leading() {
+		if time.Now().After(deadline) {
+			t.Fatal("elector is still leading")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestElectorRunsTaskWhileLeading(t *testing.T) {
+	store := newMemoryLeases()
+	elector, started, stop := runElector(store)
+
+	waitStarted(t, started)
+	if !elector.Leading() {
+		t.Error("elector running the task should be leading")
+	}
+
+	// Renewals keep the lease well past its duration
+	time.Sleep(3 * testConfig.LeaseDuration)
+	if holder, _, _ := store.Holder(context.Background(), "sync"); holder != InstanceID() {
+		t.Errorf("lease held by %q, want %q", holder, InstanceID())
+	}
+
+	stop()
+	if elector.Leading() {
+		t.Error("stopped elector is still leading")
+	}
+	if holder, _, _ := store.Holder(context.Background(), "sync"); holder != "" {
+		t.Errorf("lease still held by %q after stopping", holder)
+	}
+}
+
+func TestOnlyOneElectorLeads(t *testing.T) {
+	store := newMemoryLeases()
+	first, firstStarted, stopFirst := runElector(store)
+	defer stopFirst()
+	second, secondStarted, stopSecond := runElector(store)
+	defer stopSecond()
+
+	time.Sleep(3 * testConfig.LeaseDuration)
+	if len(firstStarted)+len(secondStarted) != 1 {
+		t.Errorf("task started %d times, want once", len(firstStarted)+len(secondStarted))
+	}
+	if first.Leading() == second.Leading() {
+		t.Error("exactly one elector should be leading")
+	}
+}
+
+func TestElectorStepsDownWhenLeaseIsTaken(t *testing.T) {
+	store := newMemoryLeases()
+	elector, started, stop := runElector(store)
+	defer stop()
+
+	leaderCtx := waitStarted(t, started)
+	store.steal("sync")
+
+	waitStopped(t, leaderCtx)
+	waitNotLeading(t, elector)
+	if holder, _, _ := store.Holder(context.Background(), "sync"); holder != "someone-else" {
+		t.Errorf("lease of the new holder was released, now held by %q", holder)
+	}
+}
+
+func TestElectorTakesOverAfterLeaderStops(t *testing.T) {
+	store := newMemoryLeases()
+	_, firstStarted, stopFirst := runElector(store)
+	waitStarted(t, firstStarted)
+
+	second, secondStarted, stopSecond := runElector(store)
+	defer stopSecond()
+
+	stopFirst()
+	waitStarted(t, secondStarted)
+	if !second.Leading() {
+		t.Error("elector that took over is not leading")
+	}
+}
+
+func TestElectorStepsDownWhenRenewalsFail(t *testing.T) {
+	store := newMemoryLeases()
+	elector, started, stop := runElector(store)
+	defer stop()
+
+	leaderCtx := waitStarted(t, started)
+	store.failRenewals(errors.New("connection refused"))
+
+	// The task is cancelled before the lease can run out under it
+	select {
+	case <-leaderCtx.Done():
+	case <-time.After(testConfig.LeaseDuration):
+		t.Fatal("task kept running after the lease may have expired")
+	}
+	waitNotLeading(t, elector)
+}
+
+func TestElectorStepsDownWhenRenewalsHang(t *testing.T) {
+	store := newMemoryLeases()
+	elector, started, stop := runElector(store)
+	defer stop()
+
+	leaderCtx := waitStarted(t, started)
+	expiresAt := store.hangRenewals("sync")
+
+	// A renewal that never answers is given up when the lease runs out
+	waitStopped(t, leaderCtx)
+	if late := time.Since(expiresAt); late > 5*time.Millisecond {
+		t.Errorf("task stopped %v after the lease expired", late)
+	}
+	waitNotLeading(t, elector)
+}
@@ -0,0 +1,184 @@
+// Package metrics collects the service's Prometheus metrics: HTTP requests per
+// route, authentication failures, JWKS fetches, datastore latencies and Go
+// runtime stats. Other packages record into it and register their own
+// collectors, and Handler serves them all.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the service's own metric names
+const Namespace = "agent_auth"
+
+// Datastores timed by ObserveOperation
+const (
+	StoreMongo = "mongo"
+	StoreRedis = "redis"
+)
+
+// Authentication failure reasons
+const (
+	AuthMissingHeader         = "missing_header"
+	AuthMalformedHeader       = "malformed_header"
+	AuthMalformedToken        = "malformed_token"
+	AuthBadSignature          = "bad_signature"
+	AuthBadAudience           = "bad_audience"
+	AuthBadIssuer             = "bad_issuer"
+	AuthExpired               = "expired"
+	AuthNotYetValid           = "not_yet_valid"
+	AuthKeyUnavailable        = "key_unavailable"
+	AuthInactive              = "inactive"
+	AuthRevoked               = "revoked"
+	AuthRevocationUnavailable = "revocation_unavailable"
+	AuthInvalid               = "invalid"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths do
+// not each get their own series
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels requests with a method outside knownMethods. Clients
+// choose the method freely, so it is not used as a label as is.
+const otherMethod = "OTHER"
+
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// methodLabel returns the label value for the request method
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return otherMethod
+}
+
+// Registry holds every metric served by Handler
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "auth_failures_total",
+		Help:      "Rejected bearer tokens by reason.",
+	}, []string{"reason"})
+
+	jwksFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "jwks_fetches_total",
+		Help:      "JWKS downloads by endpoint and result.",
+	}, []string{"jwks_url", "result"})
+
+	operationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "datastore_operation_duration_seconds",
+		Help:      "Latency of data access operations by datastore, DAL and operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"store", "dal", "operation"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		authFailures,
+		jwksFetches,
+		operationDuration,
+	)
+}
+
+// MustRegister adds collectors to Registry, panicking if one is registered
+// twice
+func MustRegister(cs ...prometheus.Collector) {
+	Registry.MustRegister(cs...)
+}
+
+// Handler serves Registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts and times requests by their chi route pattern, such as
+// /projects/{project_id}, rather than by path
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		// The pattern is only complete once routing is done
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		method := methodLabel(r.Method)
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RecordAuthFailure counts a rejected token under one of the Auth reasons
+func RecordAuthFailure(reason string) {
+	authFailures.WithLabelValues(reason).Inc()
+}
+
+// RecordJWKSFetch counts a download of the key set at jwksURL
+func RecordJWKSFetch(jwksURL string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	jwksFetches.WithLabelValues(jwksURL, result).Inc()
+}
+
+// ObserveOperation starts timing a data access operation and returns the
+// function that records it:
+//
+//	defer metrics.ObserveOperation(metrics.StoreMongo, "projects", "Create")()
+func ObserveOperation(store, dal, operation string) func() {
+	start := time.Now()
+	return func() {
+		operationDuration.WithLabelValues(store, dal, operation).Observe(time.Since(start).Seconds())
+	}
+}
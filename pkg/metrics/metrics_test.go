@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareLabelsRequestsByRoutePattern(t *testing.T) {
+	api := chi.NewRouter()
+	api.Get("/projects/{project_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Mount("/", api)
+
+	for _, path := range []string{"/projects/a", "/projects/b", "/nowhere/c"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/projects/{project_id}", "204")); got != 2 {
+		t.Errorf("counted %v requests for the route pattern, want 2", got)
+	}
+	for _, path := range []string{"/projects/a", "/nowhere/c"} {
+		if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", path, "204")); got != 0 {
+			t.Errorf("request counted under its path %s", path)
+		}
+	}
+	if n := testutil.CollectAndCount(httpDuration); n < 1 {
+		t.Error("no request latency recorded")
+	}
+}
+
+func TestMiddlewareLabelsUnknownMethodsAsOther(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/methods", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, method := range []string{"FOO", "BAR"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/methods", nil))
+	}
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("OTHER", unmatchedRoute, "405")); got != 2 {
+		t.Errorf("counted %v requests with unknown methods, want 2", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("FOO", unmatchedRoute, "405")); got != 0 {
+		t.Error("request counted under its own method")
+	}
+}
+
+func TestHandlerServesRecordedMetrics(t *testing.T) {
+	RecordAuthFailure(AuthBadAudience)
+	RecordJWKSFetch("https://issuer.example.com/jwks", errors.New("timeout"))
+	ObserveOperation(StoreMongo, "projects", "GetByID")()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`agent_auth_auth_failures_total{reason="bad_audience"} 1`,
+		`agent_auth_jwks_fetches_total{jwks_url="https://issuer.example.com/jwks",result="error"} 1`,
+		`agent_auth_datastore_operation_duration_seconds_count{dal="projects",operation="GetByID",store="mongo"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package notify delivers outbound messages such as invitation and role
+// change emails. Handlers send through a Queue so a slow or unavailable mail
+// server never blocks a request.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrNoRecipients is returned for a message without recipients
+var ErrNoRecipients = errors.New("message has no recipients")
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages
+type Notifier interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// LogNotifier logs messages instead of delivering them, and saves each one
+// as a file in Dir when set. It is meant for local development. Only the
+// recipients and subject are logged: bodies carry secrets such as invitation
+// tokens, so they are only written to the outbox directory.
+type LogNotifier struct {
+	logger *zap.Logger
+	dir    string
+}
+
+// NewLogNotifier creates a notifier logging to logger and saving messages to
+// dir, which is created if needed. An empty dir only logs.
+func NewLogNotifier(logger *zap.Logger, dir string) (*LogNotifier, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+	}
+	return &LogNotifier{logger: logger, dir: dir}, nil
+}
+
+// Send logs msg and saves it to the outbox directory
+func (n *LogNotifier) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	n.logger.Info("notification",
+		zap.Strings("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.Int("bodyBytes", len(msg.Body)))
+
+	if n.dir == "" {
+		return nil
+	}
+
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(n.dir, name), msg.bytes("", time.Now()), 0o644); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	return nil
+}
+
+// bytes formats msg as an RFC 5322 message
+func (msg *Message) bytes(from string, date time.Time) []byte {
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", sanitizeHeader(strings.Join(msg.To, ", ")))
+	fmt.Fprintf(&b, "Subject: %s\r\n", sanitizeHeader(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// sanitizeHeader prevents header injection through line breaks
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
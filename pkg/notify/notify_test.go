@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type flakyNotifier struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     chan *Message
+}
+
+func (f *flakyNotifier) Send(ctx context.Context, msg *Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.attempts <= f.failures {
+		return errors.New("relay unavailable")
+	}
+	f.sent <- msg
+	return nil
+}
+
+func TestQueueRetriesUntilDelivered(t *testing.T) {
+	next := &flakyNotifier{failures: 2, sent: make(chan *Message, 1)}
+	q := NewQueue(next, zap.NewNop(), QueueConfig{
+		Workers:        1,
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+	})
+	defer q.Close()
+
+	if err := q.Send(context.Background(), &Message{To: []string{"a@example.com"}, Subject: "hi"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	select {
+	case msg := <-next.sent:
+		if msg.Subject != "hi" {
+			t.Errorf("subject = %q, want %q", msg.Subject, "hi")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered")
+	}
+
+	next.mu.Lock()
+	defer next.mu.Unlock()
+	if next.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", next.attempts)
+	}
+}
+
+type blockingNotifier struct {
+	release chan struct{}
+}
+
+func (b *blockingNotifier) Send(ctx context.Context, msg *Message) error {
+	<-b.release
+	return nil
+}
+
+func TestQueueSendNeverBlocks(t *testing.T) {
+	next := &blockingNotifier{release: make(chan struct{})}
+	q := NewQueue(next, zap.NewNop(), QueueConfig{Size: 1, Workers: 1})
+	defer q.Close()
+	defer close(next.release)
+
+	msg := &Message{To: []string{"a@example.com"}}
+
+	// One message is held by the worker and one fills the buffer
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = q.Send(context.Background(), msg)
+	}
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Send() error = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestRenderInvitation(t *testing.T) {
+	msg, err := Render(TemplateInvitation, InvitationData{
+		Workspace: "Acme\r\nBcc: evil@example.com",
+		InvitedBy: "owner@example.com",
+		Role:      "member",
+		AcceptURL: "https://app.example.com/invitations/accept",
+		Token:     "tok",
+		ExpiresAt: time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC),
+	}, "new@example.com")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		t.Errorf("subject contains a line break: %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "tok") || !strings.Contains(msg.Body, "2026-01-02 03:04 UTC") {
+		t.Errorf("body missing token or expiry: %q", msg.Body)
+	}
+	if len(msg.To) != 1 || msg.To[0] != "new@example.com" {
+		t.Errorf("to = %v", msg.To)
+	}
+
+	if _, err := Render("unknown", nil); err == nil {
+		t.Error("Render() of an unknown template succeeded")
+	}
+}
+
+func TestLogNotifierKeepsBodiesOutOfTheLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	dir := t.TempDir()
+	notifier, err := NewLogNotifier(zap.New(core), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &Message{
+		To:      []string{"user@example.com"},
+		Subject: "You are invited",
+		Body:    "Accept with token secret-token",
+	}
+	if err := notifier.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if logs.Len() != 1 {
+		t.Fatalf("logged %d entries, want 1", logs.Len())
+	}
+	for key, value := range logs.All()[0].ContextMap() {
+		if strings.Contains(fmt.Sprint(value), "secret-token") {
+			t.Errorf("message body logged under %s", key)
+		}
+	}
+
+	saved, err := os.ReadDir(dir)
+	if err != nil || len(saved) != 1 {
+		t.Fatalf("outbox holds %d messages, %v", len(saved), err)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, saved[0].Name()))
+	if !strings.Contains(string(content), "secret-token") {
+		t.Error("outbox message lacks its body")
+	}
+}
@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultQueueSize      = 1000
+	defaultWorkers        = 2
+	defaultMaxRetries     = 5
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+	defaultSendTimeout    = 30 * time.Second
+)
+
+// ErrQueueFull is returned when a message cannot be queued without blocking
+var ErrQueueFull = errors.New("notification queue is full")
+
+// ErrQueueClosed is returned for messages sent after Close
+var ErrQueueClosed = errors.New("notification queue is closed")
+
+// QueueConfig tunes a Queue. Zero values select the defaults.
+type QueueConfig struct {
+	Size           int           `mapstructure:"size"`
+	Workers        int           `mapstructure:"workers"`
+	MaxRetries     int           `mapstructure:"max_retries"`
+	InitialBackoff time.Duration `mapstructure:"-"`
+	MaxBackoff     time.Duration `mapstructure:"-"`
+	SendTimeout    time.Duration `mapstructure:"-"`
+}
+
+// Queue is a Notifier that hands messages to background workers, which
+// deliver them through the wrapped notifier and retry failures with
+// exponential backoff. Send never waits for delivery.
+type Queue struct {
+	next   Notifier
+	logger *zap.Logger
+	config QueueConfig
+
+	mu     sync.RWMutex
+	jobs   chan *Message
+	closed bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue creates a queue delivering through next and starts its workers
+func NewQueue(next Notifier, logger *zap.Logger, config QueueConfig) *Queue {
+	if config.Size <= 0 {
+		config.Size = defaultQueueSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.SendTimeout <= 0 {
+		config.SendTimeout = defaultSendTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		next:   next,
+		logger: logger,
+		config: config,
+		jobs:   make(chan *Message, config.Size),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	return q
+}
+
+// Send queues msg for delivery. It fails instead of blocking when the queue
+// is full.
+func (q *Queue) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.jobs <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits for the queued ones to be
+// attempted. Messages still waiting for a retry are dropped.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.jobs)
+	q.mu.Unlock()
+
+	q.cancel()
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for msg := range q.jobs {
+		q.deliver(msg)
+	}
+}
+
+// deliver sends msg, retrying with exponential backoff until it succeeds,
+// runs out of retries or the queue is closed
+func (q *Queue) deliver(msg *Message) {
+	backoff := q.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), q.config.SendTimeout)
+		err := q.next.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		if attempt >= q.config.MaxRetries {
+			q.logger.Error("Failed to deliver notification",
+				zap.Strings("to", msg.To),
+				zap.String("subject", msg.Subject),
+				zap.Int("attempts", attempt+1),
+				zap.Error(err))
+			return
+		}
+
+		q.logger.Warn("Retrying notification delivery",
+			zap.Strings("to", msg.To),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		select {
+		case <-time.After(backoff):
+		case <-q.ctx.Done():
+			q.logger.Error("Dropped notification on shutdown",
+				zap.Strings("to", msg.To),
+				zap.String("subject", msg.Subject))
+			return
+		}
+
+		backoff *= 2
+		if backoff > q.config.MaxBackoff {
+			backoff = q.config.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig configures delivery through an SMTP relay
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+
+	// StartTLS upgrades the connection before authenticating. Credentials
+	// are never sent over an unencrypted connection to a remote host.
+	StartTLS bool `mapstructure:"starttls"`
+
+	// Timeout bounds connecting to the relay
+	Timeout time.Duration `mapstructure:"-"`
+}
+
+// SMTPNotifier delivers messages through an SMTP relay
+type SMTPNotifier struct {
+	config SMTPConfig
+}
+
+// NewSMTPNotifier creates a notifier for the relay described by config
+func NewSMTPNotifier(config SMTPConfig) (*SMTPNotifier, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("smtp host is required")
+	}
+	if config.From == "" {
+		return nil, fmt.Errorf("smtp sender address is required")
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &SMTPNotifier{config: config}, nil
+}
+
+// Send delivers msg to every recipient
+func (n *SMTPNotifier) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	addr := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+	dialer := &net.Dialer{Timeout: n.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if n.config.StartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: n.config.Host}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	if n.config.Username != "" {
+		auth := smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.config.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(msg.bytes(n.config.From, time.Now())); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
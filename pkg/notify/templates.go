@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// Template names
+const (
+	TemplateInvitation           = "invitation"
+	TemplateRoleGranted          = "role_granted"
+	TemplateAccessRequestPending = "access_request_pending"
+	TemplateOwnershipTransfer    = "ownership_transfer"
+)
+
+// InvitationData fills the invitation template
+type InvitationData struct {
+	Workspace string
+	InvitedBy string
+	Role      string
+	AcceptURL string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// RoleGrantedData fills the role granted template
+type RoleGrantedData struct {
+	Project   string
+	Role      string
+	GrantedBy string
+}
+
+// AccessRequestData fills the access request pending template
+type AccessRequestData struct {
+	Requester string
+	Project   string
+	Role      string
+	Reason    string
+	ReviewURL string
+}
+
+// OwnershipTransferData fills the ownership transfer template
+type OwnershipTransferData struct {
+	ResourceType string
+	Resource     string
+	From         string
+	TransferID   string
+	ExpiresAt    time.Time
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templates = map[string]messageTemplate{
+	TemplateInvitation: parse(TemplateInvitation,
+		`You have been invited to join {{.Workspace}}`,
+		`{{.InvitedBy}} invited you to join the workspace {{.Workspace}} as {{.Role}}.
+
+Accept the invitation at {{.AcceptURL}} using this token:
+
+    {{.Token}}
+
+The invitation expires on {{.ExpiresAt.UTC.Format "2006-01-02 15:04 MST"}}. If you were not expecting it, you can ignore this message.
+`),
+	TemplateRoleGranted: parse(TemplateRoleGranted,
+		`You have been granted {{.Role}} in {{.Project}}`,
+		`{{.GrantedBy}} granted you the role {{.Role}} in the project {{.Project}}.
+`),
+	TemplateAccessRequestPending: parse(TemplateAccessRequestPending,
+		`Access request from {{.Requester}} for {{.Project}}`,
+		`{{.Requester}} requested the role {{.Role}} in the project {{.Project}}.
+{{if .Reason}}
+Reason: {{.Reason}}
+{{end}}
+Review the request at {{.ReviewURL}}
+`),
+	TemplateOwnershipTransfer: parse(TemplateOwnershipTransfer,
+		`{{.From}} wants to transfer {{.Resource}} to you`,
+		`{{.From}} asked to transfer ownership of the {{.ResourceType}} {{.Resource}} to you.
+
+Accept or decline transfer {{.TransferID}} before {{.ExpiresAt.UTC.Format "2006-01-02 15:04 MST"}}.
+`),
+}
+
+func parse(name, subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New(name + "_subject").Parse(subject)),
+		body:    template.Must(template.New(name + "_body").Parse(body)),
+	}
+}
+
+// Render builds a message addressed to to from the named template
+func Render(name string, data interface{}, to ...string) (*Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template: %s", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s body: %w", name, err)
+	}
+
+	return &Message{
+		To:      to,
+		Subject: sanitizeHeader(subject.String()),
+		Body:    body.String(),
+	}, nil
+}
@@ -0,0 +1,68 @@
+package revocation
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloomFilter is a fixed size bloom filter. It answers "definitely not
+// present" without false negatives, which lets the checker skip Redis for the
+// vast majority of tokens that were never revoked.
+type bloomFilter struct {
+	bits   []uint64
+	m      uint64
+	hashes uint64
+}
+
+// newBloomFilter sizes a filter for capacity items at the given false positive rate
+func newBloomFilter(capacity int, falsePositiveRate float64) *bloomFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &bloomFilter{
+		bits:   make([]uint64, (m+63)/64),
+		m:      m,
+		hashes: k,
+	}
+}
+
+// locations derives the bit positions for value using double hashing
+func (b *bloomFilter) locations(value string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	h1 := h.Sum64()
+	h2 := h1>>33 | h1<<31
+	if h2 == 0 {
+		h2 = 1
+	}
+	return h1, h2
+}
+
+func (b *bloomFilter) add(value string) {
+	h1, h2 := b.locations(value)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloomFilter) mayContain(value string) bool {
+	h1, h2 := b.locations(value)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
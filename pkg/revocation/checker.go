@@ -0,0 +1,275 @@
+// Package revocation answers whether a validated token has been revoked,
+// either individually by jti or for its whole subject before a point in time.
+// Subjects are only unique per issuer, so subject revocations are keyed on
+// the issuer and subject together.
+package revocation
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBloomCapacity     = 100000
+	defaultFalsePositiveRate = 0.01
+	defaultLRUCapacity       = 10000
+	defaultLRUTTL            = 30 * time.Second
+	defaultReloadInterval    = 5 * time.Minute
+	defaultRetryInterval     = 5 * time.Second
+)
+
+// Event types published when a revocation is recorded
+const (
+	EventTokenRevoked   = "token"
+	EventSubjectRevoked = "subject"
+)
+
+// Event announces a new revocation to every API replica
+type Event struct {
+	Type    string    `json:"type"`
+	JTI     string    `json:"jti,omitempty"`
+	Issuer  string    `json:"issuer,omitempty"`
+	Subject string    `json:"subject,omitempty"`
+	Before  time.Time `json:"before,omitempty"`
+}
+
+// Store persists revocations. It is implemented on top of Redis by redis_dal.
+// ListRevokedSubjects returns the cutoffs keyed by SubjectKey.
+type Store interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	RevokeSubject(ctx context.Context, issuer, subject string, before time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	GetSubjectRevocation(ctx context.Context, issuer, subject string) (time.Time, bool, error)
+	ListRevokedTokens(ctx context.Context) ([]string, error)
+	ListRevokedSubjects(ctx context.Context) (map[string]time.Time, error)
+	Subscribe(ctx context.Context) (<-chan Event, error)
+}
+
+// SubjectKey identifies the subject of an issuer. Both parts are escaped, so
+// no issuer and subject pair can collide with another. Tokens without an iss
+// claim have an empty issuer.
+func SubjectKey(issuer, subject string) string {
+	return url.QueryEscape(issuer) + "|" + url.QueryEscape(subject)
+}
+
+// Checker implements authz.RevocationChecker. Revoked jtis are mirrored into
+// a bloom filter and subject markers into a map, both kept current through
+// the store's event stream and a periodic reload, so a check for a token that
+// was never revoked costs no network round trip. Bloom filter hits are
+// confirmed against the store and the answer kept in a small LRU.
+type Checker struct {
+	store Store
+
+	mu       sync.RWMutex
+	bloom    *bloomFilter
+	subjects map[string]time.Time
+	ready    bool
+
+	lruMu sync.Mutex
+	lru   *lruCache
+
+	cancel context.CancelFunc
+}
+
+// NewChecker creates a checker and starts mirroring the store in the background
+func NewChecker(store Store) *Checker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &Checker{
+		store:    store,
+		bloom:    newBloomFilter(defaultBloomCapacity, defaultFalsePositiveRate),
+		subjects: make(map[string]time.Time),
+		lru:      newLRUCache(defaultLRUCapacity, defaultLRUTTL),
+		cancel:   cancel,
+	}
+	go c.run(ctx)
+
+	return c
+}
+
+// Close stops the background mirroring
+func (c *Checker) Close() {
+	c.cancel()
+}
+
+// IsRevoked reports whether the token described by claims has been revoked
+func (c *Checker) IsRevoked(ctx context.Context, claims map[string]interface{}) (bool, error) {
+	jti, _ := claims["jti"].(string)
+	issuer, _ := claims["iss"].(string)
+	subject, _ := claims["sub"].(string)
+
+	c.mu.RLock()
+	ready := c.ready
+	cutoff, subjectRevoked := c.subjects[SubjectKey(issuer, subject)]
+	mayBeRevoked := jti != "" && c.bloom.mayContain(jti)
+	c.mu.RUnlock()
+
+	// Until the mirror is loaded and subscribed it cannot rule anything out
+	if !ready && subject != "" {
+		var err error
+		cutoff, subjectRevoked, err = c.store.GetSubjectRevocation(ctx, issuer, subject)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if subjectRevoked {
+		// Tokens without iat cannot prove they were issued after the cutoff
+		iat, ok := claims["iat"].(float64)
+		if !ok || !time.Unix(int64(iat), 0).After(cutoff) {
+			return true, nil
+		}
+	}
+
+	if jti == "" || (ready && !mayBeRevoked) {
+		return false, nil
+	}
+
+	c.lruMu.Lock()
+	revoked, cached := c.lru.get(jti)
+	c.lruMu.Unlock()
+	if cached {
+		return revoked, nil
+	}
+
+	revoked, err := c.store.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	c.lruMu.Lock()
+	c.lru.set(jti, revoked)
+	c.lruMu.Unlock()
+
+	return revoked, nil
+}
+
+// RevokeToken records a jti revocation and applies it locally right away
+func (c *Checker) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return errors.New("jti is required")
+	}
+	if err := c.store.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	c.apply(Event{Type: EventTokenRevoked, JTI: jti})
+	return nil
+}
+
+// RevokeSubject revokes every token issued by issuer to subject before the
+// given time. Tokens of the same subject from other issuers are unaffected.
+func (c *Checker) RevokeSubject(ctx context.Context, issuer, subject string, before time.Time) error {
+	if subject == "" {
+		return errors.New("subject is required")
+	}
+	if err := c.store.RevokeSubject(ctx, issuer, subject, before); err != nil {
+		return err
+	}
+	c.apply(Event{Type: EventSubjectRevoked, Issuer: issuer, Subject: subject, Before: before})
+	return nil
+}
+
+// apply folds a revocation event into the local mirror
+func (c *Checker) apply(event Event) {
+	switch event.Type {
+	case EventTokenRevoked:
+		c.mu.Lock()
+		c.bloom.add(event.JTI)
+		c.mu.Unlock()
+
+		c.lruMu.Lock()
+		c.lru.set(event.JTI, true)
+		c.lruMu.Unlock()
+	case EventSubjectRevoked:
+		key := SubjectKey(event.Issuer, event.Subject)
+		c.mu.Lock()
+		if event.Before.After(c.subjects[key]) {
+			c.subjects[key] = event.Before
+		}
+		c.mu.Unlock()
+	}
+}
+
+// reload rebuilds the mirror from the store, dropping expired revocations
+func (c *Checker) reload(ctx context.Context) error {
+	jtis, err := c.store.ListRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+	subjects, err := c.store.ListRevokedSubjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	capacity := defaultBloomCapacity
+	if len(jtis)*2 > capacity {
+		capacity = len(jtis) * 2
+	}
+	bloom := newBloomFilter(capacity, defaultFalsePositiveRate)
+	for _, jti := range jtis {
+		bloom.add(jti)
+	}
+
+	c.mu.Lock()
+	c.bloom = bloom
+	c.subjects = subjects
+	c.ready = true
+	c.mu.Unlock()
+
+	c.lruMu.Lock()
+	c.lru.clear()
+	c.lruMu.Unlock()
+
+	return nil
+}
+
+func (c *Checker) setReady(ready bool) {
+	c.mu.Lock()
+	c.ready = ready
+	c.mu.Unlock()
+}
+
+// run subscribes to revocation events and periodically reloads the mirror.
+// Subscribing before each load ensures no event is missed in between, and
+// losing the subscription takes the mirror out of service until it is back.
+func (c *Checker) run(ctx context.Context) {
+	var events <-chan Event
+
+	ticker := time.NewTicker(defaultReloadInterval)
+	defer ticker.Stop()
+
+	retry := time.NewTimer(0)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				c.setReady(false)
+				retry.Reset(defaultRetryInterval)
+				continue
+			}
+			c.apply(event)
+		case <-ticker.C:
+			retry.Reset(0)
+		case <-retry.C:
+			if events == nil {
+				subscription, err := c.store.Subscribe(ctx)
+				if err != nil {
+					retry.Reset(defaultRetryInterval)
+					continue
+				}
+				events = subscription
+			}
+			if err := c.reload(ctx); err != nil {
+				retry.Reset(defaultRetryInterval)
+			}
+		}
+	}
+}
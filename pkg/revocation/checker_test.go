@@ -0,0 +1,262 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps revocations in memory and counts lookups of revoked jtis
+type memoryStore struct {
+	mu        sync.Mutex
+	tokens    map[string]bool
+	subjects  map[string]time.Time
+	jtiLookup int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		tokens:   make(map[string]bool),
+		subjects: make(map[string]time.Time),
+	}
+}
+
+func (m *memoryStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[jti] = true
+	return nil
+}
+
+func (m *memoryStore) RevokeSubject(ctx context.Context, issuer, subject string, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subjects[SubjectKey(issuer, subject)] = before
+	return nil
+}
+
+func (m *memoryStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jtiLookup++
+	return m.tokens[jti], nil
+}
+
+func (m *memoryStore) GetSubjectRevocation(ctx context.Context, issuer, subject string) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, ok := m.subjects[SubjectKey(issuer, subject)]
+	return before, ok, nil
+}
+
+func (m *memoryStore) ListRevokedTokens(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var jtis []string
+	for jti := range m.tokens {
+		jtis = append(jtis, jti)
+	}
+	return jtis, nil
+}
+
+func (m *memoryStore) ListRevokedSubjects(ctx context.Context) (map[string]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subjects := make(map[string]time.Time, len(m.subjects))
+	for key, before := range m.subjects {
+		subjects[key] = before
+	}
+	return subjects, nil
+}
+
+func (m *memoryStore) Subscribe(ctx context.Context) (<-chan Event, error) {
+	events := make(chan Event)
+	go func() {
+		<-ctx.Done()
+		close(events)
+	}()
+	return events, nil
+}
+
+func (m *memoryStore) lookups() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jtiLookup
+}
+
+func newReadyChecker(t *testing.T, store Store) *Checker {
+	t.Helper()
+	checker := NewChecker(store)
+	t.Cleanup(checker.Close)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		checker.mu.RLock()
+		ready := checker.ready
+		checker.mu.RUnlock()
+		if ready {
+			return checker
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("checker never loaded the store")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBloomFilterHasNoFalseNegatives(t *testing.T) {
+	bloom := newBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		bloom.add(fmt.Sprintf("jti-%d", i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !bloom.mayContain(fmt.Sprintf("jti-%d", i)) {
+			t.Fatalf("added jti-%d reported absent", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if bloom.mayContain(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 300 {
+		t.Errorf("%d false positives in 10000 lookups, want about 100", falsePositives)
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	lru := newLRUCache(2, time.Minute)
+	lru.set("a", true)
+	lru.set("b", false)
+	lru.get("a")
+	lru.set("c", true)
+
+	if _, ok := lru.get("b"); ok {
+		t.Error("least recently used entry was kept")
+	}
+	if revoked, ok := lru.get("a"); !ok || !revoked {
+		t.Errorf("get(a) = %v, %v, want true, true", revoked, ok)
+	}
+	if revoked, ok := lru.get("c"); !ok || !revoked {
+		t.Errorf("get(c) = %v, %v, want true, true", revoked, ok)
+	}
+	if n := lru.order.Len(); n != 2 {
+		t.Errorf("cache holds %d entries, want 2", n)
+	}
+}
+
+func TestLRUCacheExpiresEntries(t *testing.T) {
+	lru := newLRUCache(2, -time.Second)
+	lru.set("a", true)
+
+	if _, ok := lru.get("a"); ok {
+		t.Error("expired entry returned")
+	}
+	if n := lru.order.Len(); n != 0 {
+		t.Errorf("expired entry still held, %d entries", n)
+	}
+}
+
+func TestCheckerConfirmsBloomHitsAgainstStore(t *testing.T) {
+	store := newMemoryStore()
+	store.tokens["revoked"] = true
+	checker := newReadyChecker(t, store)
+
+	// A bloom hit for a jti the store does not hold, as for a false
+	// positive, falls through to the store and the answer is cached
+	checker.mu.Lock()
+	checker.bloom.add("false-positive")
+	checker.mu.Unlock()
+
+	for i := 0; i < 3; i++ {
+		revoked, err := checker.IsRevoked(context.Background(), map[string]interface{}{"jti": "false-positive"})
+		if err != nil || revoked {
+			t.Fatalf("IsRevoked() = %v, %v, want false", revoked, err)
+		}
+	}
+	if n := store.lookups(); n != 1 {
+		t.Errorf("store consulted %d times, want once", n)
+	}
+
+	revoked, err := checker.IsRevoked(context.Background(), map[string]interface{}{"jti": "revoked"})
+	if err != nil || !revoked {
+		t.Errorf("IsRevoked() = %v, %v for a revoked jti", revoked, err)
+	}
+
+	// Jtis the bloom filter rules out never reach the store
+	before := store.lookups()
+	for i := 0; i < 100; i++ {
+		checker.IsRevoked(context.Background(), map[string]interface{}{"jti": fmt.Sprintf("fresh-%d", i)})
+	}
+	if n := store.lookups() - before; n > 5 {
+		t.Errorf("store consulted %d times for jtis never revoked", n)
+	}
+}
+
+func TestCheckerSubjectCutoff(t *testing.T) {
+	cutoff := time.Now().Truncate(time.Second)
+	store := newMemoryStore()
+	checker := newReadyChecker(t, store)
+
+	if err := checker.RevokeSubject(context.Background(), "https://idp-a.example.com", "user-1", cutoff); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   bool
+	}{
+		{"issued before cutoff", map[string]interface{}{
+			"iss": "https://idp-a.example.com", "sub": "user-1", "iat": float64(cutoff.Add(-time.Minute).Unix()),
+		}, true},
+		{"issued at cutoff", map[string]interface{}{
+			"iss": "https://idp-a.example.com", "sub": "user-1", "iat": float64(cutoff.Unix()),
+		}, true},
+		{"issued after cutoff", map[string]interface{}{
+			"iss": "https://idp-a.example.com", "sub": "user-1", "iat": float64(cutoff.Add(time.Minute).Unix()),
+		}, false},
+		{"without iat", map[string]interface{}{
+			"iss": "https://idp-a.example.com", "sub": "user-1",
+		}, true},
+		{"same subject of another issuer", map[string]interface{}{
+			"iss": "https://idp-b.example.com", "sub": "user-1", "iat": float64(cutoff.Add(-time.Minute).Unix()),
+		}, false},
+		{"another subject", map[string]interface{}{
+			"iss": "https://idp-a.example.com", "sub": "user-2", "iat": float64(cutoff.Add(-time.Minute).Unix()),
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := checker.IsRevoked(context.Background(), tt.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+
+	// Reloading from the store keeps the cutoff scoped to its issuer
+	if err := checker.reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	revoked, _ := checker.IsRevoked(context.Background(), tests[0].claims)
+	other, _ := checker.IsRevoked(context.Background(), tests[4].claims)
+	if !revoked || other {
+		t.Errorf("after reload revoked = %v, other issuer revoked = %v", revoked, other)
+	}
+}
+
+func TestSubjectKeyDoesNotCollide(t *testing.T) {
+	if SubjectKey("a|b", "c") == SubjectKey("a", "b|c") {
+		t.Error("issuer and subject pairs collide")
+	}
+}
@@ -0,0 +1,74 @@
+package revocation
+
+import (
+	"container/list"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	revoked   bool
+	expiresAt time.Time
+}
+
+// lruCache remembers recent Redis answers for tokens the bloom filter could
+// not rule out. It is not safe for concurrent use; the checker guards it.
+type lruCache struct {
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+func newLRUCache(capacity int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(key string) (bool, bool) {
+	element, ok := c.items[key]
+	if !ok {
+		return false, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.items, key)
+		return false, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.revoked, true
+}
+
+func (c *lruCache) set(key string, revoked bool) {
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.revoked = revoked
+		entry.expiresAt = time.Now().Add(c.ttl)
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{
+		key:       key,
+		revoked:   revoked,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *lruCache) clear() {
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
@@ -0,0 +1,235 @@
+// Package tracing sets up OpenTelemetry tracing and provides the spans the
+// service records: one per HTTP route served, per outbound HTTP call and per
+// data access operation. Trace context is propagated in W3C traceparent and
+// tracestate headers, both from inbound requests and to outbound calls.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters selectable in Config
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+const (
+	instrumentationName = "github.com/agent-auth/agent-auth-api"
+	defaultServiceName  = "agent-auth-api"
+)
+
+// tracer records every span of the service. It delegates to the provider
+// installed by Setup, so it may be used before Setup runs.
+var tracer = otel.Tracer(instrumentationName)
+
+// Config selects where spans are exported. Endpoint is the OTLP/HTTP
+// collector address, such as localhost:4318, and defaults to the standard
+// OTEL_EXPORTER_OTLP_* environment variables. SampleRatio is the share of new
+// traces recorded, defaulting to all; traces started by a caller follow the
+// caller's decision.
+type Config struct {
+	Exporter       string            `mapstructure:"exporter"`
+	Endpoint       string            `mapstructure:"endpoint"`
+	Insecure       bool              `mapstructure:"insecure"`
+	Headers        map[string]string `mapstructure:"headers"`
+	SampleRatio    *float64          `mapstructure:"sample_ratio"`
+	ServiceName    string            `mapstructure:"-"`
+	ServiceVersion string            `mapstructure:"-"`
+}
+
+// Setup installs the W3C trace context propagator and, unless the exporter
+// is "none", a tracer provider exporting spans as configured. The returned
+// function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(config.ServiceVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service: %w", err)
+	}
+
+	ratio := 1.0
+	if config.SampleRatio != nil {
+		ratio = *config.SampleRatio
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(config.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(config.Headers))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil
+
+	default:
+		return nil, fmt.Errorf("tracing exporter %q is not supported", config.Exporter)
+	}
+}
+
+// Start starts an internal span, for background work such as the role sync
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// TraceOperation starts a client span for a data access operation and
+// returns the function that ends it:
+//
+//	defer tracing.TraceOperation(ctx, metrics.StoreMongo, "projects", "Create")()
+func TraceOperation(ctx context.Context, store, dal, operation string) func() {
+	system := attribute.String(string(semconv.DBSystemKey), store)
+	switch store {
+	case metrics.StoreMongo:
+		system = semconv.DBSystemMongoDB
+	case metrics.StoreRedis:
+		system = semconv.DBSystemRedis
+	}
+
+	_, span := tracer.Start(ctx, dal+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(system, semconv.DBOperationName(operation), attribute.String("dal", dal)),
+	)
+	return func() { span.End() }
+}
+
+// Middleware starts a server span for each request, continuing the trace of
+// the caller when it sent trace context. Spans are named after the chi route
+// pattern, such as GET /projects/{project_id}, once routing is done.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				span.SetName(r.Method + " " + pattern)
+				span.SetAttributes(semconv.HTTPRoute(pattern))
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// transport traces outbound requests and passes the trace context on
+type transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport wraps base, http.DefaultTransport when nil, so each request
+// gets a client span and carries the trace context of its own context
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The query is left out, it may carry credentials
+	target := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+
+	ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(target),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
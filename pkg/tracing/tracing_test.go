@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/go-chi/chi"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recorder holds every span ended in the tests. The global provider can only
+// be installed once, so tests tell their spans apart by trace ID.
+var recorder = tracetest.NewSpanRecorder()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	os.Exit(m.Run())
+}
+
+func spansOf(traceID trace.TraceID) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == traceID {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func attributeOf(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareContinuesCallerTraceUnderRoutePattern(t *testing.T) {
+	api := chi.NewRouter()
+	api.Get("/projects/{project_id}", func(w http.ResponseWriter, r *http.Request) {
+		defer TraceOperation(r.Context(), metrics.StoreRedis, "roles", "GetProjectRoles")()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Mount("/", api)
+
+	// The caller's span is not ended, so only this service's spans are
+	// recorded in its trace
+	_, caller := Start(context.Background(), "caller")
+	callerContext := caller.SpanContext()
+
+	req := httptest.NewRequest(http.MethodGet, "/projects/a", nil)
+	req.Header.Set("traceparent", "00-"+callerContext.TraceID().String()+"-"+callerContext.SpanID().String()+"-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := spansOf(callerContext.TraceID())
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans in the caller's trace, want 2", len(spans))
+	}
+
+	operation, server := spans[0], spans[1]
+	if server.Name() != "GET /projects/{project_id}" {
+		t.Errorf("server span is named %q", server.Name())
+	}
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("server span has kind %v", server.SpanKind())
+	}
+	if server.Parent().SpanID() != callerContext.SpanID() || !server.Parent().IsRemote() {
+		t.Errorf("server span has parent %s, want the caller's span", server.Parent().SpanID())
+	}
+	if got := attributeOf(server, "http.response.status_code").AsInt64(); got != http.StatusInternalServerError {
+		t.Errorf("server span has status code %d", got)
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("server span has status %v, want an error", server.Status().Code)
+	}
+
+	if operation.Name() != "roles.GetProjectRoles" {
+		t.Errorf("operation span is named %q", operation.Name())
+	}
+	if operation.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("operation span is not a child of the server span")
+	}
+	if got := attributeOf(operation, "db.system").AsString(); got != "redis" {
+		t.Errorf("operation span has db.system %q", got)
+	}
+}
+
+func TestTransportPropagatesTraceContext(t *testing.T) {
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer upstream.Close()
+
+	ctx, parent := Start(context.Background(), "caller")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL+"/jwks?secret=x", nil)
+	resp, err := (&http.Client{Transport: NewTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if req.Header.Get("traceparent") != "" {
+		t.Error("caller's request was modified")
+	}
+
+	spans := spansOf(parent.SpanContext().TraceID())
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+
+	client := spans[0]
+	if client.SpanKind() != trace.SpanKindClient || client.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("outbound call is not a client span of the caller")
+	}
+	want := "00-" + client.SpanContext().TraceID().String() + "-" + client.SpanContext().SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("upstream got traceparent %q, want %q", traceparent, want)
+	}
+	if got := attributeOf(client, "url.full").AsString(); got != upstream.URL+"/jwks" {
+		t.Errorf("client span has url.full %q", got)
+	}
+	if client.Status().Code != codes.Error {
+		t.Errorf("client span has status %v, want an error", client.Status().Code)
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Exporter: "jaeger"}); err == nil {
+		t.Error("unknown exporter accepted")
+	}
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenDestination is returned for webhook URLs that resolve to an
+// address inside the network the API runs in
+var ErrForbiddenDestination = errors.New("webhook url resolves to a private, loopback or link-local address")
+
+// forbiddenNetworks are the ranges the standard library does not classify
+// that deliveries must not reach either
+var forbiddenNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "this" network
+	mustParseCIDR("100.64.0.0/10"), // carrier-grade NAT
+	mustParseCIDR("64:ff9b::/96"),  // NAT64, which embeds IPv4 addresses
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// forbiddenIP reports whether ip is private, loopback, link-local or
+// otherwise not a public unicast address
+func forbiddenIP(ip net.IP) bool {
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckDestination resolves the host of rawURL and returns
+// ErrForbiddenDestination when any of its addresses is forbidden, unless the
+// dispatcher allows private networks. The check is repeated when each
+// delivery connects, as the host may resolve elsewhere by then.
+func (d *Dispatcher) CheckDestination(ctx context.Context, rawURL string) error {
+	if d.config.AllowPrivateNetworks {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if forbiddenIP(ip) {
+			return ErrForbiddenDestination
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if forbiddenIP(addr.IP) {
+			return ErrForbiddenDestination
+		}
+	}
+	return nil
+}
+
+// newTransport returns the transport deliveries are posted through. It
+// refuses to connect to forbidden addresses, checking the address actually
+// dialled after resolution, and ignores proxy settings so that check applies
+// to the endpoint itself.
+func newTransport(allowPrivateNetworks bool, timeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || forbiddenIP(ip) {
+				return ErrForbiddenDestination
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,370 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWorkers        = 2
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = 30 * time.Second
+	defaultMaxBackoff     = time.Hour
+	defaultTimeout        = 10 * time.Second
+	defaultPollInterval   = 5 * time.Second
+	defaultQueueSize      = 1000
+)
+
+// SubscriptionStore reads webhook subscriptions
+type SubscriptionStore interface {
+	Get(ctx context.Context, id bson.ObjectID) (*Subscription, error)
+	ListActive(ctx context.Context, workspaceID bson.ObjectID) ([]*Subscription, error)
+}
+
+// DeliveryStore persists deliveries. Claim leases the pending delivery that
+// has been due the longest by moving its NextAttemptAt to leaseUntil, so one
+// dispatcher among many attempts it, and a dispatcher that dies mid-attempt
+// leaves it to be retried once the lease runs out. It returns nil when no
+// delivery is due.
+type DeliveryStore interface {
+	Create(ctx context.Context, delivery *Delivery) (*Delivery, error)
+	Claim(ctx context.Context, now, leaseUntil time.Time) (*Delivery, error)
+	Update(ctx context.Context, delivery *Delivery) error
+}
+
+// Config tunes a Dispatcher. Zero values select the defaults. QueueSize
+// bounds the events waiting for their deliveries to be created.
+// AllowPrivateNetworks lets deliveries reach private, loopback and link-local
+// addresses, for local development.
+type Config struct {
+	QueueSize            int           `mapstructure:"queue_size"`
+	Workers              int           `mapstructure:"workers"`
+	MaxAttempts          int           `mapstructure:"max_attempts"`
+	InitialBackoff       time.Duration `mapstructure:"-"`
+	MaxBackoff           time.Duration `mapstructure:"-"`
+	Timeout              time.Duration `mapstructure:"-"`
+	PollInterval         time.Duration `mapstructure:"-"`
+	AllowPrivateNetworks bool          `mapstructure:"allow_private_networks"`
+}
+
+// Dispatcher queues a delivery for each subscription an audit event matches
+// and posts due deliveries from background workers. It is an audit.Listener;
+// events are handed to a background goroutine that creates their deliveries,
+// so recording an event never waits on the delivery store.
+type Dispatcher struct {
+	subscriptions SubscriptionStore
+	deliveries    DeliveryStore
+	client        *http.Client
+	logger        *zap.Logger
+	config        Config
+
+	mu     sync.RWMutex
+	events chan *audit.Event
+	closed bool
+	queued chan struct{}
+
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher and starts its workers
+func NewDispatcher(subscriptions SubscriptionStore, deliveries DeliveryStore, logger *zap.Logger, config Config) *Dispatcher {
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: newTransport(config.AllowPrivateNetworks, config.Timeout),
+			// A redirect could point the delivery somewhere the subscriber
+			// never registered, so it counts as a failed attempt
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger: logger,
+		config: config,
+		events: make(chan *audit.Event, config.QueueSize),
+		queued: make(chan struct{}),
+		wake:   make(chan struct{}, config.Workers),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	go d.queueDeliveries()
+	for i := 0; i < config.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+
+	return d
+}
+
+// EventRecorded hands event over to have a delivery queued for each
+// subscription that wants it. It never blocks: when the queue is full the
+// event is dropped and logged.
+func (d *Dispatcher) EventRecorded(event *audit.Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return
+	}
+
+	select {
+	case d.events <- event:
+	default:
+		d.logger.Error("Webhook event queue is full, dropping event",
+			zap.String("eventID", event.ID.Hex()),
+			zap.String("action", event.Action))
+	}
+}
+
+// queueDeliveries creates the deliveries of each event handed over, until the
+// events channel is closed
+func (d *Dispatcher) queueDeliveries() {
+	defer close(d.queued)
+	for event := range d.events {
+		d.queue(event)
+	}
+}
+
+// queue creates a delivery of event to each subscription that wants it
+func (d *Dispatcher) queue(event *audit.Event) {
+	subscriptions, err := d.subscriptions.ListActive(d.ctx, event.WorkspaceID)
+	if err != nil {
+		d.logger.Error("Failed to list webhook subscriptions",
+			zap.String("eventID", event.ID.Hex()),
+			zap.Error(err))
+		return
+	}
+
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscription.Wants(event) {
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = NewPayload(event); err != nil {
+				d.logger.Error("Failed to encode webhook payload", zap.String("eventID", event.ID.Hex()), zap.Error(err))
+				return
+			}
+		}
+
+		_, err := d.deliveries.Create(d.ctx, &Delivery{
+			WebhookID:     subscription.ID,
+			WorkspaceID:   event.WorkspaceID,
+			EventID:       event.ID,
+			EventType:     event.Action,
+			Payload:       payload,
+			NextAttemptAt: time.Now().UTC(),
+		})
+		if err != nil {
+			d.logger.Error("Failed to queue webhook delivery",
+				zap.String("webhookID", subscription.ID.Hex()),
+				zap.String("eventID", event.ID.Hex()),
+				zap.Error(err))
+			continue
+		}
+		d.notify()
+	}
+}
+
+// Redeliver queues a new delivery of the payload of delivery, which keeps
+// its own history
+func (d *Dispatcher) Redeliver(ctx context.Context, delivery *Delivery) (*Delivery, error) {
+	redelivery, err := d.deliveries.Create(ctx, &Delivery{
+		WebhookID:     delivery.WebhookID,
+		WorkspaceID:   delivery.WorkspaceID,
+		EventID:       delivery.EventID,
+		EventType:     delivery.EventType,
+		Payload:       delivery.Payload,
+		NextAttemptAt: time.Now().UTC(),
+		RedeliveryOf:  delivery.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.notify()
+	return redelivery, nil
+}
+
+// Close stops accepting events, waits for the deliveries of those already
+// handed over to be created, then stops the workers. Attempts in flight are
+// abandoned and count as failures, so they are retried later.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.events)
+	d.mu.Unlock()
+
+	<-d.queued
+	d.cancel()
+	d.wg.Wait()
+}
+
+// notify wakes an idle worker without blocking
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work attempts due deliveries until none are left, then waits to be woken
+// or for the next poll
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now().UTC()
+		// The lease outlasts an attempt, so it only lapses if this dispatcher dies
+		delivery, err := d.deliveries.Claim(d.ctx, now, now.Add(2*d.config.Timeout))
+		if err != nil {
+			d.logger.Error("Failed to claim webhook delivery", zap.Error(err))
+		}
+		if delivery != nil {
+			d.attempt(delivery)
+			continue
+		}
+
+		select {
+		case <-d.wake:
+		case <-ticker.C:
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// attempt posts delivery once and records the outcome: success, a retry
+// after backoff, or the dead-letter list once attempts run out
+func (d *Dispatcher) attempt(delivery *Delivery) {
+	subscription, err := d.subscriptions.Get(d.ctx, delivery.WebhookID)
+	if err != nil {
+		// Left leased, the delivery is retried once the lease lapses
+		d.logger.Error("Failed to get webhook subscription",
+			zap.String("webhookID", delivery.WebhookID.Hex()),
+			zap.Error(err))
+		return
+	}
+
+	var result Attempt
+	if subscription == nil || !subscription.Active {
+		result = Attempt{TimestampUTC: time.Now().UTC(), Error: "webhook was deleted or disabled"}
+		delivery.Status = StatusDeadLetter
+	} else {
+		result = d.post(subscription, delivery)
+		switch {
+		case result.Error == "" && result.StatusCode >= 200 && result.StatusCode < 300:
+			delivery.Status = StatusSucceeded
+		case len(delivery.Attempts)+1 >= d.config.MaxAttempts:
+			delivery.Status = StatusDeadLetter
+		default:
+			delivery.NextAttemptAt = result.TimestampUTC.Add(d.backoff(len(delivery.Attempts) + 1))
+		}
+	}
+	delivery.Attempts = append(delivery.Attempts, result)
+
+	if delivery.Status == StatusDeadLetter {
+		d.logger.Warn("Webhook delivery dead-lettered",
+			zap.String("deliveryID", delivery.ID.Hex()),
+			zap.String("webhookID", delivery.WebhookID.Hex()),
+			zap.Int("attempts", len(delivery.Attempts)),
+			zap.String("error", result.Error),
+			zap.Int("statusCode", result.StatusCode))
+	}
+
+	if err := d.deliveries.Update(d.ctx, delivery); err != nil {
+		d.logger.Error("Failed to record webhook delivery attempt",
+			zap.String("deliveryID", delivery.ID.Hex()),
+			zap.Error(err))
+	}
+}
+
+// post sends the signed payload of delivery to the subscription's URL
+func (d *Dispatcher) post(subscription *Subscription, delivery *Delivery) Attempt {
+	started := time.Now().UTC()
+	result := Attempt{TimestampUTC: started}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to build request: %v", err)
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "agent-auth-webhooks")
+	req.Header.Set(HeaderDelivery, delivery.ID.Hex())
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, fmt.Sprintf("%d", started.Unix()))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, started.Unix(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	result.DurationMs = time.Since(started).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// Only the status is kept; the body could echo whatever the endpoint,
+	// or a host it redirects to, chooses to reveal
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("endpoint responded %s", resp.Status)
+	}
+	return result
+}
+
+// backoff returns the wait before the retry that follows attempts failures
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.config.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+	return backoff
+}
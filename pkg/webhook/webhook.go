@@ -0,0 +1,159 @@
+// Package webhook notifies subscribed services of changes to policy and
+// membership. Every audit event is matched against the webhook subscriptions
+// of its workspace and queued as a delivery, which a Dispatcher posts with an
+// HMAC-SHA256 signature, retrying with exponential backoff until it succeeds
+// or is dead-lettered.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Delivery states
+const (
+	StatusPending    = "pending"
+	StatusSucceeded  = "succeeded"
+	StatusDeadLetter = "dead_letter"
+)
+
+// wildcard subscribes to every event type, or ends a prefix filter
+const wildcard = "*"
+
+// Subscription asks for the events of a workspace, or of one of its projects,
+// to be posted to URL. Empty EventTypes selects every event.
+type Subscription struct {
+	ID                  bson.ObjectID `json:"id" bson:"_id,omitempty"`
+	WorkspaceID         bson.ObjectID `json:"workspace_id" bson:"WorkspaceID"`
+	ProjectID           bson.ObjectID `json:"project_id,omitempty" bson:"ProjectID,omitempty"`
+	URL                 string        `json:"url" bson:"URL"`
+	Secret              string        `json:"-" bson:"Secret"`
+	EventTypes          []string      `json:"event_types" bson:"EventTypes"`
+	Description         string        `json:"description,omitempty" bson:"Description,omitempty"`
+	Active              bool          `json:"active" bson:"Active"`
+	CreatedBy           string        `json:"created_by" bson:"CreatedBy"`
+	CreatedTimestampUTC time.Time     `json:"created_timestamp_utc" bson:"CreatedTimestampUTC"`
+	UpdatedTimestampUTC time.Time     `json:"updated_timestamp_utc" bson:"UpdatedTimestampUTC"`
+}
+
+// Wants reports whether the subscription selects event
+func (s *Subscription) Wants(event *audit.Event) bool {
+	if !s.ProjectID.IsZero() && s.ProjectID != event.ProjectID {
+		return false
+	}
+	return Matches(s.EventTypes, event.Action)
+}
+
+// Attempt is one try at posting a delivery
+type Attempt struct {
+	TimestampUTC time.Time `json:"timestamp_utc" bson:"TimestampUTC"`
+	StatusCode   int       `json:"status_code,omitempty" bson:"StatusCode,omitempty"`
+	Error        string    `json:"error,omitempty" bson:"Error,omitempty"`
+	DurationMs   int64     `json:"duration_ms" bson:"DurationMs"`
+}
+
+// Delivery is one event queued for one subscription, with the history of the
+// attempts to post it. Payload holds the exact body that is signed and sent.
+type Delivery struct {
+	ID                  bson.ObjectID   `json:"id" bson:"_id,omitempty"`
+	WebhookID           bson.ObjectID   `json:"webhook_id" bson:"WebhookID"`
+	WorkspaceID         bson.ObjectID   `json:"workspace_id" bson:"WorkspaceID"`
+	EventID             bson.ObjectID   `json:"event_id" bson:"EventID"`
+	EventType           string          `json:"event_type" bson:"EventType"`
+	Payload             json.RawMessage `json:"payload" bson:"Payload"`
+	Status              string          `json:"status" bson:"Status"`
+	Attempts            []Attempt       `json:"attempts" bson:"Attempts"`
+	NextAttemptAt       time.Time       `json:"next_attempt_at" bson:"NextAttemptAt"`
+	RedeliveryOf        bson.ObjectID   `json:"redelivery_of,omitempty" bson:"RedeliveryOf,omitempty"`
+	CreatedTimestampUTC time.Time       `json:"created_timestamp_utc" bson:"CreatedTimestampUTC"`
+	UpdatedTimestampUTC time.Time       `json:"updated_timestamp_utc" bson:"UpdatedTimestampUTC"`
+}
+
+// Payload is the body posted for an event
+type Payload struct {
+	ID          string         `json:"id"`
+	Type        string         `json:"type"`
+	WorkspaceID string         `json:"workspace_id"`
+	ProjectID   string         `json:"project_id,omitempty"`
+	Actor       string         `json:"actor"`
+	TargetType  string         `json:"target_type"`
+	TargetID    string         `json:"target_id"`
+	Changes     []audit.Change `json:"changes"`
+	OccurredAt  time.Time      `json:"occurred_at"`
+}
+
+// NewPayload encodes the body posted for event
+func NewPayload(event *audit.Event) (json.RawMessage, error) {
+	payload := Payload{
+		ID:          event.ID.Hex(),
+		Type:        event.Action,
+		WorkspaceID: event.WorkspaceID.Hex(),
+		Actor:       event.Actor,
+		TargetType:  event.TargetType,
+		TargetID:    event.TargetID,
+		Changes:     event.Changes,
+		OccurredAt:  event.TimestampUTC,
+	}
+	if !event.ProjectID.IsZero() {
+		payload.ProjectID = event.ProjectID.Hex()
+	}
+	return json.Marshal(payload)
+}
+
+// Matches reports whether eventType is selected by filters. A filter is an
+// event type, a prefix such as "role.*", or "*"; no filters select everything.
+func Matches(filters []string, eventType string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if filter == wildcard || filter == eventType {
+			return true
+		}
+		if prefix := strings.TrimSuffix(filter, wildcard); prefix != filter && strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidFilter reports whether filter selects at least one known event type
+func ValidFilter(filter string) bool {
+	if filter == wildcard {
+		return true
+	}
+	if !strings.HasSuffix(filter, wildcard) {
+		for _, action := range audit.Actions {
+			if action == filter {
+				return true
+			}
+		}
+		return false
+	}
+
+	prefix := strings.TrimSuffix(filter, wildcard)
+	if !strings.HasSuffix(prefix, ".") {
+		return false
+	}
+	for _, action := range audit.Actions {
+		if strings.HasPrefix(action, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSecret returns a random signing secret for a subscription
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
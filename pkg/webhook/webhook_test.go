@@ -0,0 +1,349 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		filters   []string
+		eventType string
+		want      bool
+	}{
+		{nil, audit.ActionRoleCreate, true},
+		{[]string{"*"}, audit.ActionRoleCreate, true},
+		{[]string{"role.*"}, audit.ActionRoleAssign, true},
+		{[]string{"role.*"}, audit.ActionResourceCreate, false},
+		{[]string{"workspace.member.*"}, audit.ActionWorkspaceMemberAdd, true},
+		{[]string{"workspace.member.*"}, audit.ActionWorkspaceUpdate, false},
+		{[]string{"resource.update", "permission.update"}, audit.ActionPermissionUpdate, true},
+	}
+
+	for _, tt := range tests {
+		if got := Matches(tt.filters, tt.eventType); got != tt.want {
+			t.Errorf("Matches(%v, %q) = %v, want %v", tt.filters, tt.eventType, got, tt.want)
+		}
+	}
+}
+
+func TestValidFilter(t *testing.T) {
+	for _, filter := range []string{"*", "role.*", "workspace.member.*", "permission.update"} {
+		if !ValidFilter(filter) {
+			t.Errorf("%q should be valid", filter)
+		}
+	}
+	for _, filter := range []string{"", "role", "rol*", "billing.*", "role.rename"} {
+		if ValidFilter(filter) {
+			t.Errorf("%q should be invalid", filter)
+		}
+	}
+}
+
+func TestSignatureRoundTrip(t *testing.T) {
+	body := []byte(`{"type":"role.create"}`)
+	now := time.Now().Unix()
+	signature := Sign("secret", now, body)
+	timestamp := strconv.FormatInt(now, 10)
+
+	if err := VerifySignature("secret", timestamp, signature, body, time.Minute); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if err := VerifySignature("other", timestamp, signature, body, time.Minute); err != ErrInvalidSignature {
+		t.Errorf("wrong secret: got %v", err)
+	}
+	if err := VerifySignature("secret", timestamp, signature, []byte(`{}`), time.Minute); err != ErrInvalidSignature {
+		t.Errorf("edited body: got %v", err)
+	}
+	stale := strconv.FormatInt(now-3600, 10)
+	if err := VerifySignature("secret", stale, Sign("secret", now-3600, body), body, time.Minute); err != ErrStaleTimestamp {
+		t.Errorf("stale timestamp: got %v", err)
+	}
+}
+
+type memorySubscriptions map[bson.ObjectID]*Subscription
+
+func (m memorySubscriptions) Get(ctx context.Context, id bson.ObjectID) (*Subscription, error) {
+	return m[id], nil
+}
+
+func (m memorySubscriptions) ListActive(ctx context.Context, workspaceID bson.ObjectID) ([]*Subscription, error) {
+	var active []*Subscription
+	for _, s := range m {
+		if s.WorkspaceID == workspaceID && s.Active {
+			active = append(active, s)
+		}
+	}
+	return active, nil
+}
+
+type memoryDeliveries struct {
+	mu         sync.Mutex
+	deliveries []*Delivery
+	updated    chan *Delivery
+}
+
+func (m *memoryDeliveries) Create(ctx context.Context, delivery *Delivery) (*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delivery.ID = bson.NewObjectID()
+	delivery.Status = StatusPending
+	copied := *delivery
+	m.deliveries = append(m.deliveries, &copied)
+	return delivery, nil
+}
+
+func (m *memoryDeliveries) Claim(ctx context.Context, now, leaseUntil time.Time) (*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.deliveries {
+		if d.Status == StatusPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = leaseUntil
+			copied := *d
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryDeliveries) Update(ctx context.Context, delivery *Delivery) error {
+	m.mu.Lock()
+	for i, d := range m.deliveries {
+		if d.ID == delivery.ID {
+			copied := *delivery
+			m.deliveries[i] = &copied
+		}
+	}
+	m.mu.Unlock()
+	m.updated <- delivery
+	return nil
+}
+
+func subscribed(url string, eventTypes ...string) (memorySubscriptions, *Subscription) {
+	subscription := &Subscription{
+		ID:          bson.NewObjectID(),
+		WorkspaceID: bson.NewObjectID(),
+		URL:         url,
+		Secret:      "secret",
+		EventTypes:  eventTypes,
+		Active:      true,
+	}
+	return memorySubscriptions{subscription.ID: subscription}, subscription
+}
+
+func awaitUpdate(t *testing.T, deliveries *memoryDeliveries) *Delivery {
+	t.Helper()
+	select {
+	case d := <-deliveries.updated:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("delivery was not attempted")
+		return nil
+	}
+}
+
+func TestDispatcherDeliversSignedPayload(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer server.Close()
+
+	subscriptions, subscription := subscribed(server.URL, "role.*")
+	deliveries := &memoryDeliveries{updated: make(chan *Delivery, 10)}
+	d := NewDispatcher(subscriptions, deliveries, zap.NewNop(), Config{Workers: 1, PollInterval: time.Hour, AllowPrivateNetworks: true})
+	defer d.Close()
+
+	// Filtered out by event type
+	d.EventRecorded(&audit.Event{ID: bson.NewObjectID(), WorkspaceID: subscription.WorkspaceID, Action: audit.ActionResourceCreate})
+	d.EventRecorded(&audit.Event{ID: bson.NewObjectID(), WorkspaceID: subscription.WorkspaceID, Action: audit.ActionRoleCreate})
+
+	delivery := awaitUpdate(t, deliveries)
+	if delivery.Status != StatusSucceeded || len(delivery.Attempts) != 1 {
+		t.Fatalf("unexpected delivery %+v", delivery)
+	}
+
+	r := <-received
+	if r.Header.Get(HeaderEvent) != audit.ActionRoleCreate {
+		t.Errorf("event header = %q", r.Header.Get(HeaderEvent))
+	}
+	err := VerifySignature("secret", r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Minute)
+	if err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if len(deliveries.deliveries) != 1 {
+		t.Errorf("queued %d deliveries, want 1", len(deliveries.deliveries))
+	}
+}
+
+func TestDispatcherRetriesThenDeadLetters(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	subscriptions, subscription := subscribed(server.URL)
+	deliveries := &memoryDeliveries{updated: make(chan *Delivery, 10)}
+	d := NewDispatcher(subscriptions, deliveries, zap.NewNop(), Config{
+		Workers:              1,
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		PollInterval:         5 * time.Millisecond,
+		AllowPrivateNetworks: true,
+	})
+	defer d.Close()
+
+	d.EventRecorded(&audit.Event{ID: bson.NewObjectID(), WorkspaceID: subscription.WorkspaceID, Action: audit.ActionRoleDelete})
+
+	for i := 1; i <= 3; i++ {
+		delivery := awaitUpdate(t, deliveries)
+		if len(delivery.Attempts) != i {
+			t.Fatalf("attempt %d recorded %d attempts", i, len(delivery.Attempts))
+		}
+		if i < 3 && delivery.Status != StatusPending {
+			t.Fatalf("attempt %d left status %q", i, delivery.Status)
+		}
+		if i == 3 && delivery.Status != StatusDeadLetter {
+			t.Fatalf("final status %q, want %q", delivery.Status, StatusDeadLetter)
+		}
+		if delivery.Attempts[i-1].StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("attempt %d status code %d", i, delivery.Attempts[i-1].StatusCode)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 3 {
+		t.Errorf("endpoint called %d times, want 3", calls)
+	}
+}
+
+func TestCheckDestinationRefusesInternalAddresses(t *testing.T) {
+	d := &Dispatcher{}
+	for _, raw := range []string{
+		"https://127.0.0.1/hook",
+		"https://localhost:8443/hook",
+		"https://10.1.2.3/hook",
+		"https://192.168.0.10/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+		"https://[fd00::1]/hook",
+		"https://[fe80::1]/hook",
+		"https://100.64.0.1/hook",
+		"https://0.0.0.0/hook",
+	} {
+		if err := d.CheckDestination(context.Background(), raw); !errors.Is(err, ErrForbiddenDestination) {
+			t.Errorf("CheckDestination(%s) = %v, want ErrForbiddenDestination", raw, err)
+		}
+	}
+
+	if err := d.CheckDestination(context.Background(), "https://203.0.113.10/hook"); err != nil {
+		t.Errorf("public address refused: %v", err)
+	}
+
+	allowed := &Dispatcher{config: Config{AllowPrivateNetworks: true}}
+	if err := allowed.CheckDestination(context.Background(), "https://127.0.0.1/hook"); err != nil {
+		t.Errorf("loopback refused while private networks are allowed: %v", err)
+	}
+}
+
+func TestDispatcherRefusesToDialInternalAddresses(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	// The subscription was accepted while its host resolved elsewhere
+	subscriptions, subscription := subscribed(server.URL)
+	deliveries := &memoryDeliveries{updated: make(chan *Delivery, 10)}
+	d := NewDispatcher(subscriptions, deliveries, zap.NewNop(), Config{Workers: 1, PollInterval: time.Hour})
+	defer d.Close()
+
+	d.EventRecorded(&audit.Event{ID: bson.NewObjectID(), WorkspaceID: subscription.WorkspaceID, Action: audit.ActionRoleCreate})
+
+	delivery := awaitUpdate(t, deliveries)
+	if delivery.Status == StatusSucceeded || delivery.Attempts[0].Error == "" {
+		t.Fatalf("delivery to a loopback address was attempted: %+v", delivery.Attempts[0])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 0 {
+		t.Errorf("endpoint called %d times", calls)
+	}
+}
+
+// slowSubscriptions holds ListActive until release is closed
+type slowSubscriptions struct {
+	memorySubscriptions
+	release chan struct{}
+}
+
+func (s slowSubscriptions) ListActive(ctx context.Context, workspaceID bson.ObjectID) ([]*Subscription, error) {
+	<-s.release
+	return s.memorySubscriptions.ListActive(ctx, workspaceID)
+}
+
+func TestEventRecordedDoesNotWaitForTheStore(t *testing.T) {
+	subscriptions, subscription := subscribed("https://example.com/hook")
+	slow := slowSubscriptions{memorySubscriptions: subscriptions, release: make(chan struct{})}
+	deliveries := &memoryDeliveries{updated: make(chan *Delivery, 10)}
+	d := NewDispatcher(slow, deliveries, zap.NewNop(), Config{Workers: 1, PollInterval: time.Hour, QueueSize: 2})
+
+	recorded := make(chan struct{})
+	go func() {
+		defer close(recorded)
+		// One event is taken by the stalled goroutine, two fill the queue
+		// and the rest are dropped
+		for i := 0; i < 5; i++ {
+			d.EventRecorded(&audit.Event{ID: bson.NewObjectID(), WorkspaceID: subscription.WorkspaceID, Action: audit.ActionRoleCreate})
+		}
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		t.Fatal("EventRecorded waited for the subscription store")
+	}
+
+	// Closing creates the deliveries of the events already queued
+	close(slow.release)
+	d.Close()
+
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+	if n := len(deliveries.deliveries); n < 2 || n > 3 {
+		t.Errorf("created %d deliveries, want the queued events' 2 or 3", n)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	d := &Dispatcher{config: Config{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := d.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
@@ -66,3 +66,13 @@ func ErrorInternalServerError(err error) render.Renderer {
 		Error:          err.Error(),
 	}
 }
+
+// ErrorServiceUnavailable returns status 503 Service Unavailable while a dependency is down.
+func ErrorServiceUnavailable(err error) render.Renderer {
+	return &errorinterface.ErrorResponse{
+		HTTPStatusCode: http.StatusServiceUnavailable,
+		Status:         http.StatusText(http.StatusServiceUnavailable),
+		Err:            err,
+		Error:          err.Error(),
+	}
+}
@@ -0,0 +1,28 @@
+package router
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+// newAuditSigner builds the signer for audit exports from the base64 Ed25519
+// key in AUDIT_SIGNING_KEY, labelled with audit.signing_key_id from the app
+// config. Without a key exports are refused but everything else still works.
+func newAuditSigner() *audit.Signer {
+	key := os.Getenv("AUDIT_SIGNING_KEY")
+	if key == "" {
+		logger.NewLogger().Warn("AUDIT_SIGNING_KEY is not set, audit exports are disabled")
+		return nil
+	}
+
+	signer, err := audit.NewSigner(key, viper.GetString("audit.signing_key_id"))
+	if err != nil {
+		panic(fmt.Sprintf("AUDIT_SIGNING_KEY is invalid: %v", err))
+	}
+
+	return signer
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultChangefeedHeartbeat keeps idle event streams open behind proxies
+// that close connections after 30 to 60 seconds of silence
+const defaultChangefeedHeartbeat = 15 * time.Second
+
+// changefeedHeartbeat reads changefeed.heartbeat_seconds from the app config
+func changefeedHeartbeat() time.Duration {
+	seconds := viper.GetInt("changefeed.heartbeat_seconds")
+	if seconds <= 0 {
+		return defaultChangefeedHeartbeat
+	}
+	return time.Duration(seconds) * time.Second
+}
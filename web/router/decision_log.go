@@ -0,0 +1,46 @@
+package router
+
+import (
+	"fmt"
+
+	decision_logs_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/decision_logs"
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/agent-auth-api/pkg/decisionlog"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+// newDecisionLogger builds the authorization decision log from the
+// decision_log block of the app config. The sink is "stdout", "file", which
+// appends to decision_log.file, or "mongo", which writes to
+// DB_DECISION_LOGS_COLLECTION. It returns nil when no sink is configured.
+func newDecisionLogger() authz.DecisionLogger {
+	var sink decisionlog.Sink
+	switch driver := viper.GetString("decision_log.sink"); driver {
+	case "", "none":
+		return nil
+
+	case "stdout":
+		sink = decisionlog.NewStdoutSink()
+
+	case "file":
+		file, err := decisionlog.NewFileSink(viper.GetString("decision_log.file"))
+		if err != nil {
+			panic(fmt.Sprintf("decision_log.file is invalid: %v", err))
+		}
+		sink = file
+
+	case "mongo":
+		sink = decision_logs_dal.NewDecisionLogsDal()
+
+	default:
+		panic(fmt.Sprintf("decision_log.sink %q is not supported", driver))
+	}
+
+	var config decisionlog.Config
+	if err := viper.UnmarshalKey("decision_log", &config); err != nil {
+		panic(fmt.Sprintf("decision_log is invalid: %v", err))
+	}
+
+	return decisionlog.NewLogger(sink, logger.NewLogger(), config)
+}
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/notify"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+// newNotifier builds the outbound message queue from the notifications block
+// of the app config. The driver is "log" for local development, which logs
+// recipients and subjects and saves whole messages under outbox_dir when set,
+// or "smtp". The SMTP password is
+// read from SMTP_PASSWORD.
+func newNotifier() *notify.Queue {
+	log := logger.NewLogger()
+
+	var next notify.Notifier
+	switch driver := viper.GetString("notifications.driver"); driver {
+	case "", "log":
+		notifier, err := notify.NewLogNotifier(log, viper.GetString("notifications.outbox_dir"))
+		if err != nil {
+			panic(fmt.Sprintf("notifications.outbox_dir is invalid: %v", err))
+		}
+		next = notifier
+
+	case "smtp":
+		var config notify.SMTPConfig
+		if err := viper.UnmarshalKey("notifications.smtp", &config); err != nil {
+			panic(fmt.Sprintf("notifications.smtp is invalid: %v", err))
+		}
+		if password := os.Getenv("SMTP_PASSWORD"); password != "" {
+			config.Password = password
+		}
+		config.Timeout = time.Duration(viper.GetInt("notifications.smtp.timeout_seconds")) * time.Second
+
+		notifier, err := notify.NewSMTPNotifier(config)
+		if err != nil {
+			panic(fmt.Sprintf("notifications.smtp is invalid: %v", err))
+		}
+		next = notifier
+
+	default:
+		panic(fmt.Sprintf("notifications.driver %q is not supported", driver))
+	}
+
+	var config notify.QueueConfig
+	if err := viper.UnmarshalKey("notifications.queue", &config); err != nil {
+		panic(fmt.Sprintf("notifications.queue is invalid: %v", err))
+	}
+
+	return notify.NewQueue(next, log, config)
+}
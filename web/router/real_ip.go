@@ -0,0 +1,91 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// trustedProxies reads web.trusted_proxies, the addresses or CIDR ranges of
+// the load balancers and proxies in front of the API
+func trustedProxies() []*net.IPNet {
+	var proxies []*net.IPNet
+	for _, entry := range viper.GetStringSlice("web.trusted_proxies") {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				panic(fmt.Sprintf("web.trusted_proxies entry %q is not an address or CIDR range", entry))
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			panic(fmt.Sprintf("web.trusted_proxies entry %q is invalid: %v", entry, err))
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies
+}
+
+// realIP sets RemoteAddr to the client address reported by a trusted proxy.
+// X-Forwarded-For is read from the right, skipping the trusted proxies that
+// appended to it, and X-Real-IP is used when it is absent. Forwarding headers
+// of clients connecting directly are ignored, so they cannot choose the
+// address recorded in the audit log.
+func realIP(proxies []*net.IPNet) func(http.Handler) http.Handler {
+	trusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return false
+		}
+		for _, network := range proxies {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				peer = r.RemoteAddr
+			}
+			if !trusted(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := ""
+			if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+				hops := strings.Split(strings.Join(forwarded, ","), ",")
+				for i := len(hops) - 1; i >= 0; i-- {
+					hop := strings.TrimSpace(hops[i])
+					if net.ParseIP(hop) == nil {
+						break
+					}
+					client = hop
+					if !trusted(hop) {
+						break
+					}
+				}
+			} else if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+				client = ip
+			}
+
+			if client != "" {
+				r.RemoteAddr = client
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIPHonoursOnlyTrustedProxies(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	handler := realIP([]*net.IPNet{proxies})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       string
+	}{
+		{"direct client", "203.0.113.7:4000", http.Header{}, "203.0.113.7:4000"},
+		{"direct client spoofing", "203.0.113.7:4000", http.Header{
+			"X-Forwarded-For": {"198.51.100.1"}, "X-Real-Ip": {"198.51.100.2"},
+		}, "203.0.113.7:4000"},
+		{"trusted proxy", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+		{"spoofed entry left of the client", "10.0.0.1:4000", http.Header{
+			"X-Forwarded-For": {"192.0.2.9, 198.51.100.1"},
+		}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.1:4000", http.Header{
+			"X-Forwarded-For": {"198.51.100.1, 10.0.0.2", "10.0.0.3"},
+		}, "198.51.100.1"},
+		{"x-real-ip", "10.0.0.1:4000", http.Header{"X-Real-Ip": {"198.51.100.2"}}, "198.51.100.2"},
+		{"malformed header", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {"unknown"}}, "10.0.0.1:4000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r.RemoteAddr })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header = tt.header
+			handler(next).ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,38 +3,71 @@
 package router
 
 import (
+	"expvar"
 	"fmt"
 	"net/url"
 	"os"
 
 	swagger "github.com/swaggo/http-swagger"
 
+	audit_events_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/audit_events"
+	projects_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/projects"
+	webhook_deliveries_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/webhook_deliveries"
+	webhooks_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/webhooks"
+	"github.com/agent-auth/agent-auth-api/db/redis_dal"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/agent-auth-api/pkg/changefeed"
+	"github.com/agent-auth/agent-auth-api/pkg/metrics"
+	"github.com/agent-auth/agent-auth-api/pkg/revocation"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
 	_ "github.com/agent-auth/agent-auth-api/web/docs" // docs is generated by Swag CLI, you have to import it.
+	auditservice "github.com/agent-auth/agent-auth-api/web/services/audit"
+	"github.com/agent-auth/agent-auth-api/web/services/authorization"
+	changefeedservice "github.com/agent-auth/agent-auth-api/web/services/changefeed"
 	"github.com/agent-auth/agent-auth-api/web/services/health"
+	"github.com/agent-auth/agent-auth-api/web/services/invitations"
+	"github.com/agent-auth/agent-auth-api/web/services/ownership"
 	"github.com/agent-auth/agent-auth-api/web/services/projects"
 	"github.com/agent-auth/agent-auth-api/web/services/resources"
+	"github.com/agent-auth/agent-auth-api/web/services/revocations"
 	"github.com/agent-auth/agent-auth-api/web/services/roles_permissions"
+	"github.com/agent-auth/agent-auth-api/web/services/rolesync"
+	"github.com/agent-auth/agent-auth-api/web/services/webhooks"
 	"github.com/agent-auth/agent-auth-api/web/services/workspaces"
+	"github.com/agent-auth/common-lib/pkg/logger"
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/spf13/viper"
 )
 
 type router struct {
-	health           health.Health
-	resourceService  resources.ResourceService
-	tokenProvider    authz.TokenProvider
-	workspaceService workspaces.WorkspaceService
-	rolesService     roles_permissions.RolesService
-	projectService   projects.ProjectService
+	health            health.Health
+	resourceService   resources.ResourceService
+	tokenProvider     *authz.TokenProvider
+	revocationChecker *revocation.Checker
+	decisionLogger    authz.DecisionLogger
+	workspaceRoles    *workspaceRoles
+	projectDal        projects_dal.ProjectsDal
+	workspaceService  workspaces.WorkspaceService
+	rolesService      roles_permissions.RolesService
+	projectService    projects.ProjectService
+	revocationService revocations.RevocationService
+	authzService      authorization.AuthorizationService
+	invitationService invitations.InvitationService
+	ownershipService  ownership.OwnershipService
+	auditService      auditservice.AuditService
+	webhookService    webhooks.WebhookService
+	changefeedService changefeedservice.ChangefeedService
+	roleSyncService   rolesync.RoleSyncService
 }
 
 // NewRouter returns the router implementation
 func NewRouter() Router {
 	// Validate required environment variables
 	requiredEnvVars := map[string]string{
-		"KEYCLOAK_URL":      os.Getenv("KEYCLOAK_URL"),
-		"KEYCLOAK_TOKEN":    os.Getenv("KEYCLOAK_TOKEN"),
-		"API_AUTH_JWKS_URL": os.Getenv("API_AUTH_JWKS_URL"),
+		"KEYCLOAK_URL":   os.Getenv("KEYCLOAK_URL"),
+		"KEYCLOAK_TOKEN": os.Getenv("KEYCLOAK_TOKEN"),
 	}
 
 	// Check for missing required variables
@@ -46,8 +79,7 @@ func NewRouter() Router {
 
 	// Validate URL format for relevant variables
 	urlVars := map[string]string{
-		"KEYCLOAK_URL":      requiredEnvVars["KEYCLOAK_URL"],
-		"API_AUTH_JWKS_URL": requiredEnvVars["API_AUTH_JWKS_URL"],
+		"KEYCLOAK_URL": requiredEnvVars["KEYCLOAK_URL"],
 	}
 
 	for name, value := range urlVars {
@@ -56,15 +88,38 @@ func NewRouter() Router {
 		}
 	}
 
+	revocationChecker := revocation.NewChecker(redis_dal.NewRedisRevocationDal())
+	workspaceRoles := newWorkspaceRoles()
+	notifier := newNotifier()
+	auditEvents := audit_events_dal.NewAuditEventsDal()
+	webhooksDal := webhooks_dal.NewWebhooksDal()
+	deliveriesDal := webhook_deliveries_dal.NewWebhookDeliveriesDal()
+	// Every recorded audit event is offered to the workspace's webhooks
+	dispatcher := newWebhookDispatcher(webhooksDal, deliveriesDal)
+	// Policy changes are also streamed to project watchers through Redis
+	changes := redis_dal.NewRedisChangefeedDal()
+	publisher := changefeed.NewPublisher(changes, logger.NewLogger())
+	auditor := audit.NewRecorder(auditEvents, logger.NewLogger(), dispatcher, publisher)
+
 	return &router{
-		health:          health.NewHealth(),
-		resourceService: resources.NewResourceService(),
-		tokenProvider: *authz.NewTokenProvider(
-			requiredEnvVars["API_AUTH_JWKS_URL"],
-		),
-		workspaceService: workspaces.NewWorkspaceService(),
-		rolesService:     roles_permissions.NewRolesService(),
-		projectService:   projects.NewProjectService(),
+		health:            health.NewHealth(),
+		resourceService:   resources.NewResourceService(auditor),
+		tokenProvider:     newTokenProvider(),
+		revocationChecker: revocationChecker,
+		decisionLogger:    newDecisionLogger(),
+		workspaceRoles:    workspaceRoles,
+		projectDal:        projects_dal.NewProjectsDal(),
+		workspaceService:  workspaces.NewWorkspaceService(auditor),
+		rolesService:      roles_permissions.NewRolesService(notifier, auditor),
+		projectService:    projects.NewProjectService(auditor),
+		revocationService: revocations.NewRevocationService(revocationChecker),
+		authzService:      authorization.NewAuthorizationService(workspaceRoles),
+		invitationService: invitations.NewInvitationService(notifier, auditor, viper.GetString("notifications.invitation_url")),
+		ownershipService:  ownership.NewOwnershipService(notifier, auditor),
+		auditService:      auditservice.NewAuditService(auditEvents, newAuditSigner()),
+		webhookService:    webhooks.NewWebhookService(webhooksDal, deliveriesDal, dispatcher, auditor, viper.GetBool("webhooks.allow_http")),
+		changefeedService: changefeedservice.NewChangefeedService(changefeed.NewHub(changes, logger.NewLogger()), changefeedHeartbeat()),
+		roleSyncService:   rolesync.NewRoleSyncService(redis_dal.NewRedisLeaseDal(), redis_dal.NewRedisRolesDal()),
 	}
 }
 
@@ -72,6 +127,16 @@ func NewRouter() Router {
 func (router *router) Router(enableCORS bool) *chi.Mux {
 	r := chi.NewRouter()
 
+	// Requests are traced, continuing the caller's trace, and counted and
+	// timed per route pattern
+	r.Use(tracing.Middleware)
+	r.Use(metrics.Middleware)
+
+	// Request IDs and client addresses are recorded in the audit log.
+	// Forwarding headers are honoured only from web.trusted_proxies.
+	r.Use(middleware.RequestID)
+	r.Use(realIP(trustedProxies()))
+
 	// use CORS middleware if client is not served by this api, e.g. from other domain or CDN
 	if enableCORS {
 		r.Use(corsConfig().Handler)
@@ -80,104 +145,163 @@ func (router *router) Router(enableCORS bool) *chi.Mux {
 	// =================  health routes ======================
 	r.Get("/health", router.health.GetHealth)
 
+	// ================= runtime metrics ======================
+	// Prometheus scrape endpoint
+	r.Handle("/metrics", metrics.Handler())
+
 	// ================= API Documentation ====================
 	r.Get("/swagger/*", swagger.Handler())
 
 	protected := chi.NewRouter()
-	protected.Use(authz.AuthMiddleware(
-		&router.tokenProvider,
-		os.Getenv("API_AUTH_AUDIENCE"),
-		os.Getenv("API_AUTH_ISSUER"),
-	))
+	// Every authorization decision below, including failed authentication,
+	// goes to the decision log when one is configured
+	if router.decisionLogger != nil {
+		protected.Use(authz.LogDecisions(router.decisionLogger))
+	}
+	// Audience and issuer rules are configured per trusted issuer
+	protected.Use(authz.AuthMiddleware(router.tokenProvider, "", ""))
+	protected.Use(authz.RejectRevoked(router.revocationChecker))
+
+	// Token revocation is reserved for system administrators
+	protected.Route("/admin/revocations", func(r chi.Router) {
+		r.Use(authz.RequirePermission(authz.TokenRevoke))
+		r.Post("/tokens", router.revocationService.RevokeToken)
+		r.Post("/subjects", router.revocationService.RevokeSubject)
+	})
+
+	// Role sync status and repair are reserved for system administrators
+	protected.Route("/admin/sync", func(r chi.Router) {
+		r.With(authz.RequirePermission(authz.SyncRead)).Get("/status", router.roleSyncService.Status)
+		r.With(authz.RequirePermission(authz.SyncRead)).Get("/drift", router.roleSyncService.Drift)
+		r.With(authz.RequirePermission(authz.SyncResync)).Post("/projects/{project_id}/resync", router.roleSyncService.ResyncProject)
+	})
+
+	// Runtime variables, including role_sync_mode, role_sync_lag_seconds and
+	// role_sync_events, are reserved for system administrators
+	protected.With(authz.RequirePermission(authz.DebugRead)).Get("/debug/vars", expvar.Handler().ServeHTTP)
+
+	// Authorization decisions for resource servers and agents. Each check is
+	// further limited to projects of the caller's workspaces.
+	protected.Route("/v1/authorize", func(r chi.Router) {
+		r.Use(authz.RequirePermission(authz.AuthorizeCheck))
+		r.Post("/", router.authzService.Authorize)
+		r.Post("/batch", router.authzService.AuthorizeBatch)
+	})
+
+	// Access to each route is granted through authz.RolePermissions, or within
+	// a workspace through the caller's authz.WorkspaceRolePermissions
 
 	// Add workspace routes
 	protected.Route("/workspaces", func(r chi.Router) {
-		// Workspace Admin routes
-		r.With(authz.RequireRoles(authz.WorkspaceAdmin, authz.SystemAdmin)).
-			Group(func(r chi.Router) {
-				r.Post("/", router.workspaceService.Create)
-				r.Put("/{workspace_id}", router.workspaceService.Update)
-				r.Delete("/{workspace_id}", router.workspaceService.Delete)
+		r.With(authz.RequirePermission(authz.WorkspaceCreate)).Post("/", router.workspaceService.Create)
+		r.Get("/", router.workspaceService.List)
+
+		r.Route("/{workspace_id}", func(r chi.Router) {
+			r.Use(authz.ResolveWorkspace(router.workspaceRoles, workspaceIDFromURL))
+			r.With(authz.RequirePermission(authz.WorkspaceRead)).Get("/", router.workspaceService.Get)
+			r.With(authz.RequirePermission(authz.WorkspaceUpdate)).Put("/", router.workspaceService.Update)
+			r.With(authz.RequirePermission(authz.WorkspaceDelete)).Delete("/", router.workspaceService.Delete)
+
+			r.Route("/members", func(r chi.Router) {
+				r.With(authz.RequirePermission(authz.MemberList)).Get("/", router.workspaceService.ListMembers)
+				r.With(authz.RequirePermission(authz.MemberAdd)).Post("/", router.workspaceService.AddMember)
+				r.With(authz.RequirePermission(authz.MemberRemove)).Delete("/{member_id}", router.workspaceService.RemoveMember)
+			})
+
+			r.Route("/invitations", func(r chi.Router) {
+				r.With(authz.RequirePermission(authz.InvitationList)).Get("/", router.invitationService.ListByWorkspace)
+				r.With(authz.RequirePermission(authz.InvitationCreate)).Post("/", router.invitationService.Create)
+				r.With(authz.RequirePermission(authz.InvitationRevoke)).Delete("/{invitation_id}", router.invitationService.Revoke)
 			})
 
-		// Viewer routes (and above)
-		r.With(authz.RequireRoles(
-			authz.AppViewer,
-			authz.AppDeveloper,
-			authz.AppAdmin,
-			authz.WorkspaceAdmin,
-			authz.SystemAdmin,
-		)).Group(func(r chi.Router) {
-			r.Get("/", router.workspaceService.List)
-			r.Get("/{workspace_id}", router.workspaceService.Get)
+			r.With(authz.RequirePermission(authz.WorkspaceTransfer)).Post("/transfer", router.ownershipService.TransferWorkspace)
+			r.With(authz.RequirePermission(authz.WorkspaceRead)).Get("/transfers", router.ownershipService.ListWorkspaceTransfers)
+
+			r.Route("/webhooks", func(r chi.Router) {
+				r.With(authz.RequirePermission(authz.WebhookCreate)).Post("/", router.webhookService.Create)
+				r.With(authz.RequirePermission(authz.WebhookList)).Get("/", router.webhookService.List)
+				r.With(authz.RequirePermission(authz.WebhookRead)).Get("/dead-letters", router.webhookService.ListDeadLetters)
+				r.With(authz.RequirePermission(authz.WebhookRead)).Get("/{webhook_id}", router.webhookService.Get)
+				r.With(authz.RequirePermission(authz.WebhookUpdate)).Put("/{webhook_id}", router.webhookService.Update)
+				r.With(authz.RequirePermission(authz.WebhookDelete)).Delete("/{webhook_id}", router.webhookService.Delete)
+				r.With(authz.RequirePermission(authz.WebhookRead)).Get("/{webhook_id}/deliveries", router.webhookService.ListDeliveries)
+				r.With(authz.RequirePermission(authz.WebhookUpdate)).Post("/{webhook_id}/deliveries/{delivery_id}/redeliver", router.webhookService.Redeliver)
+			})
 		})
 	})
 
-	// Path for all project operations
-	protected.Route("/projects", func(r chi.Router) {
-		r.With(authz.RequireRoles(authz.WorkspaceAdmin, authz.SystemAdmin, authz.AppAdmin)).
-			Group(func(r chi.Router) {
-				r.Post("/", router.projectService.Create)
-				r.Put("/{project_id}", router.projectService.Update)
-				r.Delete("/{project_id}", router.projectService.Delete)
-			})
+	// Invitations addressed to the caller, who is not yet a workspace member
+	protected.Route("/invitations", func(r chi.Router) {
+		r.Get("/", router.invitationService.ListMine)
+		r.Post("/accept", router.invitationService.Accept)
+		r.Post("/decline", router.invitationService.Decline)
+	})
 
-		r.With(authz.RequireRoles(
-			authz.WorkspaceAdmin,
-			authz.SystemAdmin,
-			authz.AppViewer,
-			authz.AppDeveloper,
-			authz.AppAdmin,
-		)).Group(func(r chi.Router) {
-			r.Get("/", router.projectService.List)
-			r.Get("/{project_id}", router.projectService.Get)
-		})
+	// Audit log of a workspace, optionally narrowed to one of its projects
+	protected.Route("/audit", func(r chi.Router) {
+		r.Use(authz.ResolveWorkspace(router.workspaceRoles, workspaceIDFromQuery))
+		r.With(authz.RequirePermission(authz.AuditRead)).Get("/", router.auditService.Query)
+		r.With(authz.RequirePermission(authz.AuditRead)).Get("/verify", router.auditService.Verify)
+		r.With(authz.RequirePermission(authz.AuditRead)).Get("/export", router.auditService.Export)
 	})
 
-	// Add roles and permissions routes
-	protected.Route("/projects/{project_id}/roles", func(r chi.Router) {
-		r.With(authz.RequireRoles(authz.WorkspaceAdmin, authz.SystemAdmin, authz.AppAdmin, authz.AppDeveloper)).
-			Group(func(r chi.Router) {
-				r.Post("/", router.rolesService.CreateRole)
-				r.Delete("/", router.rolesService.DeleteRolesByProject)
-				r.Delete("/{role_id}", router.rolesService.DeleteRole)
-			})
+	// Ownership transfers addressed to or requested by the caller
+	protected.Route("/transfers", func(r chi.Router) {
+		r.Get("/", router.ownershipService.ListMine)
+		r.Post("/{transfer_id}/accept", router.ownershipService.Accept)
+		r.Post("/{transfer_id}/decline", router.ownershipService.Decline)
+		r.Post("/{transfer_id}/cancel", router.ownershipService.Cancel)
+	})
 
-		r.With(authz.RequireRoles(authz.WorkspaceAdmin, authz.SystemAdmin, authz.AppAdmin, authz.AppDeveloper)).
-			Group(func(r chi.Router) {
-				r.Put("/{role_id}/permissions", router.rolesService.UpdatePermission)
+	// Path for all project operations, scoped to the workspace owning the project
+	protected.Route("/projects", func(r chi.Router) {
+		r.Post("/", router.projectService.Create)
+		r.Get("/", router.projectService.List)
+
+		r.Route("/{project_id}", func(r chi.Router) {
+			r.Use(authz.ResolveWorkspace(router.workspaceRoles, projectWorkspaceID(router.projectDal)))
+			r.With(authz.RequirePermission(authz.ProjectRead)).Get("/", router.projectService.Get)
+			r.With(authz.RequirePermission(authz.ProjectUpdate)).Put("/", router.projectService.Update)
+			r.With(authz.RequirePermission(authz.ProjectDelete)).Delete("/", router.projectService.Delete)
+
+			r.Route("/members", func(r chi.Router) {
+				r.With(authz.RequirePermission(authz.ProjectMemberList)).Get("/", router.projectService.ListMembers)
+				r.With(authz.RequirePermission(authz.ProjectMemberAdd)).Post("/", router.projectService.AddMember)
+				r.With(authz.RequirePermission(authz.ProjectMemberRemove)).Delete("/{member_id}", router.projectService.RemoveMember)
 			})
 
-		r.With(authz.RequireRoles(
-			authz.WorkspaceAdmin,
-			authz.SystemAdmin,
-			authz.AppAdmin,
-			authz.AppDeveloper,
-			authz.AppViewer,
-		)).Group(func(r chi.Router) {
-			r.Get("/", router.rolesService.GetRolesByProject)
-			r.Get("/{role_id}", router.rolesService.GetRole)
-		})
-	})
+			r.With(authz.RequirePermission(authz.ProjectTransfer)).Post("/transfer", router.ownershipService.TransferProject)
+			r.With(authz.RequirePermission(authz.ProjectRead)).Get("/transfers", router.ownershipService.ListProjectTransfers)
+
+			// Server-Sent Events stream of role, permission and resource changes
+			r.With(authz.RequirePermission(authz.ProjectWatch)).Get("/events", router.changefeedService.Stream)
+
+			// Add roles and permissions routes
+			r.Route("/roles", func(r chi.Router) {
+				r.With(authz.RequirePermission(authz.RoleCreate)).Post("/", router.rolesService.CreateRole)
+				r.With(authz.RequirePermission(authz.RoleList)).Get("/", router.rolesService.GetRolesByProject)
+				r.With(authz.RequirePermission(authz.RoleDelete)).Delete("/", router.rolesService.DeleteRolesByProject)
+				r.With(authz.RequirePermission(authz.RoleRead)).Get("/{role_id}", router.rolesService.GetRole)
+				r.With(authz.RequirePermission(authz.RoleDelete)).Delete("/{role_id}", router.rolesService.DeleteRole)
+				r.With(authz.RequirePermission(authz.RoleUpdate)).Put("/{role_id}/permissions", router.rolesService.UpdatePermission)
+
+				r.Route("/{role_id}/members", func(r chi.Router) {
+					r.With(authz.RequirePermission(authz.RoleRead)).Get("/", router.rolesService.ListRoleMembers)
+					r.With(authz.RequirePermission(authz.RoleAssign)).Post("/", router.rolesService.AssignRole)
+					r.With(authz.RequirePermission(authz.RoleAssign)).Delete("/{subject}", router.rolesService.UnassignRole)
+				})
+			})
 
-	// Path for all resource operations
-	protected.Route("/projects/{project_id}/resources", func(r chi.Router) {
-		r.With(authz.RequireRoles(authz.WorkspaceAdmin, authz.SystemAdmin, authz.AppAdmin, authz.AppDeveloper)).
-			Group(func(r chi.Router) {
-				r.Post("/", router.resourceService.Create)
-				r.Put("/{resource_id}", router.resourceService.Update)
-				r.Delete("/{resource_id}", router.resourceService.Delete)
+			// Path for all resource operations
+			r.Route("/resources", func(r chi.Router) {
+				r.With(authz.RequirePermission(authz.ResourceCreate)).Post("/", router.resourceService.Create)
+				r.With(authz.RequirePermission(authz.ResourceList)).Get("/", router.resourceService.ListByProject)
+				r.With(authz.RequirePermission(authz.ResourceRead)).Get("/{resource_id}", router.resourceService.Get)
+				r.With(authz.RequirePermission(authz.ResourceUpdate)).Put("/{resource_id}", router.resourceService.Update)
+				r.With(authz.RequirePermission(authz.ResourceDelete)).Delete("/{resource_id}", router.resourceService.Delete)
 			})
 
-		r.With(authz.RequireRoles(
-			authz.WorkspaceAdmin,
-			authz.SystemAdmin,
-			authz.AppAdmin,
-			authz.AppDeveloper,
-			authz.AppViewer,
-		)).Group(func(r chi.Router) {
-			r.Get("/", router.resourceService.ListByProject)
-			r.Get("/{resource_id}", router.resourceService.Get)
+			r.With(authz.RequirePermission(authz.RoleRead)).Get("/subjects/{subject}/roles", router.rolesService.ListSubjectRoles)
 		})
 	})
 
@@ -0,0 +1,134 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/agent-auth-api/pkg/tracing"
+	"github.com/spf13/viper"
+)
+
+// newTokenProvider builds the token provider from the trusted issuers listed
+// under auth.issuers in the app config. Without that list a single issuer is
+// configured from API_AUTH_JWKS_URL, API_AUTH_ISSUER and API_AUTH_AUDIENCE,
+// trusted for user tokens.
+func newTokenProvider() *authz.TokenProvider {
+	var issuers []authz.IssuerConfig
+	if err := viper.UnmarshalKey("auth.issuers", &issuers); err != nil {
+		panic(fmt.Sprintf("auth.issuers is invalid: %v", err))
+	}
+
+	if len(issuers) == 0 {
+		jwksURL := os.Getenv("API_AUTH_JWKS_URL")
+		issuer := os.Getenv("API_AUTH_ISSUER")
+		if jwksURL == "" && issuer == "" {
+			panic("auth.issuers or API_AUTH_JWKS_URL is required")
+		}
+
+		legacy := authz.IssuerConfig{
+			Issuer:  issuer,
+			JWKSURL: jwksURL,
+			Users:   true,
+		}
+		if audience := os.Getenv("API_AUTH_AUDIENCE"); audience != "" {
+			legacy.Audiences = []string{audience}
+		}
+		for _, alg := range strings.Split(os.Getenv("API_AUTH_ALLOWED_ALGORITHMS"), ",") {
+			if alg = strings.TrimSpace(alg); alg != "" {
+				legacy.AllowedAlgorithms = append(legacy.AllowedAlgorithms, alg)
+			}
+		}
+		issuers = append(issuers, legacy)
+	}
+
+	seen := make(map[string]bool, len(issuers))
+	for _, issuer := range issuers {
+		if seen[issuer.Issuer] {
+			panic(fmt.Sprintf("issuer %q is configured more than once", issuer.Issuer))
+		}
+		seen[issuer.Issuer] = true
+
+		for _, role := range issuer.AllowedRoles {
+			if _, ok := authz.RolePermissions[authz.Role(role)]; !ok {
+				panic(fmt.Sprintf("issuer %q allows unknown role %q", issuer.Issuer, role))
+			}
+		}
+
+		for _, value := range []string{issuer.Issuer, issuer.JWKSURL, issuer.DiscoveryURL} {
+			if value == "" {
+				continue
+			}
+			if _, err := url.Parse(value); err != nil {
+				panic(fmt.Sprintf("issuer %q has an invalid URL: %v", issuer.Issuer, err))
+			}
+		}
+	}
+
+	return authz.NewTokenProviderWithConfig(authz.TokenProviderConfig{
+		Issuers:       issuers,
+		JWKSCache:     authz.DefaultJWKSCacheConfig(),
+		Introspection: introspectionConfig(),
+		ClaimMapper:   claimMapper(),
+	})
+}
+
+// claimMapper compiles the claim paths under auth.claim_mapping. Fields left
+// out of the config fall back to authz.DefaultClaimMapping.
+func claimMapper() *authz.ClaimMapper {
+	var mapping authz.ClaimMapping
+	if err := viper.UnmarshalKey("auth.claim_mapping", &mapping); err != nil {
+		panic(fmt.Sprintf("auth.claim_mapping is invalid: %v", err))
+	}
+
+	mapper, err := authz.NewClaimMapper(mapping)
+	if err != nil {
+		panic(fmt.Sprintf("auth.claim_mapping is invalid: %v", err))
+	}
+
+	return mapper
+}
+
+// introspectionConfig reads auth.introspection from the app config, with the
+// client credentials overridable through API_AUTH_INTROSPECTION_CLIENT_ID and
+// API_AUTH_INTROSPECTION_CLIENT_SECRET. It returns nil when no endpoint is set.
+func introspectionConfig() *authz.IntrospectionConfig {
+	var config authz.IntrospectionConfig
+	if err := viper.UnmarshalKey("auth.introspection", &config); err != nil {
+		panic(fmt.Sprintf("auth.introspection is invalid: %v", err))
+	}
+
+	if endpoint := os.Getenv("API_AUTH_INTROSPECTION_URL"); endpoint != "" {
+		config.Endpoint = endpoint
+	}
+	if config.Endpoint == "" {
+		return nil
+	}
+	if _, err := url.Parse(config.Endpoint); err != nil {
+		panic(fmt.Sprintf("auth.introspection.endpoint is invalid: %v", err))
+	}
+
+	if clientID := os.Getenv("API_AUTH_INTROSPECTION_CLIENT_ID"); clientID != "" {
+		config.ClientID = clientID
+	}
+	if clientSecret := os.Getenv("API_AUTH_INTROSPECTION_CLIENT_SECRET"); clientSecret != "" {
+		config.ClientSecret = clientSecret
+	}
+	if config.ClientID == "" || config.ClientSecret == "" {
+		panic("auth.introspection requires client credentials")
+	}
+
+	config.HTTPClient = &http.Client{
+		Transport: tracing.NewTransport(nil),
+		Timeout:   time.Duration(viper.GetInt("auth.introspection.timeout_seconds")) * time.Second,
+	}
+	if config.HTTPClient.Timeout <= 0 {
+		config.HTTPClient.Timeout = 10 * time.Second
+	}
+
+	return &config
+}
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"fmt"
+	"time"
+
+	webhook_deliveries_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/webhook_deliveries"
+	webhooks_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/webhooks"
+	"github.com/agent-auth/agent-auth-api/pkg/webhook"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+// newWebhookDispatcher starts the webhook delivery workers configured by the
+// webhooks block of the app config. Durations are given in seconds.
+func newWebhookDispatcher(subscriptions webhooks_dal.WebhooksDal, deliveries webhook_deliveries_dal.WebhookDeliveriesDal) *webhook.Dispatcher {
+	var config webhook.Config
+	if err := viper.UnmarshalKey("webhooks", &config); err != nil {
+		panic(fmt.Sprintf("webhooks is invalid: %v", err))
+	}
+	config.InitialBackoff = time.Duration(viper.GetInt("webhooks.initial_backoff_seconds")) * time.Second
+	config.MaxBackoff = time.Duration(viper.GetInt("webhooks.max_backoff_seconds")) * time.Second
+	config.Timeout = time.Duration(viper.GetInt("webhooks.timeout_seconds")) * time.Second
+	config.PollInterval = time.Duration(viper.GetInt("webhooks.poll_interval_seconds")) * time.Second
+
+	return webhook.NewDispatcher(subscriptions, deliveries, logger.NewLogger(), config)
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"context"
+	"net/http"
+
+	projects_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/projects"
+	workspace_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspace_members"
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// workspaceRoles resolves workspace roles from the workspace membership records
+type workspaceRoles struct {
+	memberDal workspace_members_dal.WorkspaceMembersDal
+}
+
+func newWorkspaceRoles() *workspaceRoles {
+	return &workspaceRoles{
+		memberDal: workspace_members_dal.NewWorkspaceMembersDal(),
+	}
+}
+
+// WorkspaceRole implements authz.WorkspaceRoleResolver
+func (wr *workspaceRoles) WorkspaceRole(ctx context.Context, workspaceID, email string) (authz.WorkspaceRole, error) {
+	id, err := bson.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return "", nil
+	}
+
+	member, err := wr.memberDal.Get(ctx, id, email)
+	if err != nil {
+		return "", err
+	}
+	if member == nil {
+		return "", nil
+	}
+
+	return authz.WorkspaceRole(member.Role), nil
+}
+
+// workspaceIDFromURL reads the workspace being operated on from the route
+func workspaceIDFromURL(r *http.Request) (string, error) {
+	id, err := bson.ObjectIDFromHex(chi.URLParam(r, "workspace_id"))
+	if err != nil {
+		return "", err
+	}
+	return id.Hex(), nil
+}
+
+// workspaceIDFromQuery reads the workspace being queried from the
+// workspace_id query parameter
+func workspaceIDFromQuery(r *http.Request) (string, error) {
+	id, err := bson.ObjectIDFromHex(r.URL.Query().Get("workspace_id"))
+	if err != nil {
+		return "", err
+	}
+	return id.Hex(), nil
+}
+
+// projectWorkspaceID returns a function resolving the workspace of the
+// project named in the route
+func projectWorkspaceID(projectDal projects_dal.ProjectsDal) authz.WorkspaceIDFunc {
+	return func(r *http.Request) (string, error) {
+		projectID, err := bson.ObjectIDFromHex(chi.URLParam(r, "project_id"))
+		if err != nil {
+			return "", err
+		}
+
+		project, err := projectDal.GetByID(r.Context(), projectID)
+		if err != nil {
+			return "", err
+		}
+
+		return project.WorkspaceID.Hex(), nil
+	}
+}
@@ -0,0 +1,27 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+)
+
+// AuditService interface
+type AuditService interface {
+	Query(w http.ResponseWriter, r *http.Request)
+	Verify(w http.ResponseWriter, r *http.Request)
+	Export(w http.ResponseWriter, r *http.Request)
+}
+
+// The list of error types presented to the end user
+var (
+	ErrIncompleteDetails   = errors.New("incorrect details provided, please provide correct details")
+	ErrSigningUnavailable  = errors.New("audit export signing is not configured")
+	ErrInternalServerError = errors.New("internal server error")
+)
+
+// List of error codes
+var (
+	FailedToQueryAuditEvents = "Failed-To-Query-Audit-Events"
+	FailedToVerifyAuditChain = "Failed-To-Verify-Audit-Chain"
+	FailedToExportAuditLog   = "Failed-To-Export-Audit-Log"
+)
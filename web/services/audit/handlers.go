@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+
+	audit_events_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/audit_events"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// @Description Audit events page response model
+type EventsResponse struct {
+	Events []*audit.Event `json:"events"`
+	Skip   int64          `json:"skip"`
+	Limit  int64          `json:"limit"`
+}
+
+// @Summary Query audit log
+// @Description Lists the recorded mutations of a workspace, newest first, with optional filters and pagination
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param workspace_id query string true "Workspace ID"
+// @Param project_id query string false "Only events of this project"
+// @Param actor query string false "Only events by this actor (email or agent subject)"
+// @Param action query string false "Only this action, e.g. role.assign"
+// @Param target_type query string false "Only this target type, e.g. resource"
+// @Param target_id query string false "Only events about this target"
+// @Param since query string false "Only events at or after this RFC 3339 time"
+// @Param until query string false "Only events before this RFC 3339 time"
+// @Param skip query integer false "Number of records to skip" default(0)
+// @Param limit query integer false "Number of records to return" default(50)
+// @Success 200 {object} EventsResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /audit [get]
+// @Security BearerAuth
+func (as *auditService) Query(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	workspaceID, err := scopedWorkspace(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	filter := audit_events_dal.Filter{
+		WorkspaceID: workspaceID,
+		Actor:       query.Get("actor"),
+		Action:      query.Get("action"),
+		TargetType:  query.Get("target_type"),
+		TargetID:    query.Get("target_id"),
+	}
+
+	if projectID := query.Get("project_id"); projectID != "" {
+		if filter.ProjectID, err = bson.ObjectIDFromHex(projectID); err != nil {
+			as.logger.Error("invalid project ID", zap.Error(err))
+			render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+			return
+		}
+	}
+
+	if filter.Since, filter.Until, err = timeRange(query); err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	skip, err := strconv.ParseInt(query.Get("skip"), 10, 64)
+	if err != nil || skip < 0 {
+		skip = 0
+	}
+
+	limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 50 // default limit
+	}
+	if limit > 500 {
+		limit = 500 // maximum page size
+	}
+
+	events, err := as.eventDal.Query(r.Context(), filter, skip, limit)
+	if err != nil {
+		as.logger.Error("failed to query audit events", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	render.Respond(w, r, &EventsResponse{
+		Events: events,
+		Skip:   skip,
+		Limit:  limit,
+	})
+}
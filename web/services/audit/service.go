@@ -0,0 +1,24 @@
+package audit
+
+import (
+	audit_events_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/audit_events"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type auditService struct {
+	logger   *zap.Logger
+	eventDal audit_events_dal.AuditEventsDal
+	signer   *audit.Signer
+}
+
+// NewAuditService returns service impl. Exports are refused when signer is
+// nil.
+func NewAuditService(eventDal audit_events_dal.AuditEventsDal, signer *audit.Signer) AuditService {
+	return &auditService{
+		logger:   logger.NewLogger(),
+		eventDal: eventDal,
+		signer:   signer,
+	}
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// scopedWorkspace returns the workspace the caller's access was checked against
+func scopedWorkspace(r *http.Request) (bson.ObjectID, error) {
+	scope, ok := authz.WorkspaceScopeFromContext(r.Context())
+	if !ok {
+		return bson.NilObjectID, ErrIncompleteDetails
+	}
+
+	workspaceID, err := bson.ObjectIDFromHex(scope.WorkspaceID)
+	if err != nil {
+		return bson.NilObjectID, ErrIncompleteDetails
+	}
+	return workspaceID, nil
+}
+
+// timeRange reads the optional RFC 3339 since and until query parameters
+func timeRange(query url.Values) (since, until time.Time, err error) {
+	if value := query.Get("since"); value != "" {
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			return since, until, ErrIncompleteDetails
+		}
+	}
+
+	if value := query.Get("until"); value != "" {
+		if until, err = time.Parse(time.RFC3339, value); err != nil {
+			return since, until, ErrIncompleteDetails
+		}
+	}
+
+	return since, until, nil
+}
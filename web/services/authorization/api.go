@@ -0,0 +1,29 @@
+package authorization
+
+import (
+	"errors"
+	"net/http"
+)
+
+// AuthorizationService interface
+type AuthorizationService interface {
+	Authorize(w http.ResponseWriter, r *http.Request)
+	AuthorizeBatch(w http.ResponseWriter, r *http.Request)
+}
+
+// The list of error types presented to the end user
+var (
+	ErrIncompleteDetails   = errors.New("incorrect details provided, please provide correct details")
+	ErrTooManyChecks       = errors.New("too many checks in a single request")
+	ErrForbidden           = errors.New("not allowed to evaluate checks in the project")
+	ErrInternalServerError = errors.New("internal server error")
+)
+
+// Decisions returned for a check
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+// maxBatchChecks bounds the number of checks evaluated by one batch request
+const maxBatchChecks = 100
@@ -0,0 +1,64 @@
+package authorization
+
+import (
+	"sort"
+
+	"github.com/agent-auth/common-lib/models"
+)
+
+// wildcard matches any resource URN or action in a role's permissions
+const wildcard = "*"
+
+// projectRoles holds the permissions of every role of a project, keyed by
+// role name and resource URN, as materialised in the Redis snapshot
+type projectRoles map[string]map[string]models.Permission
+
+// evaluate decides whether any of roles grants action on resource. Roles are
+// tried in name order so the reported matching role is stable.
+func (pr projectRoles) evaluate(roles []string, resource, action string) *Decision {
+	sorted := append([]string(nil), roles...)
+	sort.Strings(sorted)
+
+	for _, role := range sorted {
+		permissions, ok := pr[role]
+		if !ok {
+			continue
+		}
+
+		for _, urn := range []string{resource, wildcard} {
+			permission, ok := permissions[urn]
+			if !ok {
+				continue
+			}
+			if matched, ok := matchAction(permission.Actions, action); ok {
+				return &Decision{
+					Decision: DecisionAllow,
+					Role:     role,
+					Permission: &MatchedPermission{
+						Resource: urn,
+						Action:   matched,
+					},
+				}
+			}
+		}
+	}
+
+	return &Decision{
+		Decision: DecisionDeny,
+		Reason:   "no role grants the action on the resource",
+	}
+}
+
+// matchAction looks for action among actions and their nested sub-actions and
+// returns the name of the granting action
+func matchAction(actions []models.Action, action string) (string, bool) {
+	for _, a := range actions {
+		if a.Action == action || a.Action == wildcard {
+			return a.Action, true
+		}
+		if matched, ok := matchAction(a.Actions, action); ok {
+			return matched, true
+		}
+	}
+	return "", false
+}
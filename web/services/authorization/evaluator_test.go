@@ -0,0 +1,147 @@
+package authorization
+
+import (
+	"testing"
+
+	"github.com/agent-auth/common-lib/models"
+)
+
+func actions(names ...string) []models.Action {
+	var list []models.Action
+	for _, name := range names {
+		list = append(list, models.Action{Action: name})
+	}
+	return list
+}
+
+func TestProjectRolesEvaluate(t *testing.T) {
+	roles := projectRoles{
+		"reader": {
+			"urn:docs:report": {Actions: actions("read")},
+		},
+		"editor": {
+			"urn:docs:report": {Actions: []models.Action{{
+				Action:  "write",
+				Actions: []models.Action{{Action: "comment", Actions: actions("react")}},
+			}}},
+		},
+		"auditor": {
+			"*": {Actions: actions("read")},
+		},
+		"admin": {
+			"urn:docs:report": {Actions: actions("*")},
+		},
+		"empty": {},
+	}
+
+	tests := []struct {
+		name       string
+		held       []string
+		resource   string
+		action     string
+		decision   string
+		role       string
+		permission *MatchedPermission
+	}{
+		{
+			name: "exact resource and action", held: []string{"reader"},
+			resource: "urn:docs:report", action: "read",
+			decision: DecisionAllow, role: "reader",
+			permission: &MatchedPermission{Resource: "urn:docs:report", Action: "read"},
+		},
+		{
+			name: "action not granted", held: []string{"reader"},
+			resource: "urn:docs:report", action: "write",
+			decision: DecisionDeny,
+		},
+		{
+			name: "other resource", held: []string{"reader"},
+			resource: "urn:docs:other", action: "read",
+			decision: DecisionDeny,
+		},
+		{
+			name: "wildcard resource", held: []string{"auditor"},
+			resource: "urn:docs:anything", action: "read",
+			decision: DecisionAllow, role: "auditor",
+			permission: &MatchedPermission{Resource: "*", Action: "read"},
+		},
+		{
+			name: "wildcard resource keeps its actions", held: []string{"auditor"},
+			resource: "urn:docs:anything", action: "delete",
+			decision: DecisionDeny,
+		},
+		{
+			name: "wildcard action", held: []string{"admin"},
+			resource: "urn:docs:report", action: "delete",
+			decision: DecisionAllow, role: "admin",
+			permission: &MatchedPermission{Resource: "urn:docs:report", Action: "*"},
+		},
+		{
+			name: "nested action", held: []string{"editor"},
+			resource: "urn:docs:report", action: "comment",
+			decision: DecisionAllow, role: "editor",
+			permission: &MatchedPermission{Resource: "urn:docs:report", Action: "comment"},
+		},
+		{
+			name: "deeply nested action", held: []string{"editor"},
+			resource: "urn:docs:report", action: "react",
+			decision: DecisionAllow, role: "editor",
+			permission: &MatchedPermission{Resource: "urn:docs:report", Action: "react"},
+		},
+		{
+			name: "roles tried in name order", held: []string{"reader", "auditor", "admin"},
+			resource: "urn:docs:report", action: "read",
+			decision: DecisionAllow, role: "admin",
+			permission: &MatchedPermission{Resource: "urn:docs:report", Action: "*"},
+		},
+		{
+			name: "exact resource tried before wildcard", held: []string{"auditor", "reader"},
+			resource: "urn:docs:report", action: "read",
+			decision: DecisionAllow, role: "auditor",
+			permission: &MatchedPermission{Resource: "*", Action: "read"},
+		},
+		{
+			name: "unknown role", held: []string{"ghost"},
+			resource: "urn:docs:report", action: "read",
+			decision: DecisionDeny,
+		},
+		{
+			name: "role without permissions", held: []string{"empty"},
+			resource: "urn:docs:report", action: "read",
+			decision: DecisionDeny,
+		},
+		{
+			name: "no roles", held: nil,
+			resource: "urn:docs:report", action: "read",
+			decision: DecisionDeny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held := append([]string(nil), tt.held...)
+			got := roles.evaluate(tt.held, tt.resource, tt.action)
+
+			if got.Decision != tt.decision {
+				t.Fatalf("decision = %s, want %s", got.Decision, tt.decision)
+			}
+			if got.Role != tt.role {
+				t.Errorf("role = %q, want %q", got.Role, tt.role)
+			}
+			switch {
+			case tt.permission == nil && got.Permission != nil:
+				t.Errorf("permission = %+v, want none", got.Permission)
+			case tt.permission != nil && (got.Permission == nil || *got.Permission != *tt.permission):
+				t.Errorf("permission = %+v, want %+v", got.Permission, tt.permission)
+			}
+			if tt.decision == DecisionDeny && got.Reason == "" {
+				t.Error("deny without a reason")
+			}
+			for i := range held {
+				if tt.held[i] != held[i] {
+					t.Fatalf("held roles reordered to %v", tt.held)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package authorization
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// @Description Authorization check: may subject perform action on resource in project
+type AuthorizeRequest struct {
+	ProjectID string   `json:"project_id"`
+	Subject   string   `json:"subject"`
+	Roles     []string `json:"roles,omitempty"` // defaults to the subject's role bindings
+	Resource  string   `json:"resource"`
+	Action    string   `json:"action"`
+}
+
+func (a *AuthorizeRequest) Bind(r *http.Request) error {
+	return a.validate()
+}
+
+func (a *AuthorizeRequest) validate() error {
+	if a.ProjectID == "" || a.Subject == "" || a.Resource == "" || a.Action == "" {
+		return ErrIncompleteDetails
+	}
+	if _, err := bson.ObjectIDFromHex(a.ProjectID); err != nil {
+		return ErrIncompleteDetails
+	}
+	return nil
+}
+
+// @Description Batch of authorization checks
+type AuthorizeBatchRequest struct {
+	Checks []*AuthorizeRequest `json:"checks"`
+}
+
+func (b *AuthorizeBatchRequest) Bind(r *http.Request) error {
+	if len(b.Checks) == 0 {
+		return ErrIncompleteDetails
+	}
+	if len(b.Checks) > maxBatchChecks {
+		return ErrTooManyChecks
+	}
+	for _, check := range b.Checks {
+		if check == nil {
+			return ErrIncompleteDetails
+		}
+		if err := check.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// @Description Permission that granted an allowed check
+type MatchedPermission struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// @Description Authorization decision
+type Decision struct {
+	Decision   string             `json:"decision"`
+	Role       string             `json:"role,omitempty"`
+	Permission *MatchedPermission `json:"permission,omitempty"`
+	Reason     string             `json:"reason,omitempty"`
+}
+
+// @Description Batch authorization decisions, in the order of the checks
+type AuthorizeBatchResponse struct {
+	Decisions []*Decision `json:"decisions"`
+}
+
+// @Summary Authorize
+// @Description Decides whether a subject may perform an action on a resource URN. Roles default to the subject's project role bindings when omitted. The caller must be a workspace owner, admin or member of the project's workspace, or a system administrator
+// @Tags authorization
+// @Accept json
+// @Produce json
+// @Param check body AuthorizeRequest true "Authorization check"
+// @Success 200 {object} Decision
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /v1/authorize [post]
+// @Security BearerAuth
+func (as *authorizationService) Authorize(w http.ResponseWriter, r *http.Request) {
+	var req AuthorizeRequest
+	if err := render.Bind(r, &req); err != nil {
+		as.logger.Error("failed to bind authorize request", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	if !as.authorizeCaller(w, r, []*AuthorizeRequest{&req}) {
+		return
+	}
+
+	decision, err := as.decide(r.Context(), &req, make(map[bson.ObjectID]projectRoles))
+	if err != nil {
+		as.logger.Error("failed to evaluate authorization check", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	logDecision(r, &req, decision)
+
+	render.Respond(w, r, decision)
+}
+
+// @Summary Authorize batch
+// @Description Evaluates many authorization checks at once, returning decisions in the same order. The whole batch is refused unless the caller may evaluate checks in every project it names
+// @Tags authorization
+// @Accept json
+// @Produce json
+// @Param checks body AuthorizeBatchRequest true "Authorization checks"
+// @Success 200 {object} AuthorizeBatchResponse
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /v1/authorize/batch [post]
+// @Security BearerAuth
+func (as *authorizationService) AuthorizeBatch(w http.ResponseWriter, r *http.Request) {
+	var req AuthorizeBatchRequest
+	if err := render.Bind(r, &req); err != nil {
+		as.logger.Error("failed to bind authorize batch request", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	if !as.authorizeCaller(w, r, req.Checks) {
+		return
+	}
+
+	// Checks against the same project share one load of its roles
+	loaded := make(map[bson.ObjectID]projectRoles)
+
+	decisions := make([]*Decision, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		decision, err := as.decide(r.Context(), check, loaded)
+		if err != nil {
+			as.logger.Error("failed to evaluate authorization check", zap.Error(err))
+			render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+			return
+		}
+		logDecision(r, check, decision)
+		decisions = append(decisions, decision)
+	}
+
+	render.Respond(w, r, &AuthorizeBatchResponse{
+		Decisions: decisions,
+	})
+}
+
+// authorizeCaller ensures the caller may evaluate checks in every project the
+// checks name. The route permission alone is not enough, as it would let a
+// workspace admin read the policy of any other workspace. It renders the
+// error response and returns false otherwise.
+func (as *authorizationService) authorizeCaller(w http.ResponseWriter, r *http.Request, checks []*AuthorizeRequest) bool {
+	principal, ok := authz.PrincipalFromContext(r.Context())
+	if !ok {
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrForbidden))
+		return false
+	}
+	if principal.HasRole(authz.SystemAdmin) {
+		return true
+	}
+
+	allowed := make(map[string]bool)
+	for _, check := range checks {
+		if allowed[check.ProjectID] {
+			continue
+		}
+
+		projectID, _ := bson.ObjectIDFromHex(check.ProjectID)
+		workspaceID, ok, err := as.mayEvaluate(r.Context(), projectID, principal.Email)
+		if err != nil {
+			as.logger.Error("failed to resolve project workspace", zap.String("projectID", check.ProjectID), zap.Error(err))
+			render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+			return false
+		}
+		if !ok {
+			authz.LogDecision(r, &authz.Decision{
+				Check:       authz.CheckWorkspace,
+				WorkspaceID: workspaceID,
+				ProjectID:   check.ProjectID,
+				Decision:    authz.DecisionDeny,
+				Reason:      "caller may not evaluate checks in the project",
+			})
+			render.Render(w, r, renderers.ErrorForbidden(ErrForbidden))
+			return false
+		}
+		allowed[check.ProjectID] = true
+	}
+
+	return true
+}
+
+// decide evaluates a single check, loading project roles once per project
+func (as *authorizationService) decide(ctx context.Context, check *AuthorizeRequest, loaded map[bson.ObjectID]projectRoles) (*Decision, error) {
+	projectID, _ := bson.ObjectIDFromHex(check.ProjectID)
+
+	held := check.Roles
+	if len(held) == 0 {
+		var err error
+		held, err = as.subjectRoles(ctx, projectID, check.Subject)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(held) == 0 {
+		return &Decision{
+			Decision: DecisionDeny,
+			Reason:   "subject holds no roles in the project",
+		}, nil
+	}
+
+	roles, ok := loaded[projectID]
+	if !ok {
+		var err error
+		roles, err = as.projectRoles(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		loaded[projectID] = roles
+	}
+
+	return roles.evaluate(held, check.Resource, check.Action), nil
+}
+
+// logDecision sends a check and its outcome to the decision log
+func logDecision(r *http.Request, check *AuthorizeRequest, decision *Decision) {
+	logged := &authz.Decision{
+		Subject:     check.Subject,
+		Check:       authz.CheckResource,
+		Resource:    check.Resource,
+		Action:      check.Action,
+		ProjectID:   check.ProjectID,
+		Decision:    decision.Decision,
+		MatchedRole: decision.Role,
+		Reason:      decision.Reason,
+	}
+	if decision.Permission != nil {
+		logged.MatchedPermission = decision.Permission.Action + " on " + decision.Permission.Resource
+	}
+	authz.LogDecision(r, logged)
+}
@@ -0,0 +1,119 @@
+package authorization
+
+import (
+	"context"
+	"errors"
+
+	projects_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/projects"
+	role_bindings_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/role_bindings"
+	roles_permissions_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/roles_permissions"
+	"github.com/agent-auth/agent-auth-api/db/redis_dal"
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/common-lib/models"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+// projectRolesReader reads the Redis snapshot of a project's roles and bindings
+type projectRolesReader interface {
+	GetProjectRoles(ctx context.Context, projectID string) (map[string]map[string]models.Permission, error)
+	GetProjectBindings(ctx context.Context, projectID string) (map[string][]string, error)
+}
+
+type authorizationService struct {
+	logger         *zap.Logger
+	snapshot       projectRolesReader
+	rolesDal       roles_permissions_dal.RolesDal
+	bindingsDal    role_bindings_dal.RoleBindingsDal
+	projectDal     projects_dal.ProjectsDal
+	workspaceRoles authz.WorkspaceRoleResolver
+}
+
+// NewAuthorizationService returns service impl. Callers may only evaluate
+// checks in projects of workspaces where workspaceRoles grants them
+// authz.AuthorizeCheck, unless they are system administrators.
+func NewAuthorizationService(workspaceRoles authz.WorkspaceRoleResolver) AuthorizationService {
+	return &authorizationService{
+		logger:         logger.NewLogger(),
+		snapshot:       redis_dal.NewRedisRolesDal(),
+		rolesDal:       roles_permissions_dal.NewRolesDal(),
+		bindingsDal:    role_bindings_dal.NewRoleBindingsDal(),
+		projectDal:     projects_dal.NewProjectsDal(),
+		workspaceRoles: workspaceRoles,
+	}
+}
+
+// mayEvaluate reports whether the user with email may evaluate checks in a
+// project, through the role they hold in the project's workspace. Unknown
+// projects are refused like foreign ones, so their existence is not revealed.
+func (as *authorizationService) mayEvaluate(ctx context.Context, projectID bson.ObjectID, email string) (string, bool, error) {
+	if email == "" {
+		return "", false, nil
+	}
+
+	project, err := as.projectDal.GetByID(ctx, projectID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	workspaceID := project.WorkspaceID.Hex()
+	role, err := as.workspaceRoles.WorkspaceRole(ctx, workspaceID, email)
+	if err != nil {
+		return workspaceID, false, err
+	}
+
+	return workspaceID, role.HasPermission(authz.AuthorizeCheck), nil
+}
+
+// projectRoles loads the roles of a project from the Redis snapshot, falling
+// back to Mongo when the project has not been synced yet
+func (as *authorizationService) projectRoles(ctx context.Context, projectID bson.ObjectID) (projectRoles, error) {
+	roles, err := as.snapshot.GetProjectRoles(ctx, projectID.Hex())
+	if err != nil {
+		as.logger.Error("failed to read project roles snapshot", zap.String("projectID", projectID.Hex()), zap.Error(err))
+	}
+	if roles != nil {
+		return roles, nil
+	}
+
+	stored, err := as.rolesDal.GetByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles = make(projectRoles, len(stored))
+	for _, role := range stored {
+		roles[role.Role] = role.Permissions
+	}
+
+	return roles, nil
+}
+
+// subjectRoles loads the roles bound to a subject in a project from the Redis
+// snapshot, falling back to Mongo when the project has not been synced yet
+func (as *authorizationService) subjectRoles(ctx context.Context, projectID bson.ObjectID, subject string) ([]string, error) {
+	bindings, err := as.snapshot.GetProjectBindings(ctx, projectID.Hex())
+	if err != nil {
+		as.logger.Error("failed to read project bindings snapshot", zap.String("projectID", projectID.Hex()), zap.Error(err))
+	}
+	if bindings != nil {
+		return bindings[subject], nil
+	}
+
+	stored, err := as.bindingsDal.ListBySubject(ctx, projectID, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]string, len(stored))
+	for i, binding := range stored {
+		roles[i] = binding.Role
+	}
+
+	return roles, nil
+}
@@ -0,0 +1,24 @@
+package changefeed
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ChangefeedService interface
+type ChangefeedService interface {
+	Stream(w http.ResponseWriter, r *http.Request)
+}
+
+// The list of error types presented to the end user
+var (
+	ErrIncompleteDetails   = errors.New("incorrect details provided, please provide correct details")
+	ErrInvalidEventID      = errors.New("last event id is invalid")
+	ErrStreamUnavailable   = errors.New("event stream is temporarily unavailable, please retry")
+	ErrInternalServerError = errors.New("internal server error")
+)
+
+// List of error codes
+var (
+	FailedToStreamProjectEvents = "Failed-To-Stream-Project-Events"
+)
@@ -0,0 +1,120 @@
+package changefeed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/changefeed"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+const (
+	// reconnectDelay is the retry interval suggested to clients, in milliseconds
+	reconnectDelay = 3000
+
+	// eventReset tells the client that changes were missed and it must refetch
+	// the project's roles, permissions and resources
+	eventReset = "reset"
+)
+
+// @Summary Stream project changes
+// @Description Streams role, permission and resource changes of a project as Server-Sent Events. Each event carries its ID, its type (such as role.assign) as the event name, and the change as JSON data. Reconnecting with the Last-Event-ID header, or the last_event_id query parameter, replays the changes missed meanwhile; a "reset" event means they are no longer available and the client must refetch the project's policy.
+// @Tags projects
+// @Produce text/event-stream
+// @Param project_id path string true "Project ID"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Param last_event_id query string false "ID of the last event received, for clients that cannot set headers"
+// @Success 200 {object} changefeed.Event
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 503 {object} errorinterface.ErrorResponse{}
+// @Router /projects/{project_id}/events [get]
+// @Security BearerAuth
+func (cs *changefeedService) Stream(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "project_id")
+	if projectID == "" {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID != "" && !changefeed.ValidID(lastEventID) {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrInvalidEventID))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		cs.logger.Error("response writer does not support streaming")
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	watch, err := cs.hub.Watch(r.Context(), projectID, lastEventID)
+	if errors.Is(err, changefeed.ErrUnavailable) {
+		render.Render(w, r, renderers.ErrorServiceUnavailable(ErrStreamUnavailable))
+		return
+	}
+	if err != nil {
+		cs.logger.Error("failed to watch project changes", zap.String("projectID", projectID), zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay)
+	if watch.Reset {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventReset)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(cs.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-watch.Events:
+			if !ok {
+				// The client reconnects and resumes from the last ID it received
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes event in the Server-Sent Events format
+func writeEvent(w io.Writer, event *changefeed.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
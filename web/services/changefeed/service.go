@@ -0,0 +1,25 @@
+package changefeed
+
+import (
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/changefeed"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type changefeedService struct {
+	logger    *zap.Logger
+	hub       *changefeed.Hub
+	heartbeat time.Duration
+}
+
+// NewChangefeedService returns service impl. A comment is written to idle
+// streams every heartbeat so proxies keep them open.
+func NewChangefeedService(hub *changefeed.Hub, heartbeat time.Duration) ChangefeedService {
+	return &changefeedService{
+		logger:    logger.NewLogger(),
+		hub:       hub,
+		heartbeat: heartbeat,
+	}
+}
@@ -0,0 +1,34 @@
+package invitations
+
+import (
+	"errors"
+	"net/http"
+)
+
+// InvitationService interface
+type InvitationService interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	ListByWorkspace(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+	ListMine(w http.ResponseWriter, r *http.Request)
+	Accept(w http.ResponseWriter, r *http.Request)
+	Decline(w http.ResponseWriter, r *http.Request)
+}
+
+// The list of error types presented to the end user
+var (
+	ErrIncompleteDetails = errors.New("incorrect details provided, please provide correct details")
+	ErrInvalidRole       = errors.New("invalid workspace role")
+	ErrAlreadyMember     = errors.New("user is already a member of the workspace")
+	ErrNotFound          = errors.New("invitation not found or expired")
+	ErrUnauthorized      = errors.New("unauthorized to perform this action")
+)
+
+// List of error codes
+var (
+	FailedToCreateInvitation  = "Failed-To-Create-Invitation"
+	FailedToListInvitations   = "Failed-To-List-Invitations"
+	FailedToRevokeInvitation  = "Failed-To-Revoke-Invitation"
+	FailedToAcceptInvitation  = "Failed-To-Accept-Invitation"
+	FailedToDeclineInvitation = "Failed-To-Decline-Invitation"
+)
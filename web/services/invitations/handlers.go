@@ -0,0 +1,422 @@
+package invitations
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	invitations_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/invitations"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/agent-auth-api/pkg/notify"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// @Description Invitation request model. Role defaults to member.
+type InvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func (i *InvitationRequest) Bind(r *http.Request) error {
+	i.Email = normalizeEmail(i.Email)
+	if i.Email == "" || !strings.Contains(i.Email, "@") {
+		return ErrIncompleteDetails
+	}
+	if i.Role == "" {
+		i.Role = string(authz.WorkspaceRoleMember)
+	}
+	// Ownership changes hands only through an explicit transfer
+	if role := authz.WorkspaceRole(i.Role); !role.Valid() || role == authz.WorkspaceRoleOwner {
+		return ErrInvalidRole
+	}
+	return nil
+}
+
+// @Description Invitation token request model
+type InvitationTokenRequest struct {
+	Token string `json:"token"`
+}
+
+func (i *InvitationTokenRequest) Bind(r *http.Request) error {
+	if i.Token == "" {
+		return ErrIncompleteDetails
+	}
+	return nil
+}
+
+// @Description Invitation response model. Token is only returned on creation.
+type InvitationResponse struct {
+	*invitations_dal.Invitation
+	Token string `json:"token,omitempty"`
+}
+
+// @Description Invitations list response model
+type InvitationsResponse struct {
+	Invitations []*invitations_dal.Invitation `json:"invitations"`
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// newToken returns a random single-use token and the hash stored in its place
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// @Summary Invite to workspace
+// @Description Invites an email address to join a workspace. The returned token is single-use and expires.
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param invitation body InvitationRequest true "Invitee and role"
+// @Success 200 {object} InvitationResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/invitations [post]
+// @Security BearerAuth
+func (is *invitationService) Create(w http.ResponseWriter, r *http.Request) {
+	email, _ := authz.GetEmailFromClaims(r)
+
+	workspaceID, err := bson.ObjectIDFromHex(chi.URLParam(r, "workspace_id"))
+	if err != nil {
+		is.logger.Error("invalid workspace ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	if !authz.ManagesWorkspace(r) {
+		is.logger.Error("unauthorized invitation attempt", zap.String("userID", email))
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+
+	var req InvitationRequest
+	if err := render.Bind(r, &req); err != nil {
+		is.logger.Error("failed to bind invitation request", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	member, err := is.memberDal.Get(r.Context(), workspaceID, req.Email)
+	if err != nil {
+		is.logger.Error("failed to check workspace membership", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+	if member != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrAlreadyMember))
+		return
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		is.logger.Error("failed to generate invitation token", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	invitation, err := is.invitationDal.Create(r.Context(), &invitations_dal.Invitation{
+		WorkspaceID:  workspaceID,
+		Email:        req.Email,
+		Role:         req.Role,
+		TokenHash:    tokenHash,
+		InvitedBy:    email,
+		ExpiresAtUTC: time.Now().UTC().Add(is.ttl),
+	})
+	if err != nil {
+		is.logger.Error("failed to create invitation", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	is.sendInvitation(r, invitation, token)
+
+	render.Respond(w, r, &InvitationResponse{
+		Invitation: invitation,
+		Token:      token,
+	})
+}
+
+// sendInvitation emails the invitee. Delivery problems are logged rather than failing
+// the request, since the token is also returned to the inviter.
+func (is *invitationService) sendInvitation(r *http.Request, invitation *invitations_dal.Invitation, token string) {
+	workspace, err := is.workspaceDal.GetByID(r.Context(), invitation.WorkspaceID)
+	if err != nil {
+		is.logger.Error("failed to get workspace for invitation email", zap.Error(err))
+		return
+	}
+
+	msg, err := notify.Render(notify.TemplateInvitation, notify.InvitationData{
+		Workspace: workspace.Name,
+		InvitedBy: invitation.InvitedBy,
+		Role:      invitation.Role,
+		AcceptURL: is.acceptURL,
+		Token:     token,
+		ExpiresAt: invitation.ExpiresAtUTC,
+	}, invitation.Email)
+	if err != nil {
+		is.logger.Error("failed to render invitation email", zap.Error(err))
+		return
+	}
+
+	if err := is.notifier.Send(r.Context(), msg); err != nil {
+		is.logger.Error("failed to queue invitation email", zap.Error(err))
+	}
+}
+
+// @Summary List workspace invitations
+// @Description Lists the pending invitations of a workspace
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} InvitationsResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/invitations [get]
+// @Security BearerAuth
+func (is *invitationService) ListByWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := bson.ObjectIDFromHex(chi.URLParam(r, "workspace_id"))
+	if err != nil {
+		is.logger.Error("invalid workspace ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	pending, err := is.invitationDal.ListPendingByWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		is.logger.Error("failed to list invitations", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	render.Respond(w, r, &InvitationsResponse{
+		Invitations: pending,
+	})
+}
+
+// @Summary Revoke invitation
+// @Description Revokes a pending workspace invitation
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param invitation_id path string true "Invitation ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/invitations/{invitation_id} [delete]
+// @Security BearerAuth
+func (is *invitationService) Revoke(w http.ResponseWriter, r *http.Request) {
+	email, _ := authz.GetEmailFromClaims(r)
+
+	workspaceID, err := bson.ObjectIDFromHex(chi.URLParam(r, "workspace_id"))
+	if err != nil {
+		is.logger.Error("invalid workspace ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	invitationID, err := bson.ObjectIDFromHex(chi.URLParam(r, "invitation_id"))
+	if err != nil {
+		is.logger.Error("invalid invitation ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	if !authz.ManagesWorkspace(r) {
+		is.logger.Error("unauthorized invitation revocation attempt", zap.String("userID", email))
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+
+	if err := is.invitationDal.Revoke(r.Context(), workspaceID, invitationID); err != nil {
+		is.logger.Error("failed to revoke invitation", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+
+	render.Status(r, http.StatusNoContent)
+}
+
+// @Summary List my invitations
+// @Description Lists the pending invitations sent to the caller's email
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Success 200 {object} InvitationsResponse
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /invitations [get]
+// @Security BearerAuth
+func (is *invitationService) ListMine(w http.ResponseWriter, r *http.Request) {
+	email, err := authz.GetEmailFromClaims(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+
+	pending, err := is.invitationDal.ListPendingByEmail(r.Context(), normalizeEmail(email))
+	if err != nil {
+		is.logger.Error("failed to list invitations", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	render.Respond(w, r, &InvitationsResponse{
+		Invitations: pending,
+	})
+}
+
+// @Summary Accept invitation
+// @Description Accepts an invitation sent to the caller's email and joins the workspace
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param invitation body InvitationTokenRequest true "Invitation token"
+// @Success 200 {object} InvitationResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /invitations/accept [post]
+// @Security BearerAuth
+func (is *invitationService) Accept(w http.ResponseWriter, r *http.Request) {
+	invitation, email, ok := is.resolve(w, r, invitations_dal.StatusAccepted)
+	if !ok {
+		return
+	}
+
+	// The membership record grants access, so it is written first; existing
+	// members keep their role rather than being moved to the invited one
+	member, err := is.memberDal.Get(r.Context(), invitation.WorkspaceID, email)
+	created := false
+	if err == nil && member == nil {
+		member, err = is.memberDal.Upsert(r.Context(), invitation.WorkspaceID, email, invitation.Role)
+		created = err == nil
+	}
+	if err != nil {
+		is.logger.Error("failed to add member", zap.Error(err))
+		is.reopen(r.Context(), invitation)
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	if err := is.workspaceDal.AddMember(r.Context(), invitation.WorkspaceID.Hex(), email); err != nil {
+		is.logger.Error("failed to add member", zap.Error(err))
+		if created {
+			is.removeMember(r.Context(), invitation.WorkspaceID, email)
+		}
+		is.reopen(r.Context(), invitation)
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	if created {
+		is.auditor.Record(r, audit.ActionWorkspaceMemberAdd, audit.Target{
+			Type:        audit.TargetWorkspace,
+			ID:          invitation.WorkspaceID.Hex(),
+			WorkspaceID: invitation.WorkspaceID,
+		}, nil, member)
+	}
+
+	render.Respond(w, r, &InvitationResponse{
+		Invitation: invitation,
+	})
+}
+
+// @Summary Decline invitation
+// @Description Declines an invitation sent to the caller's email
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param invitation body InvitationTokenRequest true "Invitation token"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Router /invitations/decline [post]
+// @Security BearerAuth
+func (is *invitationService) Decline(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := is.resolve(w, r, invitations_dal.StatusDeclined); !ok {
+		return
+	}
+
+	render.Status(r, http.StatusNoContent)
+}
+
+// reopen puts an accepted invitation back to pending after joining the
+// workspace failed, so the token is not burned and the user can retry
+func (is *invitationService) reopen(ctx context.Context, invitation *invitations_dal.Invitation) {
+	if err := is.invitationDal.Reopen(ctx, invitation.ID, invitations_dal.StatusAccepted); err != nil {
+		is.logger.Error("failed to reopen invitation",
+			zap.String("invitationID", invitation.ID.Hex()),
+			zap.Error(err))
+	}
+}
+
+// removeMember undoes the membership record written for an invitation when
+// adding the email to the workspace failed, so it grants no access
+func (is *invitationService) removeMember(ctx context.Context, workspaceID bson.ObjectID, email string) {
+	if err := is.memberDal.Remove(ctx, workspaceID, email); err != nil {
+		is.logger.Error("failed to remove member",
+			zap.String("workspaceID", workspaceID.Hex()),
+			zap.Error(err))
+	}
+}
+
+// resolve consumes the invitation token in the request body on behalf of the
+// caller, who must be the invited email. It renders the error response and
+// returns false on failure.
+func (is *invitationService) resolve(w http.ResponseWriter, r *http.Request, status string) (*invitations_dal.Invitation, string, bool) {
+	email, err := authz.GetEmailFromClaims(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return nil, "", false
+	}
+
+	var req InvitationTokenRequest
+	if err := render.Bind(r, &req); err != nil {
+		is.logger.Error("failed to bind invitation token", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return nil, "", false
+	}
+
+	invitation, err := is.invitationDal.Resolve(r.Context(), hashToken(req.Token), normalizeEmail(email), status)
+	if err != nil {
+		is.logger.Error("failed to resolve invitation", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return nil, "", false
+	}
+	if invitation == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return nil, "", false
+	}
+
+	return invitation, email, true
+}
@@ -0,0 +1,46 @@
+package invitations
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	invitations_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/invitations"
+	workspace_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspace_members"
+	workspaces_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspaces"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/notify"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type invitationService struct {
+	logger        *zap.Logger
+	invitationDal invitations_dal.InvitationsDal
+	workspaceDal  workspaces_dal.WorkspaceDal
+	memberDal     workspace_members_dal.WorkspaceMembersDal
+	notifier      notify.Notifier
+	auditor       *audit.Recorder
+	acceptURL     string
+	ttl           time.Duration
+}
+
+// NewInvitationService returns service impl. Invitees are emailed a link to
+// acceptURL along with their token.
+func NewInvitationService(notifier notify.Notifier, auditor *audit.Recorder, acceptURL string) InvitationService {
+	ttlHours, err := strconv.Atoi(os.Getenv("INVITATION_TTL_HOURS"))
+	if err != nil || ttlHours <= 0 {
+		ttlHours = 72 // default expiry
+	}
+
+	return &invitationService{
+		logger:        logger.NewLogger(),
+		invitationDal: invitations_dal.NewInvitationsDal(),
+		workspaceDal:  workspaces_dal.NewWorkspaceDal(),
+		memberDal:     workspace_members_dal.NewWorkspaceMembersDal(),
+		notifier:      notifier,
+		auditor:       auditor,
+		acceptURL:     acceptURL,
+		ttl:           time.Duration(ttlHours) * time.Hour,
+	}
+}
@@ -127,7 +127,7 @@ func (rs *resourceService) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := rs.resources.CreateAuthzResource(realmName, clientID, resource.ResourceRepresentation)
+	created, err := rs.resources.CreateAuthzResource(r.Context(), realmName, clientID, resource.ResourceRepresentation)
 	if err != nil {
 		rs.logger.Error("failed to create resource",
 			zap.Error(err),
@@ -166,7 +166,7 @@ func (rs *resourceService) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resource, err := rs.resources.GetAuthzResource(realmName, clientID, resourceID)
+	resource, err := rs.resources.GetAuthzResource(r.Context(), realmName, clientID, resourceID)
 	if err != nil {
 		rs.logger.Error("failed to get resource",
 			zap.Error(err),
@@ -208,7 +208,7 @@ func (rs *resourceService) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := rs.resources.UpdateAuthzResource(realmName, clientID, resourceID, resource.ResourceRepresentation)
+	err := rs.resources.UpdateAuthzResource(r.Context(), realmName, clientID, resourceID, resource.ResourceRepresentation)
 	if err != nil {
 		rs.logger.Error("failed to update resource",
 			zap.Error(err),
@@ -240,7 +240,7 @@ func (rs *resourceService) Delete(w http.ResponseWriter, r *http.Request) {
 	realmName := chi.URLParam(r, "workspace_id")
 	clientID := chi.URLParam(r, "project_id")
 
-	err := rs.resources.DeleteAuthzResource(realmName, clientID, resourceID)
+	err := rs.resources.DeleteAuthzResource(r.Context(), realmName, clientID, resourceID)
 	if err != nil {
 		rs.logger.Error("failed to delete resource",
 			zap.Error(err),
@@ -292,7 +292,7 @@ func (rs *resourceService) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resources, err := rs.resources.GetAuthzResources(realmName, clientID, query)
+	resources, err := rs.resources.GetAuthzResources(r.Context(), realmName, clientID, query)
 	if err != nil {
 		rs.logger.Error("failed to list resources",
 			zap.Error(err),
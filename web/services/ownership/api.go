@@ -0,0 +1,39 @@
+package ownership
+
+import (
+	"errors"
+	"net/http"
+)
+
+// OwnershipService interface
+type OwnershipService interface {
+	TransferWorkspace(w http.ResponseWriter, r *http.Request)
+	TransferProject(w http.ResponseWriter, r *http.Request)
+	ListWorkspaceTransfers(w http.ResponseWriter, r *http.Request)
+	ListProjectTransfers(w http.ResponseWriter, r *http.Request)
+	ListMine(w http.ResponseWriter, r *http.Request)
+	Accept(w http.ResponseWriter, r *http.Request)
+	Decline(w http.ResponseWriter, r *http.Request)
+	Cancel(w http.ResponseWriter, r *http.Request)
+}
+
+// The list of error types presented to the end user
+var (
+	ErrIncompleteDetails   = errors.New("incorrect details provided, please provide correct details")
+	ErrNotFound            = errors.New("ownership transfer not found or expired")
+	ErrResourceNotFound    = errors.New("resource not found")
+	ErrUnauthorized        = errors.New("unauthorized to perform this action")
+	ErrNotMember           = errors.New("the new owner must be a member")
+	ErrAlreadyOwner        = errors.New("the new owner already owns the resource")
+	ErrOwnerChanged        = errors.New("the owner changed since the transfer was requested")
+	ErrInternalServerError = errors.New("internal server error")
+)
+
+// List of error codes
+var (
+	FailedToTransferOwnership = "Failed-To-Transfer-Ownership"
+	FailedToListTransfers     = "Failed-To-List-Transfers"
+	FailedToAcceptTransfer    = "Failed-To-Accept-Transfer"
+	FailedToDeclineTransfer   = "Failed-To-Decline-Transfer"
+	FailedToCancelTransfer    = "Failed-To-Cancel-Transfer"
+)
@@ -0,0 +1,587 @@
+package ownership
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ownership_transfers_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/ownership_transfers"
+	project_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/project_members"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/agent-auth-api/pkg/notify"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/agent-auth/common-lib/models"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// @Description Ownership transfer request model. Without require_acceptance the transfer completes immediately.
+type TransferRequest struct {
+	NewOwner          string `json:"new_owner"`
+	RequireAcceptance bool   `json:"require_acceptance"`
+}
+
+func (t *TransferRequest) Bind(r *http.Request) error {
+	t.NewOwner = strings.TrimSpace(t.NewOwner)
+	if t.NewOwner == "" {
+		return ErrIncompleteDetails
+	}
+	return nil
+}
+
+// @Description Ownership transfer response model
+type TransferResponse struct {
+	*ownership_transfers_dal.OwnershipTransfer
+}
+
+// @Description Ownership transfers list response model
+type TransfersResponse struct {
+	Transfers []*ownership_transfers_dal.OwnershipTransfer `json:"transfers"`
+}
+
+// resource is the part of a workspace or project a transfer works on
+type resource struct {
+	name        string
+	owner       string
+	workspaceID bson.ObjectID
+}
+
+// getResource loads the current owner of the resource a transfer targets
+func (ows *ownershipService) getResource(ctx context.Context, resourceType string, id bson.ObjectID) (*resource, error) {
+	switch resourceType {
+	case ownership_transfers_dal.ResourceWorkspace:
+		workspace, err := ows.workspaceDal.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &resource{name: workspace.Name, owner: workspace.OwnerID, workspaceID: workspace.ID}, nil
+
+	case ownership_transfers_dal.ResourceProject:
+		project, err := ows.projectDal.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &resource{name: project.Name, owner: project.OwnerID, workspaceID: project.WorkspaceID}, nil
+	}
+
+	return nil, fmt.Errorf("unknown resource type: %s", resourceType)
+}
+
+// isMember reports whether email belongs to the resource a transfer targets
+func (ows *ownershipService) isMember(ctx context.Context, resourceType string, id bson.ObjectID, email string) (bool, error) {
+	if resourceType == ownership_transfers_dal.ResourceWorkspace {
+		member, err := ows.workspaceMemberDal.Get(ctx, id, email)
+		return member != nil, err
+	}
+	member, err := ows.projectMemberDal.Get(ctx, id, email)
+	return member != nil, err
+}
+
+// complete hands the resource to the new owner, records the change in its
+// audit log and moves the previous owner down to an administrative role
+func (ows *ownershipService) complete(r *http.Request, transfer *ownership_transfers_dal.OwnershipTransfer, current *resource, actor string) error {
+	entry := models.AuditLog{
+		Action:    "ownership_transferred",
+		Details:   fmt.Sprintf("ownership transferred from %s to %s (transfer %s)", transfer.FromOwner, transfer.ToOwner, transfer.ID.Hex()),
+		Timestamp: time.Now().UTC(),
+		UserID:    actor,
+	}
+
+	var (
+		action string
+		target = audit.Target{ID: transfer.ResourceID.Hex(), WorkspaceID: current.workspaceID}
+	)
+
+	switch transfer.ResourceType {
+	case ownership_transfers_dal.ResourceWorkspace:
+		if err := ows.completeWorkspace(r.Context(), transfer, entry); err != nil {
+			return err
+		}
+		action, target.Type = audit.ActionWorkspaceTransfer, audit.TargetWorkspace
+
+	case ownership_transfers_dal.ResourceProject:
+		if err := ows.completeProject(r.Context(), transfer, entry, actor); err != nil {
+			return err
+		}
+		action, target.Type, target.ProjectID = audit.ActionProjectTransfer, audit.TargetProject, transfer.ResourceID
+
+	default:
+		return fmt.Errorf("unknown resource type: %s", transfer.ResourceType)
+	}
+
+	ows.auditor.Record(r, action, target,
+		map[string]string{"owner_id": transfer.FromOwner},
+		map[string]string{"owner_id": transfer.ToOwner, "transfer_id": transfer.ID.Hex()})
+	return nil
+}
+
+// fail records that a transfer resolved as completed could not be applied
+func (ows *ownershipService) fail(ctx context.Context, transfer *ownership_transfers_dal.OwnershipTransfer) {
+	if _, err := ows.transferDal.Fail(ctx, transfer.ID); err != nil {
+		ows.logger.Error("failed to mark ownership transfer as failed", zap.Error(err), zap.String("transferID", transfer.ID.Hex()))
+	}
+}
+
+// completeWorkspace makes the new owner the workspace owner and the previous
+// owner an admin
+func (ows *ownershipService) completeWorkspace(ctx context.Context, transfer *ownership_transfers_dal.OwnershipTransfer, entry models.AuditLog) error {
+	if err := ows.workspaceDal.SetOwner(ctx, transfer.ResourceID, transfer.ToOwner, entry); err != nil {
+		return err
+	}
+	if _, err := ows.workspaceMemberDal.Upsert(ctx, transfer.ResourceID, transfer.ToOwner, string(authz.WorkspaceRoleOwner)); err != nil {
+		return err
+	}
+	previous, err := ows.workspaceMemberDal.Get(ctx, transfer.ResourceID, transfer.FromOwner)
+	if err != nil || previous == nil {
+		return err
+	}
+	_, err = ows.workspaceMemberDal.Upsert(ctx, transfer.ResourceID, transfer.FromOwner, string(authz.WorkspaceRoleAdmin))
+	return err
+}
+
+// completeProject makes the new owner the project owner and the previous
+// owner a plain member
+func (ows *ownershipService) completeProject(ctx context.Context, transfer *ownership_transfers_dal.OwnershipTransfer, entry models.AuditLog, actor string) error {
+	if err := ows.projectDal.SetOwner(ctx, transfer.ResourceID, transfer.ToOwner, entry); err != nil {
+		return err
+	}
+	if _, err := ows.projectMemberDal.Upsert(ctx, transfer.ResourceID, transfer.ToOwner, project_members_dal.RoleOwner, actor); err != nil {
+		return err
+	}
+	previous, err := ows.projectMemberDal.Get(ctx, transfer.ResourceID, transfer.FromOwner)
+	if err != nil || previous == nil {
+		return err
+	}
+	_, err = ows.projectMemberDal.Upsert(ctx, transfer.ResourceID, transfer.FromOwner, project_members_dal.RoleMember, actor)
+	return err
+}
+
+// @Summary Transfer workspace ownership
+// @Description Transfers a workspace to one of its members (owner or system administrator only). The previous owner becomes an admin.
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param transfer body TransferRequest true "New owner"
+// @Success 200 {object} TransferResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/transfer [post]
+// @Security BearerAuth
+func (ows *ownershipService) TransferWorkspace(w http.ResponseWriter, r *http.Request) {
+	ows.transfer(w, r, ownership_transfers_dal.ResourceWorkspace, "workspace_id")
+}
+
+// @Summary Transfer project ownership
+// @Description Transfers a project to one of its members (project owner or workspace administrator only). The previous owner stays a member.
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param project_id path string true "Project ID"
+// @Param transfer body TransferRequest true "New owner"
+// @Success 200 {object} TransferResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /projects/{project_id}/transfer [post]
+// @Security BearerAuth
+func (ows *ownershipService) TransferProject(w http.ResponseWriter, r *http.Request) {
+	ows.transfer(w, r, ownership_transfers_dal.ResourceProject, "project_id")
+}
+
+func (ows *ownershipService) transfer(w http.ResponseWriter, r *http.Request, resourceType, param string) {
+	principal, err := authz.GetPrincipal(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+	email := principal.Email
+
+	resourceID, err := bson.ObjectIDFromHex(chi.URLParam(r, param))
+	if err != nil {
+		ows.logger.Error("invalid resource ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	var req TransferRequest
+	if err := render.Bind(r, &req); err != nil {
+		ows.logger.Error("failed to bind transfer request", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	current, err := ows.getResource(r.Context(), resourceType, resourceID)
+	if err != nil {
+		ows.logger.Error("failed to get resource", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(ErrResourceNotFound))
+		return
+	}
+
+	// Workspaces are handed on by their owner, or by a system administrator
+	// when the owner has left. Projects may also be reassigned by whoever
+	// administers their workspace.
+	allowed := email != "" && email == current.owner
+	if resourceType == ownership_transfers_dal.ResourceWorkspace {
+		allowed = allowed || principal.HasRole(authz.SystemAdmin)
+	} else {
+		allowed = allowed || authz.ManagesWorkspace(r)
+	}
+	if !allowed {
+		ows.logger.Error("unauthorized ownership transfer attempt", zap.String("userID", email))
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+
+	if req.NewOwner == current.owner {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrAlreadyOwner))
+		return
+	}
+
+	member, err := ows.isMember(r.Context(), resourceType, resourceID, req.NewOwner)
+	if err != nil {
+		ows.logger.Error("failed to check membership", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if !member {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrNotMember))
+		return
+	}
+
+	actor := email
+	if actor == "" {
+		actor = principal.Subject
+	}
+
+	// A new request supersedes any transfer still awaiting an answer
+	if err := ows.transferDal.CancelPending(r.Context(), resourceType, resourceID); err != nil {
+		ows.logger.Error("failed to cancel pending transfers", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	transfer, err := ows.transferDal.Create(r.Context(), &ownership_transfers_dal.OwnershipTransfer{
+		ResourceType:      resourceType,
+		ResourceID:        resourceID,
+		FromOwner:         current.owner,
+		ToOwner:           req.NewOwner,
+		RequestedBy:       actor,
+		RequireAcceptance: req.RequireAcceptance,
+		ExpiresAtUTC:      time.Now().UTC().Add(ows.ttl),
+	})
+	if err != nil {
+		ows.logger.Error("failed to create ownership transfer", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	if req.RequireAcceptance {
+		ows.notifyRecipient(r, transfer, current.name)
+		render.Respond(w, r, &TransferResponse{OwnershipTransfer: transfer})
+		return
+	}
+
+	transfer, err = ows.transferDal.Resolve(r.Context(), transfer.ID, ownership_transfers_dal.StatusCompleted)
+	if err != nil || transfer == nil {
+		ows.logger.Error("failed to complete ownership transfer", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	if err := ows.complete(r, transfer, current, actor); err != nil {
+		ows.logger.Error("failed to apply ownership transfer", zap.Error(err))
+		ows.fail(r.Context(), transfer)
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	render.Respond(w, r, &TransferResponse{OwnershipTransfer: transfer})
+}
+
+// notifyRecipient emails the new owner that a transfer awaits their answer.
+// Delivery problems are logged rather than failing the request.
+func (ows *ownershipService) notifyRecipient(r *http.Request, transfer *ownership_transfers_dal.OwnershipTransfer, name string) {
+	msg, err := notify.Render(notify.TemplateOwnershipTransfer, notify.OwnershipTransferData{
+		ResourceType: transfer.ResourceType,
+		Resource:     name,
+		From:         transfer.RequestedBy,
+		TransferID:   transfer.ID.Hex(),
+		ExpiresAt:    transfer.ExpiresAtUTC,
+	}, transfer.ToOwner)
+	if err != nil {
+		ows.logger.Error("failed to render transfer email", zap.Error(err))
+		return
+	}
+
+	if err := ows.notifier.Send(r.Context(), msg); err != nil {
+		ows.logger.Error("failed to queue transfer email", zap.Error(err))
+	}
+}
+
+// @Summary List workspace ownership history
+// @Description Lists the ownership transfers of a workspace, newest first
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} TransfersResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/transfers [get]
+// @Security BearerAuth
+func (ows *ownershipService) ListWorkspaceTransfers(w http.ResponseWriter, r *http.Request) {
+	ows.history(w, r, ownership_transfers_dal.ResourceWorkspace, "workspace_id")
+}
+
+// @Summary List project ownership history
+// @Description Lists the ownership transfers of a project, newest first
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param project_id path string true "Project ID"
+// @Success 200 {object} TransfersResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /projects/{project_id}/transfers [get]
+// @Security BearerAuth
+func (ows *ownershipService) ListProjectTransfers(w http.ResponseWriter, r *http.Request) {
+	ows.history(w, r, ownership_transfers_dal.ResourceProject, "project_id")
+}
+
+func (ows *ownershipService) history(w http.ResponseWriter, r *http.Request, resourceType, param string) {
+	resourceID, err := bson.ObjectIDFromHex(chi.URLParam(r, param))
+	if err != nil {
+		ows.logger.Error("invalid resource ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	transfers, err := ows.transferDal.ListByResource(r.Context(), resourceType, resourceID)
+	if err != nil {
+		ows.logger.Error("failed to list ownership transfers", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	render.Respond(w, r, &TransfersResponse{Transfers: transfers})
+}
+
+// @Summary List my pending transfers
+// @Description Lists the ownership transfers awaiting the caller's answer
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Success 200 {object} TransfersResponse
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /transfers [get]
+// @Security BearerAuth
+func (ows *ownershipService) ListMine(w http.ResponseWriter, r *http.Request) {
+	email, err := authz.GetEmailFromClaims(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+
+	transfers, err := ows.transferDal.ListPendingByRecipient(r.Context(), email)
+	if err != nil {
+		ows.logger.Error("failed to list ownership transfers", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	render.Respond(w, r, &TransfersResponse{Transfers: transfers})
+}
+
+// @Summary Accept ownership transfer
+// @Description Accepts a pending ownership transfer addressed to the caller
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param transfer_id path string true "Transfer ID"
+// @Success 200 {object} TransferResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /transfers/{transfer_id}/accept [post]
+// @Security BearerAuth
+func (ows *ownershipService) Accept(w http.ResponseWriter, r *http.Request) {
+	email, transfer, ok := ows.pendingForRecipient(w, r)
+	if !ok {
+		return
+	}
+
+	// Re-check the request still holds before applying it
+	current, err := ows.getResource(r.Context(), transfer.ResourceType, transfer.ResourceID)
+	if err != nil {
+		ows.logger.Error("failed to get resource", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(ErrResourceNotFound))
+		return
+	}
+	if current.owner != transfer.FromOwner {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrOwnerChanged))
+		return
+	}
+	member, err := ows.isMember(r.Context(), transfer.ResourceType, transfer.ResourceID, email)
+	if err != nil {
+		ows.logger.Error("failed to check membership", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if !member {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrNotMember))
+		return
+	}
+
+	transfer, err = ows.transferDal.Resolve(r.Context(), transfer.ID, ownership_transfers_dal.StatusCompleted)
+	if err != nil {
+		ows.logger.Error("failed to accept ownership transfer", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if transfer == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+
+	if err := ows.complete(r, transfer, current, email); err != nil {
+		ows.logger.Error("failed to apply ownership transfer", zap.Error(err))
+		ows.fail(r.Context(), transfer)
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	render.Respond(w, r, &TransferResponse{OwnershipTransfer: transfer})
+}
+
+// @Summary Decline ownership transfer
+// @Description Declines a pending ownership transfer addressed to the caller
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param transfer_id path string true "Transfer ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Router /transfers/{transfer_id}/decline [post]
+// @Security BearerAuth
+func (ows *ownershipService) Decline(w http.ResponseWriter, r *http.Request) {
+	_, transfer, ok := ows.pendingForRecipient(w, r)
+	if !ok {
+		return
+	}
+
+	ows.resolve(w, r, transfer.ID, ownership_transfers_dal.StatusDeclined)
+}
+
+// @Summary Cancel ownership transfer
+// @Description Cancels a pending ownership transfer (requester, current owner or system administrator only)
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Param transfer_id path string true "Transfer ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 401 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Router /transfers/{transfer_id}/cancel [post]
+// @Security BearerAuth
+func (ows *ownershipService) Cancel(w http.ResponseWriter, r *http.Request) {
+	principal, err := authz.GetPrincipal(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+
+	transfer, ok := ows.getTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	requester := principal.Email
+	if requester == "" {
+		requester = principal.Subject
+	}
+	if requester != transfer.RequestedBy && requester != transfer.FromOwner && !principal.HasRole(authz.SystemAdmin) {
+		ows.logger.Error("unauthorized transfer cancellation attempt", zap.String("userID", requester))
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+
+	ows.resolve(w, r, transfer.ID, ownership_transfers_dal.StatusCancelled)
+}
+
+// getTransfer loads the transfer named in the URL, rendering the error
+// response and returning false when it cannot
+func (ows *ownershipService) getTransfer(w http.ResponseWriter, r *http.Request) (*ownership_transfers_dal.OwnershipTransfer, bool) {
+	transferID, err := bson.ObjectIDFromHex(chi.URLParam(r, "transfer_id"))
+	if err != nil {
+		ows.logger.Error("invalid transfer ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return nil, false
+	}
+
+	transfer, err := ows.transferDal.Get(r.Context(), transferID)
+	if err != nil {
+		ows.logger.Error("failed to get ownership transfer", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return nil, false
+	}
+	if transfer == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return nil, false
+	}
+
+	return transfer, true
+}
+
+// pendingForRecipient loads the transfer named in the URL and checks the
+// caller is its recipient
+func (ows *ownershipService) pendingForRecipient(w http.ResponseWriter, r *http.Request) (string, *ownership_transfers_dal.OwnershipTransfer, bool) {
+	email, err := authz.GetEmailFromClaims(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return "", nil, false
+	}
+
+	transfer, ok := ows.getTransfer(w, r)
+	if !ok {
+		return "", nil, false
+	}
+
+	// Do not reveal transfers addressed to someone else
+	if transfer.ToOwner != email {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return "", nil, false
+	}
+
+	return email, transfer, true
+}
+
+// resolve answers a pending transfer without changing ownership
+func (ows *ownershipService) resolve(w http.ResponseWriter, r *http.Request, id bson.ObjectID, status string) {
+	transfer, err := ows.transferDal.Resolve(r.Context(), id, status)
+	if err != nil {
+		ows.logger.Error("failed to resolve ownership transfer", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if transfer == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+
+	render.Status(r, http.StatusNoContent)
+}
@@ -0,0 +1,49 @@
+package ownership
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	ownership_transfers_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/ownership_transfers"
+	project_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/project_members"
+	projects_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/projects"
+	workspace_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspace_members"
+	workspaces_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspaces"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/notify"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type ownershipService struct {
+	logger             *zap.Logger
+	transferDal        ownership_transfers_dal.OwnershipTransfersDal
+	workspaceDal       workspaces_dal.WorkspaceDal
+	workspaceMemberDal workspace_members_dal.WorkspaceMembersDal
+	projectDal         projects_dal.ProjectsDal
+	projectMemberDal   project_members_dal.ProjectMembersDal
+	notifier           notify.Notifier
+	auditor            *audit.Recorder
+	ttl                time.Duration
+}
+
+// NewOwnershipService returns service impl
+func NewOwnershipService(notifier notify.Notifier, auditor *audit.Recorder) OwnershipService {
+	ttlHours, err := strconv.Atoi(os.Getenv("OWNERSHIP_TRANSFER_TTL_HOURS"))
+	if err != nil || ttlHours <= 0 {
+		ttlHours = 168 // default expiry
+	}
+
+	return &ownershipService{
+		logger:             logger.NewLogger(),
+		transferDal:        ownership_transfers_dal.NewOwnershipTransfersDal(),
+		workspaceDal:       workspaces_dal.NewWorkspaceDal(),
+		workspaceMemberDal: workspace_members_dal.NewWorkspaceMembersDal(),
+		projectDal:         projects_dal.NewProjectsDal(),
+		projectMemberDal:   project_members_dal.NewProjectMembersDal(),
+		notifier:           notifier,
+		auditor:            auditor,
+		ttl:                time.Duration(ttlHours) * time.Hour,
+	}
+}
@@ -12,6 +12,7 @@ type ProjectService interface {
 	Delete(w http.ResponseWriter, r *http.Request)
 	Get(w http.ResponseWriter, r *http.Request)
 	List(w http.ResponseWriter, r *http.Request)
+	ListMembers(w http.ResponseWriter, r *http.Request)
 	AddMember(w http.ResponseWriter, r *http.Request)
 	RemoveMember(w http.ResponseWriter, r *http.Request)
 }
@@ -22,4 +23,6 @@ var (
 	ErrNotFound            = errors.New("project not found")
 	ErrInternalServerError = errors.New("internal server error")
 	ErrUnauthorized        = errors.New("unauthorized access")
+	ErrNotWorkspaceMember  = errors.New("only workspace members can be added to a project")
+	ErrLastOwner           = errors.New("a project must keep at least one owner")
 )
@@ -2,15 +2,20 @@ package projects
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
+	project_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/project_members"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/agent-auth-api/pkg/authz"
 	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
 	"github.com/agent-auth/agent-auth-api/web/renderers"
 
 	"github.com/agent-auth/common-lib/models"
+	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"go.uber.org/zap"
 )
@@ -39,15 +44,27 @@ type ProjectsResponse struct {
 
 type AddMemberRequest struct {
 	Email string `json:"email"`
+	Role  string `json:"role"`
 }
 
 func (a *AddMemberRequest) Bind(r *http.Request) error {
 	if a.Email == "" {
 		return ErrIncompleteDetails
 	}
+	switch a.Role {
+	case "":
+		a.Role = project_members_dal.RoleMember
+	case project_members_dal.RoleOwner, project_members_dal.RoleMember:
+	default:
+		return ErrIncompleteDetails
+	}
 	return nil
 }
 
+type MembersResponse struct {
+	Members []*project_members_dal.ProjectMember `json:"members"`
+}
+
 // @Summary Create project
 // @Description Creates a new project
 // @Tags projects
@@ -72,6 +89,12 @@ func (ps *projectService) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := ps.hasWorkspaceAccess(r, project.Project.WorkspaceID, authz.ProjectCreate); err != nil {
+		ps.logger.Error("unauthorized access attempt", zap.String("userID", email))
+		render.Render(w, r, renderers.ErrorUnauthorized(errors.New("unauthorized access attempt")))
+		return
+	}
+
 	project.Project.OwnerID = email
 	project.Project.Members = []string{email}
 
@@ -81,13 +104,21 @@ func (ps *projectService) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := ps.projectDal.Create(project.Project)
+	resp, err := ps.projectDal.Create(r.Context(), project.Project)
 	if err != nil {
 		ps.logger.Error("failed to create project", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to create project")))
 		return
 	}
 
+	if _, err := ps.projectMemberDal.Upsert(r.Context(), resp.ID, email, project_members_dal.RoleOwner, email); err != nil {
+		ps.logger.Error("failed to add project owner", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to create project")))
+		return
+	}
+
+	ps.auditor.Record(r, audit.ActionProjectCreate, projectTarget(resp), nil, resp)
+
 	render.Respond(w, r, &ProjectResponse{
 		Project: resp,
 	})
@@ -113,7 +144,7 @@ func (ps *projectService) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := ps.projectDal.GetByID(projectID)
+	project, err := ps.projectDal.GetByID(r.Context(), projectID)
 	if err != nil {
 		ps.logger.Error("failed to get project", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(errors.New("failed to get project")))
@@ -159,7 +190,7 @@ func (ps *projectService) List(w http.ResponseWriter, r *http.Request) {
 		skip = 0
 	}
 
-	projects, err := ps.projectDal.List(email, skip, limit)
+	projects, err := ps.projectDal.List(r.Context(), email, skip, limit)
 	if err != nil {
 		ps.logger.Error("failed to list projects", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to list projects")))
@@ -191,7 +222,7 @@ func (ps *projectService) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get existing project
-	existing, err := ps.projectDal.GetByID(projectID)
+	existing, err := ps.projectDal.GetByID(r.Context(), projectID)
 	if err != nil {
 		ps.logger.Error("failed to get project", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(errors.New("failed to get project")))
@@ -206,6 +237,8 @@ func (ps *projectService) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *existing
+
 	// Update only mutable fields
 	existing.Name = updateReq.Name
 	existing.Description = updateReq.Description
@@ -217,12 +250,14 @@ func (ps *projectService) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := ps.projectDal.Update(existing); err != nil {
+	if err := ps.projectDal.Update(r.Context(), existing); err != nil {
 		ps.logger.Error("failed to update project", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to update project")))
 		return
 	}
 
+	ps.auditor.Record(r, audit.ActionProjectUpdate, projectTarget(existing), &before, existing)
+
 	render.Respond(w, r, &ProjectResponse{Project: existing})
 }
 
@@ -246,91 +281,175 @@ func (ps *projectService) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing, err := ps.projectDal.GetByID(projectID)
+	existing, err := ps.projectDal.GetByID(r.Context(), projectID)
 	if err != nil {
 		ps.logger.Error("failed to get project", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(errors.New("failed to get project")))
 		return
 	}
 
-	// only owner can delete project
-	if email != existing.OwnerID {
+	// only owner or a workspace administrator can delete project
+	if email != existing.OwnerID && !authz.ManagesWorkspace(r) {
 		ps.logger.Error("unauthorized access attempt", zap.String("userID", email))
 		render.Render(w, r, renderers.ErrorUnauthorized(errors.New("unauthorized access attempt")))
 		return
 	}
 
-	if err := ps.projectDal.Delete(projectID); err != nil {
+	if err := ps.projectDal.Delete(r.Context(), projectID); err != nil {
 		ps.logger.Error("failed to delete project", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to delete project")))
 		return
 	}
 
+	if err := ps.projectMemberDal.DeleteByProjectID(r.Context(), projectID); err != nil {
+		ps.logger.Error("failed to delete project members", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to delete project")))
+		return
+	}
+
+	ps.auditor.Record(r, audit.ActionProjectDelete, projectTarget(existing), existing, nil)
+
 	render.Status(r, http.StatusNoContent)
 }
 
+// @Summary List project members
+// @Description Lists the members of a project with their roles
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param project_id path string true "Project ID"
+// @Success 200 {object} MembersResponse
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 401 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /projects/{project_id}/members [get]
+// @Security BearerAuth
+func (ps *projectService) ListMembers(w http.ResponseWriter, r *http.Request) {
+	projectID, email, err := ps.hasMemberAccess(r)
+	if err != nil {
+		ps.logger.Error("unauthorized access attempt", zap.String("userID", email))
+		render.Render(w, r, renderers.ErrorUnauthorized(errors.New("unauthorized access attempt")))
+		return
+	}
+
+	members, err := ps.projectMemberDal.ListByProject(r.Context(), projectID)
+	if err != nil {
+		ps.logger.Error("failed to list members", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to list members")))
+		return
+	}
+
+	render.Respond(w, r, &MembersResponse{
+		Members: members,
+	})
+}
+
 // @Summary Remove member from project
-// @Description Removes a member from a project (owner only)
+// @Description Removes a member from a project (project owner or workspace administrator only). The last owner cannot be removed.
 // @Tags projects
 // @Accept json
 // @Produce json
 // @Param project_id path string true "Project ID"
+// @Param member_id path string true "Member email"
 // @Success 204 "No Content"
 // @Failure 400 {object} errorinterface.ErrorResponse
 // @Failure 401 {object} errorinterface.ErrorResponse
 // @Failure 404 {object} errorinterface.ErrorResponse
-// @Router /projects/{project_id}/members [delete]
+// @Router /projects/{project_id}/members/{member_id} [delete]
 // @Security BearerAuth
 func (ps *projectService) RemoveMember(w http.ResponseWriter, r *http.Request) {
-	projectID, email, err := ps.hasMemberAccess(r)
+	projectID, email, err := ps.hasOwnerAccess(r)
 	if err != nil {
 		ps.logger.Error("unauthorized access attempt", zap.String("userID", email))
 		render.Render(w, r, renderers.ErrorUnauthorized(errors.New("unauthorized access attempt")))
 		return
 	}
 
-	var req AddMemberRequest
-	if err := render.Bind(r, &req); err != nil {
-		ps.logger.Error("failed to bind project request", zap.Error(err))
-		render.Render(w, r, renderers.ErrorBadRequest(errors.New("failed to bind project request")))
+	memberID, err := url.PathUnescape(chi.URLParam(r, "member_id"))
+	if err != nil || memberID == "" {
+		ps.logger.Error("invalid member ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
 		return
 	}
 
-	existing, err := ps.projectDal.GetByID(projectID)
+	existing, err := ps.projectDal.GetByID(r.Context(), projectID)
 	if err != nil {
 		ps.logger.Error("failed to get project", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(errors.New("failed to get project")))
 		return
 	}
 
-	if req.Email == existing.OwnerID {
-		ps.logger.Error("attempt to remove owner", zap.String("email", req.Email))
-		render.Render(w, r, renderers.ErrorBadRequest(errors.New("cannot remove project owner")))
+	member, err := ps.projectMemberDal.Get(r.Context(), projectID, memberID)
+	if err != nil {
+		ps.logger.Error("failed to get member", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to get member")))
+		return
+	}
+	if member == nil {
+		render.Render(w, r, renderers.ErrorNotFound(errors.New("member not found")))
 		return
 	}
 
-	if req.Email == email && email != existing.OwnerID {
-		ps.logger.Error("non-owner attempting self-removal", zap.String("email", email))
-		render.Render(w, r, renderers.ErrorUnauthorized(errors.New("unauthorized access attempt")))
+	// The remaining owner takes over the project document when its owner leaves
+	var successor string
+	if member.Role == project_members_dal.RoleOwner || memberID == existing.OwnerID {
+		owners, err := ps.projectMemberDal.ListByProject(r.Context(), projectID)
+		if err != nil {
+			ps.logger.Error("failed to list members", zap.Error(err))
+			render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to list members")))
+			return
+		}
+		for _, owner := range owners {
+			if owner.Role == project_members_dal.RoleOwner && owner.Email != memberID {
+				successor = owner.Email
+				break
+			}
+		}
+		if successor == "" {
+			ps.logger.Error("attempt to remove last owner", zap.String("email", memberID))
+			render.Render(w, r, renderers.ErrorBadRequest(ErrLastOwner))
+			return
+		}
+	}
+
+	if memberID == existing.OwnerID {
+		entry := models.AuditLog{
+			Action:    "ownership_transferred",
+			Details:   fmt.Sprintf("ownership passed from %s to %s when %s was removed", memberID, successor, memberID),
+			Timestamp: time.Now().UTC(),
+			UserID:    email,
+		}
+		if err := ps.projectDal.SetOwner(r.Context(), projectID, successor, entry); err != nil {
+			ps.logger.Error("failed to set project owner", zap.Error(err))
+			render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to remove member")))
+			return
+		}
+	}
+
+	if err := ps.projectDal.RemoveMember(r.Context(), projectID, memberID); err != nil {
+		ps.logger.Error("failed to remove member", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to remove member")))
 		return
 	}
 
-	if err := ps.projectDal.RemoveMember(projectID, req.Email); err != nil {
+	if err := ps.projectMemberDal.Remove(r.Context(), projectID, memberID); err != nil {
 		ps.logger.Error("failed to remove member", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to remove member")))
 		return
 	}
 
+	ps.auditor.Record(r, audit.ActionProjectMemberRemove, projectTarget(existing), member, nil)
+
 	render.Status(r, http.StatusNoContent)
 }
 
 // @Summary Add member to project
-// @Description Adds a new member to a project
+// @Description Adds a workspace member to a project or changes their role (project owner or workspace administrator only)
 // @Tags projects
 // @Accept json
 // @Produce json
 // @Param project_id path string true "Project ID"
-// @Param member body AddMemberRequest true "Member ID to add"
+// @Param member body AddMemberRequest true "Member to add"
 // @Success 204 "No Content"
 // @Failure 400 {object} errorinterface.ErrorResponse
 // @Failure 401 {object} errorinterface.ErrorResponse
@@ -339,7 +458,7 @@ func (ps *projectService) RemoveMember(w http.ResponseWriter, r *http.Request) {
 // @Router /projects/{project_id}/members [post]
 // @Security BearerAuth
 func (ps *projectService) AddMember(w http.ResponseWriter, r *http.Request) {
-	projectID, email, err := ps.hasMemberAccess(r)
+	projectID, email, err := ps.hasOwnerAccess(r)
 	if err != nil {
 		ps.logger.Error("unauthorized access attempt", zap.String("userID", email))
 		render.Render(w, r, renderers.ErrorUnauthorized(errors.New("unauthorized access attempt")))
@@ -353,23 +472,60 @@ func (ps *projectService) AddMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isMember, err := ps.projectDal.IsMember(projectID, req.Email)
+	existing, err := ps.projectDal.GetByID(r.Context(), projectID)
+	if err != nil {
+		ps.logger.Error("failed to get project", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(errors.New("failed to get project")))
+		return
+	}
+
+	workspaceMember, err := ps.memberDal.Get(r.Context(), existing.WorkspaceID, req.Email)
+	if err != nil {
+		ps.logger.Error("failed to check workspace membership", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to check member status")))
+		return
+	}
+	if workspaceMember == nil {
+		ps.logger.Error("attempt to add non workspace member", zap.String("email", req.Email))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrNotWorkspaceMember))
+		return
+	}
+
+	member, err := ps.projectMemberDal.Get(r.Context(), projectID, req.Email)
 	if err != nil {
 		ps.logger.Error("failed to check member status", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to check member status")))
 		return
 	}
-	if isMember {
-		ps.logger.Error("attempt to add existing member", zap.String("email", req.Email))
-		render.Render(w, r, renderers.ErrorBadRequest(errors.New("user is already a member")))
+
+	// Demoting an owner must leave another owner behind
+	if member != nil && member.Role == project_members_dal.RoleOwner && req.Role != project_members_dal.RoleOwner {
+		owners, err := ps.projectMemberDal.CountByRole(r.Context(), projectID, project_members_dal.RoleOwner)
+		if err != nil {
+			ps.logger.Error("failed to count owners", zap.Error(err))
+			render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to check member status")))
+			return
+		}
+		if owners <= 1 || req.Email == existing.OwnerID {
+			render.Render(w, r, renderers.ErrorBadRequest(ErrLastOwner))
+			return
+		}
+	}
+
+	if err := ps.projectDal.AddMember(r.Context(), projectID, req.Email); err != nil {
+		ps.logger.Error("failed to add member", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to add member")))
 		return
 	}
 
-	if err := ps.projectDal.AddMember(projectID, req.Email); err != nil {
+	added, err := ps.projectMemberDal.Upsert(r.Context(), projectID, req.Email, req.Role, email)
+	if err != nil {
 		ps.logger.Error("failed to add member", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to add member")))
 		return
 	}
 
+	ps.auditor.Record(r, audit.ActionProjectMemberAdd, projectTarget(existing), member, added)
+
 	render.Status(r, http.StatusNoContent)
 }
@@ -1,20 +1,29 @@
 package projects
 
 import (
+	project_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/project_members"
 	projects_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/projects"
+	workspace_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspace_members"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/common-lib/pkg/logger"
 	"go.uber.org/zap"
 )
 
 type projectService struct {
-	logger     *zap.Logger
-	projectDal projects_dal.ProjectsDal
+	logger           *zap.Logger
+	projectDal       projects_dal.ProjectsDal
+	memberDal        workspace_members_dal.WorkspaceMembersDal
+	projectMemberDal project_members_dal.ProjectMembersDal
+	auditor          *audit.Recorder
 }
 
 // NewProjectService returns service impl
-func NewProjectService() ProjectService {
+func NewProjectService(auditor *audit.Recorder) ProjectService {
 	return &projectService{
-		logger:     logger.NewLogger(),
-		projectDal: projects_dal.NewProjectsDal(),
+		logger:           logger.NewLogger(),
+		projectDal:       projects_dal.NewProjectsDal(),
+		memberDal:        workspace_members_dal.NewWorkspaceMembersDal(),
+		projectMemberDal: project_members_dal.NewProjectMembersDal(),
+		auditor:          auditor,
 	}
 }
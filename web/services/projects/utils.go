@@ -5,7 +5,10 @@ import (
 
 	"errors"
 
+	project_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/project_members"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/common-lib/models"
 	"github.com/go-chi/chi"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -17,12 +20,18 @@ func (rp *projectService) hasMemberAccess(r *http.Request) (bson.ObjectID, strin
 		return bson.NilObjectID, "", errors.New("invalid project ID format")
 	}
 
+	// Workspace owners and admins manage every project of their workspace
+	if authz.ManagesWorkspace(r) {
+		email, _ := authz.GetEmailFromClaims(r)
+		return projectID, email, nil
+	}
+
 	email, err := authz.GetEmailFromClaims(r)
 	if err != nil {
 		return bson.NilObjectID, "", ErrUnauthorized
 	}
 
-	isMember, err := rp.projectDal.IsMember(projectID, email)
+	isMember, err := rp.projectDal.IsMember(r.Context(), projectID, email)
 	if err != nil {
 		return bson.NilObjectID, "", ErrInternalServerError
 	}
@@ -33,3 +42,63 @@ func (rp *projectService) hasMemberAccess(r *http.Request) (bson.ObjectID, strin
 
 	return projectID, email, nil
 }
+
+// Helper function to check if a user may manage the members of a project, as
+// one of its owners or as a workspace administrator
+func (rp *projectService) hasOwnerAccess(r *http.Request) (bson.ObjectID, string, error) {
+	projectID, email, err := rp.hasMemberAccess(r)
+	if err != nil {
+		return bson.NilObjectID, email, err
+	}
+
+	if authz.ManagesWorkspace(r) {
+		return projectID, email, nil
+	}
+
+	member, err := rp.projectMemberDal.Get(r.Context(), projectID, email)
+	if err != nil {
+		return bson.NilObjectID, email, ErrInternalServerError
+	}
+
+	if member == nil || member.Role != project_members_dal.RoleOwner {
+		return bson.NilObjectID, email, ErrUnauthorized
+	}
+
+	return projectID, email, nil
+}
+
+// Helper function to check if a user holds a permission in a workspace
+func (rp *projectService) hasWorkspaceAccess(r *http.Request, workspaceID bson.ObjectID, permission authz.Permission) error {
+	principal, err := authz.GetPrincipal(r)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	if principal.HasRole(authz.SystemAdmin) {
+		return nil
+	}
+	if principal.Email == "" {
+		return ErrUnauthorized
+	}
+
+	member, err := rp.memberDal.Get(r.Context(), workspaceID, principal.Email)
+	if err != nil {
+		return ErrInternalServerError
+	}
+
+	if member == nil || !authz.WorkspaceRole(member.Role).HasPermission(permission) {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// projectTarget identifies a project in the audit log
+func projectTarget(project *models.Project) audit.Target {
+	return audit.Target{
+		Type:        audit.TargetProject,
+		ID:          project.ID.Hex(),
+		WorkspaceID: project.WorkspaceID,
+		ProjectID:   project.ID,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
 	"github.com/agent-auth/agent-auth-api/web/renderers"
 	"github.com/agent-auth/common-lib/models"
@@ -81,7 +82,7 @@ func (rs *resourceService) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if resource with same URN exists in the project
-	existing, err := rs.resources_dal.GetByURNAndProjectID(resource.Resource.URN, project_id)
+	existing, err := rs.resources_dal.GetByURNAndProjectID(r.Context(), resource.Resource.URN, project_id)
 	if err == nil && existing != nil {
 		rs.logger.Error("resource with this URN already exists in project",
 			zap.String("urn", resource.Resource.URN),
@@ -96,13 +97,15 @@ func (rs *resourceService) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := rs.resources_dal.Create(resource.Resource)
+	resp, err := rs.resources_dal.Create(r.Context(), resource.Resource)
 	if err != nil {
 		rs.logger.Error("failed to create resource", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to create resource")))
 		return
 	}
 
+	rs.auditor.Record(r, audit.ActionResourceCreate, resourceTarget(resp), nil, resp)
+
 	render.Respond(w, r, &ResourceResponse{
 		Resource: resp,
 	})
@@ -135,7 +138,7 @@ func (rs *resourceService) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resource, err := rs.resources_dal.GetByID(resource_id)
+	resource, err := rs.resources_dal.GetByID(r.Context(), resource_id)
 	if err != nil {
 		rs.logger.Error("failed to get resource", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(errors.New("resource not found")))
@@ -163,7 +166,7 @@ func (rs *resourceService) ListByProject(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	resources, err := rs.resources_dal.GetByProjectID(project_id)
+	resources, err := rs.resources_dal.GetByProjectID(r.Context(), project_id)
 	if err != nil {
 		rs.logger.Error("failed to list resources", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to list resources")))
@@ -199,19 +202,27 @@ func (rs *resourceService) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := rs.hasRoleAccess(resource_id, project_id); err != nil {
+	if err := rs.hasRoleAccess(r.Context(), resource_id, project_id); err != nil {
 		rs.logger.Error("resource verification failed", zap.Error(err))
 		render.Render(w, r, renderers.ErrorUnauthorized(err))
 		return
 	}
 
-	existing, err := rs.resources_dal.GetByID(resource_id)
+	existing, err := rs.resources_dal.GetByID(r.Context(), resource_id)
 	if err != nil {
 		rs.logger.Error("failed to get resource", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(errors.New("resource not found")))
 		return
 	}
 
+	// Binding decodes into existing, reusing its slices, so snapshot it first
+	before, err := json.Marshal(existing)
+	if err != nil {
+		rs.logger.Error("failed to snapshot resource", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to update resource")))
+		return
+	}
+
 	resource := &ResourceRequest{Resource: existing}
 	if err := render.Bind(r, resource); err != nil {
 		rs.logger.Error("failed to bind update request", zap.Error(err))
@@ -229,12 +240,14 @@ func (rs *resourceService) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := rs.resources_dal.Update(existing); err != nil {
+	if err := rs.resources_dal.Update(r.Context(), existing); err != nil {
 		rs.logger.Error("failed to update resource", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to update resource")))
 		return
 	}
 
+	rs.auditor.Record(r, audit.ActionResourceUpdate, resourceTarget(existing), json.RawMessage(before), existing)
+
 	render.Respond(w, r, &ResourceResponse{Resource: existing})
 }
 
@@ -263,17 +276,26 @@ func (rs *resourceService) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := rs.hasRoleAccess(resource_id, project_id); err != nil {
+	if err := rs.hasRoleAccess(r.Context(), resource_id, project_id); err != nil {
 		rs.logger.Error("resource verification failed", zap.Error(err))
 		render.Render(w, r, renderers.ErrorUnauthorized(err))
 		return
 	}
 
-	if err := rs.resources_dal.Delete(resource_id); err != nil {
+	existing, err := rs.resources_dal.GetByID(r.Context(), resource_id)
+	if err != nil {
+		rs.logger.Error("failed to get resource", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(errors.New("resource not found")))
+		return
+	}
+
+	if err := rs.resources_dal.Delete(r.Context(), resource_id); err != nil {
 		rs.logger.Error("failed to delete resource", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New("failed to delete resource")))
 		return
 	}
 
+	rs.auditor.Record(r, audit.ActionResourceDelete, resourceTarget(existing), existing, nil)
+
 	render.Status(r, http.StatusNoContent)
 }
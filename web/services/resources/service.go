@@ -3,6 +3,7 @@ package resources
 import (
 	projects_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/projects"
 	resources_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/resources"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/common-lib/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -11,13 +12,15 @@ type resourceService struct {
 	logger        *zap.Logger
 	resources_dal resources_dal.ResourcesDal
 	projects_dal  projects_dal.ProjectsDal
+	auditor       *audit.Recorder
 }
 
 // NewResourceService returns service impl
-func NewResourceService() ResourceService {
+func NewResourceService(auditor *audit.Recorder) ResourceService {
 	return &resourceService{
 		logger:        logger.NewLogger(),
 		resources_dal: resources_dal.NewResourcesDal(),
 		projects_dal:  projects_dal.NewProjectsDal(),
+		auditor:       auditor,
 	}
 }
@@ -1,10 +1,13 @@
 package resources
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/common-lib/models"
 	"github.com/go-chi/chi"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -16,12 +19,18 @@ func (rs *resourceService) hasMemberAccess(r *http.Request) (bson.ObjectID, stri
 		return bson.NilObjectID, "", errors.New("invalid project ID")
 	}
 
+	// Workspace owners and admins manage every project of their workspace
+	if authz.ManagesWorkspace(r) {
+		email, _ := authz.GetEmailFromClaims(r)
+		return projectID, email, nil
+	}
+
 	email, err := authz.GetEmailFromClaims(r)
 	if err != nil {
 		return bson.NilObjectID, "", errors.New("unauthorized access")
 	}
 
-	isMember, err := rs.projects_dal.IsMember(projectID, email)
+	isMember, err := rs.projects_dal.IsMember(r.Context(), projectID, email)
 	if err != nil {
 		return bson.NilObjectID, "", errors.New("failed to verify project membership")
 	}
@@ -34,8 +43,8 @@ func (rs *resourceService) hasMemberAccess(r *http.Request) (bson.ObjectID, stri
 }
 
 // hasRoleAccess checks if the resource belongs to the specified project
-func (rs *resourceService) hasRoleAccess(resource_id, project_id bson.ObjectID) error {
-	existing, err := rs.resources_dal.GetByID(resource_id)
+func (rs *resourceService) hasRoleAccess(ctx context.Context, resource_id, project_id bson.ObjectID) error {
+	existing, err := rs.resources_dal.GetByID(ctx, resource_id)
 	if err != nil {
 		return errors.New("resource not found")
 	}
@@ -46,3 +55,12 @@ func (rs *resourceService) hasRoleAccess(resource_id, project_id bson.ObjectID)
 
 	return nil
 }
+
+// resourceTarget identifies a resource in the audit log
+func resourceTarget(resource *models.Resource) audit.Target {
+	return audit.Target{
+		Type:      audit.TargetResource,
+		ID:        resource.ID.Hex(),
+		ProjectID: resource.ProjectID,
+	}
+}
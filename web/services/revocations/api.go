@@ -0,0 +1,18 @@
+package revocations
+
+import (
+	"errors"
+	"net/http"
+)
+
+// RevocationService interface
+type RevocationService interface {
+	RevokeToken(w http.ResponseWriter, r *http.Request)
+	RevokeSubject(w http.ResponseWriter, r *http.Request)
+}
+
+// The list of error types presented to the end user
+var (
+	ErrIncompleteDetails = errors.New("incorrect details provided, please provide correct details")
+	ErrRevocationFailed  = errors.New("failed to record revocation")
+)
@@ -0,0 +1,113 @@
+package revocations
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// @Description Token revocation request model
+type RevokeTokenRequest struct {
+	JTI       string    `json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (t *RevokeTokenRequest) Bind(r *http.Request) error {
+	if t.JTI == "" {
+		return ErrIncompleteDetails
+	}
+	return nil
+}
+
+// @Description Subject revocation request model. Issuer is the iss claim of the subject's tokens, left empty only for tokens without one.
+type RevokeSubjectRequest struct {
+	Issuer  string    `json:"issuer"`
+	Subject string    `json:"subject"`
+	Before  time.Time `json:"before"`
+}
+
+func (s *RevokeSubjectRequest) Bind(r *http.Request) error {
+	if s.Subject == "" {
+		return ErrIncompleteDetails
+	}
+	return nil
+}
+
+// @Summary Revoke token
+// @Description Revokes a single token by its jti until the token expires (system admin only)
+// @Tags revocations
+// @Accept json
+// @Produce json
+// @Param revocation body RevokeTokenRequest true "Token to revoke"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /admin/revocations/tokens [post]
+// @Security BearerAuth
+func (rs *revocationService) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	email, _ := authz.GetEmailFromClaims(r)
+
+	var req RevokeTokenRequest
+	if err := render.Bind(r, &req); err != nil {
+		rs.logger.Error("failed to bind revoke token request", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	if err := rs.checker.RevokeToken(r.Context(), req.JTI, req.ExpiresAt); err != nil {
+		rs.logger.Error("failed to revoke token", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrRevocationFailed))
+		return
+	}
+
+	rs.logger.Info("token revoked",
+		zap.String("jti", req.JTI),
+		zap.String("revokedBy", email))
+
+	render.Status(r, http.StatusNoContent)
+}
+
+// @Summary Revoke subject
+// @Description Revokes every token an issuer issued to a subject before the given time, defaults to now (system admin only)
+// @Tags revocations
+// @Accept json
+// @Produce json
+// @Param revocation body RevokeSubjectRequest true "Subject to revoke"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /admin/revocations/subjects [post]
+// @Security BearerAuth
+func (rs *revocationService) RevokeSubject(w http.ResponseWriter, r *http.Request) {
+	email, _ := authz.GetEmailFromClaims(r)
+
+	var req RevokeSubjectRequest
+	if err := render.Bind(r, &req); err != nil {
+		rs.logger.Error("failed to bind revoke subject request", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	if req.Before.IsZero() {
+		req.Before = time.Now().UTC()
+	}
+
+	if err := rs.checker.RevokeSubject(r.Context(), req.Issuer, req.Subject, req.Before); err != nil {
+		rs.logger.Error("failed to revoke subject", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrRevocationFailed))
+		return
+	}
+
+	rs.logger.Info("subject revoked",
+		zap.String("issuer", req.Issuer),
+		zap.String("subject", req.Subject),
+		zap.Time("before", req.Before),
+		zap.String("revokedBy", email))
+
+	render.Status(r, http.StatusNoContent)
+}
@@ -0,0 +1,21 @@
+package revocations
+
+import (
+	"github.com/agent-auth/agent-auth-api/pkg/revocation"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type revocationService struct {
+	logger  *zap.Logger
+	checker *revocation.Checker
+}
+
+// NewRevocationService returns service impl. It shares the checker used by the
+// auth middleware so revocations take effect on this replica immediately.
+func NewRevocationService(checker *revocation.Checker) RevocationService {
+	return &revocationService{
+		logger:  logger.NewLogger(),
+		checker: checker,
+	}
+}
@@ -14,6 +14,11 @@ type RolesService interface {
 	DeleteRolesByProject(w http.ResponseWriter, r *http.Request)
 
 	UpdatePermission(w http.ResponseWriter, r *http.Request)
+
+	AssignRole(w http.ResponseWriter, r *http.Request)
+	UnassignRole(w http.ResponseWriter, r *http.Request)
+	ListRoleMembers(w http.ResponseWriter, r *http.Request)
+	ListSubjectRoles(w http.ResponseWriter, r *http.Request)
 }
 
 // The list of error types presented to the end user
@@ -0,0 +1,316 @@
+package roles_permissions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	role_bindings_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/role_bindings"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/notify"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// @Description Role assignment request model
+type AssignRoleRequest struct {
+	Subject     string `json:"subject"`
+	SubjectType string `json:"subject_type"`
+}
+
+func (a *AssignRoleRequest) Bind(r *http.Request) error {
+	a.Subject = strings.TrimSpace(a.Subject)
+	if a.Subject == "" {
+		return ErrIncompleteDetails
+	}
+
+	switch a.SubjectType {
+	case "":
+		a.SubjectType = role_bindings_dal.SubjectTypeUser
+	case role_bindings_dal.SubjectTypeUser, role_bindings_dal.SubjectTypeAgent:
+	default:
+		return ErrIncompleteDetails
+	}
+
+	// Users are identified by email, agents by their token subject
+	if a.SubjectType == role_bindings_dal.SubjectTypeUser && !strings.Contains(a.Subject, "@") {
+		return ErrIncompleteDetails
+	}
+
+	return nil
+}
+
+// @Description Role binding response model
+type RoleBindingResponse struct {
+	*role_bindings_dal.RoleBinding
+}
+
+// @Description Roles held by a subject in a project
+type SubjectRolesResponse struct {
+	Subject  string                           `json:"subject"`
+	Bindings []*role_bindings_dal.RoleBinding `json:"bindings"`
+}
+
+// subjectFromURL reads a subject path parameter, which may be escaped
+func subjectFromURL(r *http.Request) (string, error) {
+	subject, err := url.PathUnescape(chi.URLParam(r, "subject"))
+	if err != nil || subject == "" {
+		return "", ErrIncompleteDetails
+	}
+	return subject, nil
+}
+
+// @Summary Assign role
+// @Description Assigns a project role to a user or agent
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param project_id path string true "Project ID"
+// @Param role_id path string true "Role ID"
+// @Param binding body AssignRoleRequest true "Subject to assign the role to"
+// @Success 200 {object} RoleBindingResponse
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /projects/{project_id}/roles/{role_id}/members [post]
+// @Security BearerAuth
+func (rp *rolesService) AssignRole(w http.ResponseWriter, r *http.Request) {
+	projectID, email, err := rp.hasMemberAccess(r)
+	if err != nil {
+		rp.logger.Error("project membership verification failed", zap.Error(err))
+		render.Render(w, r, renderers.ErrorForbidden(fmt.Errorf("project membership verification failed")))
+		return
+	}
+
+	roleID, err := bson.ObjectIDFromHex(chi.URLParam(r, "role_id"))
+	if err != nil {
+		rp.logger.Error("invalid role ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(fmt.Errorf("invalid role ID")))
+		return
+	}
+
+	role, err := rp.rolesDal.Get(r.Context(), roleID)
+	if err != nil || role.ProjectID != projectID {
+		rp.logger.Error("role verification failed", zap.Error(err))
+		render.Render(w, r, renderers.ErrorForbidden(ErrInvalidRole))
+		return
+	}
+
+	var req AssignRoleRequest
+	if err := render.Bind(r, &req); err != nil {
+		msg := "invalid or incomplete request body"
+		rp.logger.Error(msg, zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(errors.New(msg)))
+		return
+	}
+
+	binding, err := rp.bindingsDal.Assign(r.Context(), &role_bindings_dal.RoleBinding{
+		ProjectID:   projectID,
+		RoleID:      roleID,
+		Role:        role.Role,
+		Subject:     req.Subject,
+		SubjectType: req.SubjectType,
+		AssignedBy:  email,
+	})
+	if err != nil {
+		rp.logger.Error("failed to assign role", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(fmt.Errorf("failed to assign role")))
+		return
+	}
+
+	rp.auditor.Record(r, audit.ActionRoleAssign, roleTarget(role), nil, binding)
+
+	if binding.SubjectType == role_bindings_dal.SubjectTypeUser {
+		rp.notifyRoleGranted(r, binding)
+	}
+
+	render.Respond(w, r, &RoleBindingResponse{
+		RoleBinding: binding,
+	})
+}
+
+// notifyRoleGranted emails a user who was assigned a role. Delivery problems
+// are logged rather than failing the assignment.
+func (rp *rolesService) notifyRoleGranted(r *http.Request, binding *role_bindings_dal.RoleBinding) {
+	project, err := rp.projectsDal.GetByID(r.Context(), binding.ProjectID)
+	if err != nil {
+		rp.logger.Error("failed to get project for role email", zap.Error(err))
+		return
+	}
+
+	msg, err := notify.Render(notify.TemplateRoleGranted, notify.RoleGrantedData{
+		Project:   project.Name,
+		Role:      binding.Role,
+		GrantedBy: binding.AssignedBy,
+	}, binding.Subject)
+	if err != nil {
+		rp.logger.Error("failed to render role email", zap.Error(err))
+		return
+	}
+
+	if err := rp.notifier.Send(r.Context(), msg); err != nil {
+		rp.logger.Error("failed to queue role email", zap.Error(err))
+	}
+}
+
+// @Summary Unassign role
+// @Description Removes a project role from a user or agent
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param project_id path string true "Project ID"
+// @Param role_id path string true "Role ID"
+// @Param subject path string true "Subject (email or agent ID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 404 {object} errorinterface.ErrorResponse
+// @Router /projects/{project_id}/roles/{role_id}/members/{subject} [delete]
+// @Security BearerAuth
+func (rp *rolesService) UnassignRole(w http.ResponseWriter, r *http.Request) {
+	projectID, _, err := rp.hasMemberAccess(r)
+	if err != nil {
+		rp.logger.Error("project membership verification failed", zap.Error(err))
+		render.Render(w, r, renderers.ErrorForbidden(fmt.Errorf("project membership verification failed")))
+		return
+	}
+
+	roleID, err := bson.ObjectIDFromHex(chi.URLParam(r, "role_id"))
+	if err != nil {
+		rp.logger.Error("invalid role ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(fmt.Errorf("invalid role ID")))
+		return
+	}
+
+	role, err := rp.rolesDal.Get(r.Context(), roleID)
+	if err != nil || role.ProjectID != projectID {
+		rp.logger.Error("role verification failed", zap.Error(err))
+		render.Render(w, r, renderers.ErrorForbidden(ErrInvalidRole))
+		return
+	}
+
+	subject, err := subjectFromURL(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	bindings, err := rp.bindingsDal.ListBySubject(r.Context(), projectID, subject)
+	if err != nil {
+		rp.logger.Error("failed to list subject roles", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(fmt.Errorf("failed to unassign role")))
+		return
+	}
+
+	var previous *role_bindings_dal.RoleBinding
+	for _, binding := range bindings {
+		if binding.RoleID == roleID {
+			previous = binding
+		}
+	}
+
+	if err := rp.bindingsDal.Unassign(r.Context(), roleID, subject); err != nil {
+		rp.logger.Error("failed to unassign role", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(fmt.Errorf("failed to unassign role")))
+		return
+	}
+
+	rp.auditor.Record(r, audit.ActionRoleUnassign, roleTarget(role), previous, nil)
+
+	render.Status(r, http.StatusNoContent)
+}
+
+// @Summary List role members
+// @Description Lists the users and agents holding a project role
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param project_id path string true "Project ID"
+// @Param role_id path string true "Role ID"
+// @Success 200 {array} RoleBindingResponse
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /projects/{project_id}/roles/{role_id}/members [get]
+// @Security BearerAuth
+func (rp *rolesService) ListRoleMembers(w http.ResponseWriter, r *http.Request) {
+	projectID, _, err := rp.hasMemberAccess(r)
+	if err != nil {
+		rp.logger.Error("project membership verification failed", zap.Error(err))
+		render.Render(w, r, renderers.ErrorForbidden(fmt.Errorf("project membership verification failed")))
+		return
+	}
+
+	roleID, err := bson.ObjectIDFromHex(chi.URLParam(r, "role_id"))
+	if err != nil {
+		rp.logger.Error("invalid role ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(fmt.Errorf("invalid role ID")))
+		return
+	}
+
+	if err := rp.hasRoleAccess(r.Context(), roleID, projectID); err != nil {
+		rp.logger.Error("role verification failed", zap.Error(err))
+		render.Render(w, r, renderers.ErrorForbidden(ErrInvalidRole))
+		return
+	}
+
+	bindings, err := rp.bindingsDal.ListByRole(r.Context(), roleID)
+	if err != nil {
+		rp.logger.Error("failed to list role members", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(fmt.Errorf("failed to list role members")))
+		return
+	}
+
+	response := make([]*RoleBindingResponse, len(bindings))
+	for i, binding := range bindings {
+		response[i] = &RoleBindingResponse{RoleBinding: binding}
+	}
+
+	render.Respond(w, r, response)
+}
+
+// @Summary List subject roles
+// @Description Lists the project roles held by a user or agent
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param project_id path string true "Project ID"
+// @Param subject path string true "Subject (email or agent ID)"
+// @Success 200 {object} SubjectRolesResponse
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /projects/{project_id}/subjects/{subject}/roles [get]
+// @Security BearerAuth
+func (rp *rolesService) ListSubjectRoles(w http.ResponseWriter, r *http.Request) {
+	projectID, _, err := rp.hasMemberAccess(r)
+	if err != nil {
+		rp.logger.Error("project membership verification failed", zap.Error(err))
+		render.Render(w, r, renderers.ErrorForbidden(fmt.Errorf("project membership verification failed")))
+		return
+	}
+
+	subject, err := subjectFromURL(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	bindings, err := rp.bindingsDal.ListBySubject(r.Context(), projectID, subject)
+	if err != nil {
+		rp.logger.Error("failed to list subject roles", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(fmt.Errorf("failed to list subject roles")))
+		return
+	}
+
+	render.Respond(w, r, &SubjectRolesResponse{
+		Subject:  subject,
+		Bindings: bindings,
+	})
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
 	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/agent-auth/common-lib/models"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -43,7 +45,8 @@ func (rp *rolesService) UpdatePermission(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := rp.hasRoleAccess(roleID, projectID); err != nil {
+	role, err := rp.rolesDal.Get(r.Context(), roleID)
+	if err != nil || role.ProjectID != projectID {
 		rp.logger.Error("role verification failed", zap.Error(err))
 		render.Render(w, r, renderers.ErrorForbidden(fmt.Errorf("role verification failed")))
 		return
@@ -58,7 +61,7 @@ func (rp *rolesService) UpdatePermission(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Add resource existence check
-	if resource, err := rp.resourcesDal.GetByURNAndProjectID(req.Resource, projectID); err != nil {
+	if resource, err := rp.resourcesDal.GetByURNAndProjectID(r.Context(), req.Resource, projectID); err != nil {
 		msg := "failed to verify resource existence"
 		rp.logger.Error(msg, zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New(msg)))
@@ -70,12 +73,26 @@ func (rp *rolesService) UpdatePermission(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := rp.rolesDal.UpdatePermission(roleID, req.Resource, req.Actions); err != nil {
+	if err := rp.rolesDal.UpdatePermission(r.Context(), roleID, req.Resource, req.Actions); err != nil {
 		msg := "failed to update permission attribute"
 		rp.logger.Error(msg, zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(errors.New(msg)))
 		return
 	}
 
+	// Record the grant of the one resource, keyed by its URN
+	before := map[string]models.Permission{}
+	if permission, ok := role.Permissions[req.Resource]; ok {
+		before[req.Resource] = permission
+	}
+	after := map[string]models.Permission{}
+	if len(req.Actions) > 0 {
+		after[req.Resource] = models.Permission{Actions: req.Actions}
+	}
+
+	target := roleTarget(role)
+	target.Type = audit.TargetPermission
+	rp.auditor.Record(r, audit.ActionPermissionUpdate, target, before, after)
+
 	render.Status(r, http.StatusNoContent)
 }
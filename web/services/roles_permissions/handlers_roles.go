@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
 	"github.com/agent-auth/agent-auth-api/web/renderers"
 	"github.com/agent-auth/common-lib/models"
@@ -84,7 +85,7 @@ func (rp *rolesService) CreateRole(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if role with same name already exists in the project
-	existingRole, err := rp.rolesDal.GetByProjectIDAndRole(projectID, req.Roles.Role)
+	existingRole, err := rp.rolesDal.GetByProjectIDAndRole(r.Context(), projectID, req.Roles.Role)
 	if err == nil && existingRole != nil {
 		rp.logger.Error("role with same name already exists in project",
 			zap.String("name", req.Roles.Role),
@@ -100,13 +101,15 @@ func (rp *rolesService) CreateRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := rp.rolesDal.Create(req.Roles)
+	role, err := rp.rolesDal.Create(r.Context(), req.Roles)
 	if err != nil {
 		rp.logger.Error("failed to create role", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(err))
 		return
 	}
 
+	rp.auditor.Record(r, audit.ActionRoleCreate, roleTarget(role), nil, role)
+
 	render.Respond(w, r, &RoleResponse{
 		Roles: role,
 	})
@@ -139,7 +142,7 @@ func (rp *rolesService) GetRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := rp.rolesDal.Get(roleID)
+	role, err := rp.rolesDal.Get(r.Context(), roleID)
 	if err != nil {
 		rp.logger.Error("failed to get role", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(err))
@@ -164,7 +167,7 @@ func (rp *rolesService) GetRole(w http.ResponseWriter, r *http.Request) {
 // @Router /projects/{project_id}/roles/{role_id} [delete]
 // @Security BearerAuth
 func (rp *rolesService) DeleteRole(w http.ResponseWriter, r *http.Request) {
-	_, _, err := rp.hasMemberAccess(r)
+	projectID, _, err := rp.hasMemberAccess(r)
 	if err != nil {
 		rp.logger.Error("project membership verification failed", zap.Error(err))
 		render.Render(w, r, renderers.ErrorForbidden(fmt.Errorf("project membership verification failed")))
@@ -178,12 +181,27 @@ func (rp *rolesService) DeleteRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := rp.rolesDal.Delete(roleID); err != nil {
+	role, err := rp.rolesDal.Get(r.Context(), roleID)
+	if err != nil || role.ProjectID != projectID {
+		rp.logger.Error("role verification failed", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(fmt.Errorf("failed to delete role")))
+		return
+	}
+
+	if err := rp.rolesDal.Delete(r.Context(), roleID); err != nil {
 		rp.logger.Error("failed to delete role", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(fmt.Errorf("failed to delete role")))
 		return
 	}
 
+	if err := rp.bindingsDal.DeleteByRoleID(r.Context(), roleID); err != nil {
+		rp.logger.Error("failed to delete role bindings", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(fmt.Errorf("failed to delete role bindings")))
+		return
+	}
+
+	rp.auditor.Record(r, audit.ActionRoleDelete, roleTarget(role), role, nil)
+
 	render.Status(r, http.StatusNoContent)
 }
 
@@ -206,7 +224,7 @@ func (rp *rolesService) GetRolesByProject(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	roles, err := rp.rolesDal.GetByProjectID(projectID)
+	roles, err := rp.rolesDal.GetByProjectID(r.Context(), projectID)
 	if err != nil {
 		rp.logger.Error("failed to get project roles", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(fmt.Errorf("failed to get project roles")))
@@ -240,11 +258,28 @@ func (rp *rolesService) DeleteRolesByProject(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := rp.rolesDal.DeleteByProjectID(projectID); err != nil {
+	roles, err := rp.rolesDal.GetByProjectID(r.Context(), projectID)
+	if err != nil {
+		rp.logger.Error("failed to get project roles", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(fmt.Errorf("failed to delete project roles")))
+		return
+	}
+
+	if err := rp.rolesDal.DeleteByProjectID(r.Context(), projectID); err != nil {
 		rp.logger.Error("failed to delete project roles", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(fmt.Errorf("failed to delete project roles")))
 		return
 	}
 
+	if err := rp.bindingsDal.DeleteByProjectID(r.Context(), projectID); err != nil {
+		rp.logger.Error("failed to delete role bindings", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(fmt.Errorf("failed to delete role bindings")))
+		return
+	}
+
+	for _, role := range roles {
+		rp.auditor.Record(r, audit.ActionRoleDelete, roleTarget(role), role, nil)
+	}
+
 	render.Status(r, http.StatusNoContent)
 }
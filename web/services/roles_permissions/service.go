@@ -3,7 +3,10 @@ package roles_permissions
 import (
 	projects_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/projects"
 	resources_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/resources"
+	role_bindings_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/role_bindings"
 	roles_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/roles_permissions"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/notify"
 	"github.com/agent-auth/common-lib/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -13,14 +16,20 @@ type rolesService struct {
 	rolesDal     roles_dal.RolesDal
 	resourcesDal resources_dal.ResourcesDal
 	projectsDal  projects_dal.ProjectsDal
+	bindingsDal  role_bindings_dal.RoleBindingsDal
+	notifier     notify.Notifier
+	auditor      *audit.Recorder
 }
 
 // NewRolesService returns service impl
-func NewRolesService() RolesService {
+func NewRolesService(notifier notify.Notifier, auditor *audit.Recorder) RolesService {
 	return &rolesService{
 		logger:       logger.NewLogger(),
 		rolesDal:     roles_dal.NewRolesDal(),
 		resourcesDal: resources_dal.NewResourcesDal(),
 		projectsDal:  projects_dal.NewProjectsDal(),
+		bindingsDal:  role_bindings_dal.NewRoleBindingsDal(),
+		notifier:     notifier,
+		auditor:      auditor,
 	}
 }
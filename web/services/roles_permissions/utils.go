@@ -1,11 +1,14 @@
 package roles_permissions
 
 import (
+	"context"
 	"net/http"
 
 	"errors"
 
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/common-lib/models"
 	"github.com/go-chi/chi"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -17,12 +20,18 @@ func (rp *rolesService) hasMemberAccess(r *http.Request) (bson.ObjectID, string,
 		return bson.NilObjectID, "", errors.New("invalid project ID")
 	}
 
+	// Workspace owners and admins manage every project of their workspace
+	if authz.ManagesWorkspace(r) {
+		email, _ := authz.GetEmailFromClaims(r)
+		return projectID, email, nil
+	}
+
 	email, err := authz.GetEmailFromClaims(r)
 	if err != nil {
 		return bson.NilObjectID, "", errors.New("unauthorized access")
 	}
 
-	isMember, err := rp.projectsDal.IsMember(projectID, email)
+	isMember, err := rp.projectsDal.IsMember(r.Context(), projectID, email)
 	if err != nil {
 		return bson.NilObjectID, "", errors.New("failed to verify project membership")
 	}
@@ -35,8 +44,8 @@ func (rp *rolesService) hasMemberAccess(r *http.Request) (bson.ObjectID, string,
 }
 
 // Helper function to verify role belongs to project
-func (rp *rolesService) hasRoleAccess(roleID, projectID bson.ObjectID) error {
-	role, err := rp.rolesDal.Get(roleID)
+func (rp *rolesService) hasRoleAccess(ctx context.Context, roleID, projectID bson.ObjectID) error {
+	role, err := rp.rolesDal.Get(ctx, roleID)
 	if err != nil {
 		return errors.New("role not found")
 	}
@@ -45,3 +54,12 @@ func (rp *rolesService) hasRoleAccess(roleID, projectID bson.ObjectID) error {
 	}
 	return nil
 }
+
+// roleTarget identifies a role in the audit log
+func roleTarget(role *models.Roles) audit.Target {
+	return audit.Target{
+		Type:      audit.TargetRole,
+		ID:        role.ID.Hex(),
+		ProjectID: role.ProjectID,
+	}
+}
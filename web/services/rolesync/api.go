@@ -0,0 +1,26 @@
+package rolesync
+
+import (
+	"errors"
+	"net/http"
+)
+
+// RoleSyncService interface
+type RoleSyncService interface {
+	Status(w http.ResponseWriter, r *http.Request)
+	Drift(w http.ResponseWriter, r *http.Request)
+	ResyncProject(w http.ResponseWriter, r *http.Request)
+}
+
+// The list of error types presented to the end user
+var (
+	ErrInvalidProjectID    = errors.New("invalid project id")
+	ErrInternalServerError = errors.New("internal server error")
+)
+
+// List of error codes
+var (
+	FailedToGetSyncStatus = "Failed-To-Get-Sync-Status"
+	FailedToInspectSync   = "Failed-To-Inspect-Sync"
+	FailedToResyncProject = "Failed-To-Resync-Project"
+)
@@ -0,0 +1,117 @@
+package rolesync
+
+import (
+	"net/http"
+
+	"github.com/agent-auth/agent-auth-api/db/redis_dal"
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/agent-auth-api/pkg/leader"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// @Description Role sync status response model
+type SyncStatusResponse struct {
+	// Instance is the replica that answered
+	Instance string `json:"instance"`
+	// Leader is the replica running the syncer, empty while nobody holds the lease
+	Leader                string                    `json:"leader"`
+	IsLeader              bool                      `json:"is_leader"`
+	LeaseExpiresInSeconds float64                   `json:"lease_expires_in_seconds"`
+	LastSync              *redis_dal.RoleSyncStatus `json:"last_sync"`
+}
+
+// @Summary Get role sync status
+// @Description Returns the replica currently syncing roles from MongoDB to Redis and the last successful sync (system admin only)
+// @Tags sync
+// @Produce json
+// @Success 200 {object} SyncStatusResponse
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /admin/sync/status [get]
+// @Security BearerAuth
+func (rs *roleSyncService) Status(w http.ResponseWriter, r *http.Request) {
+	holder, remaining, err := rs.leases.Holder(r.Context(), redis_dal.RoleSyncLeaseKey)
+	if err != nil {
+		rs.logger.Error("failed to read role sync lease", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	lastSync, err := rs.store.GetSyncStatus(r.Context())
+	if err != nil {
+		rs.logger.Error("failed to read role sync status", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	render.Respond(w, r, &SyncStatusResponse{
+		Instance:              leader.InstanceID(),
+		Leader:                holder,
+		IsLeader:              holder != "" && holder == leader.InstanceID(),
+		LeaseExpiresInSeconds: remaining.Seconds(),
+		LastSync:              lastSync,
+	})
+}
+
+// @Summary Inspect role sync drift
+// @Description Compares the roles of every project in MongoDB, their count and latest update, with the version and sync time of its Redis snapshot, and lists the projects whose snapshot is stale, missing or left without roles (system admin only)
+// @Tags sync
+// @Produce json
+// @Success 200 {object} redis_dal.SyncDriftReport
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /admin/sync/drift [get]
+// @Security BearerAuth
+func (rs *roleSyncService) Drift(w http.ResponseWriter, r *http.Request) {
+	report, err := rs.store.InspectSync(r.Context())
+	if err != nil {
+		rs.logger.Error("failed to inspect role sync", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	render.Respond(w, r, report)
+}
+
+// @Summary Resync project roles
+// @Description Rebuilds the Redis roles and bindings snapshots of a project from MongoDB and returns how they compare afterwards (system admin only)
+// @Tags sync
+// @Produce json
+// @Param project_id path string true "Project ID"
+// @Success 200 {object} redis_dal.ProjectSyncState
+// @Failure 400 {object} errorinterface.ErrorResponse
+// @Failure 403 {object} errorinterface.ErrorResponse
+// @Failure 500 {object} errorinterface.ErrorResponse
+// @Router /admin/sync/projects/{project_id}/resync [post]
+// @Security BearerAuth
+func (rs *roleSyncService) ResyncProject(w http.ResponseWriter, r *http.Request) {
+	email, _ := authz.GetEmailFromClaims(r)
+
+	projectID, err := bson.ObjectIDFromHex(chi.URLParam(r, "project_id"))
+	if err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrInvalidProjectID))
+		return
+	}
+
+	state, err := rs.store.ResyncProject(r.Context(), projectID)
+	if err != nil {
+		rs.logger.Error("failed to resync project roles",
+			zap.String("projectID", projectID.Hex()),
+			zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	rs.logger.Info("project roles resynced",
+		zap.String("projectID", projectID.Hex()),
+		zap.String("state", state.State),
+		zap.String("bindingsState", state.BindingsState),
+		zap.String("resyncedBy", email))
+
+	render.Respond(w, r, state)
+}
@@ -0,0 +1,34 @@
+package rolesync
+
+import (
+	"context"
+
+	"github.com/agent-auth/agent-auth-api/db/redis_dal"
+	"github.com/agent-auth/agent-auth-api/pkg/leader"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// RoleSyncStore reports on and repairs the Redis role snapshots
+type RoleSyncStore interface {
+	GetSyncStatus(ctx context.Context) (*redis_dal.RoleSyncStatus, error)
+	InspectSync(ctx context.Context) (*redis_dal.SyncDriftReport, error)
+	ResyncProject(ctx context.Context, projectID bson.ObjectID) (*redis_dal.ProjectSyncState, error)
+}
+
+type roleSyncService struct {
+	logger *zap.Logger
+	leases leader.LeaseStore
+	store  RoleSyncStore
+}
+
+// NewRoleSyncService returns service impl. The lease and sync state live in
+// Redis, so any replica can report on the sync and repair it.
+func NewRoleSyncService(leases leader.LeaseStore, store RoleSyncStore) RoleSyncService {
+	return &roleSyncService{
+		logger: logger.NewLogger(),
+		leases: leases,
+		store:  store,
+	}
+}
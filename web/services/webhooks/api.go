@@ -0,0 +1,43 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+)
+
+// WebhookService interface
+type WebhookService interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	ListDeliveries(w http.ResponseWriter, r *http.Request)
+	ListDeadLetters(w http.ResponseWriter, r *http.Request)
+	Redeliver(w http.ResponseWriter, r *http.Request)
+}
+
+// The list of error types presented to the end user
+var (
+	ErrIncompleteDetails   = errors.New("incorrect details provided, please provide correct details")
+	ErrInvalidURL          = errors.New("webhook url must be an absolute https url")
+	ErrForbiddenURL        = errors.New("webhook url must not resolve to a private, loopback or link-local address")
+	ErrUnresolvableURL     = errors.New("webhook url host cannot be resolved")
+	ErrInvalidEventType    = errors.New("unknown webhook event type")
+	ErrProjectNotFound     = errors.New("project not found in this workspace")
+	ErrNotFound            = errors.New("webhook not found")
+	ErrDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrInactive            = errors.New("webhook is disabled")
+	ErrInternalServerError = errors.New("internal server error")
+)
+
+// List of error codes
+var (
+	FailedToCreateWebhook     = "Failed-To-Create-Webhook"
+	FailedToGetWebhook        = "Failed-To-Get-Webhook"
+	FailedToUpdateWebhook     = "Failed-To-Update-Webhook"
+	FailedToDeleteWebhook     = "Failed-To-Delete-Webhook"
+	FailedToListWebhooks      = "Failed-To-List-Webhooks"
+	FailedToListDeliveries    = "Failed-To-List-Webhook-Deliveries"
+	FailedToRedeliverDelivery = "Failed-To-Redeliver-Webhook"
+)
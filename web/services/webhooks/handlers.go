@@ -0,0 +1,438 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+
+	webhook_deliveries_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/webhook_deliveries"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/agent-auth-api/pkg/webhook"
+	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
+	"github.com/agent-auth/agent-auth-api/web/renderers"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+// @Description Webhook subscription request model. Empty event_types selects every event; a filter may end in ".*" to select a family such as "role.*". Active defaults to true.
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	ProjectID   string   `json:"project_id,omitempty"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+func (wr *WebhookRequest) Bind(r *http.Request) error {
+	if wr.URL == "" {
+		return ErrInvalidURL
+	}
+	if wr.ProjectID != "" {
+		if _, err := bson.ObjectIDFromHex(wr.ProjectID); err != nil {
+			return ErrIncompleteDetails
+		}
+	}
+	if wr.EventTypes == nil {
+		wr.EventTypes = []string{}
+	}
+	for _, eventType := range wr.EventTypes {
+		if !webhook.ValidFilter(eventType) {
+			return ErrInvalidEventType
+		}
+	}
+	return nil
+}
+
+// @Description Webhook response model. Secret is only returned on creation; it signs every delivery.
+type WebhookResponse struct {
+	*webhook.Subscription
+	Secret string `json:"secret,omitempty"`
+}
+
+// @Description Webhooks list response model
+type WebhooksResponse struct {
+	Webhooks []*webhook.Subscription `json:"webhooks"`
+}
+
+// @Description Webhook deliveries response model, newest first
+type DeliveriesResponse struct {
+	Deliveries []*webhook.Delivery `json:"deliveries"`
+	Skip       int64               `json:"skip"`
+	Limit      int64               `json:"limit"`
+}
+
+// @Summary Create webhook
+// @Description Subscribes a URL to the change events of a workspace, or of one of its projects. The URL must resolve to public addresses. Deliveries are signed with the returned secret: X-Webhook-Signature is "sha256=" followed by the hex HMAC-SHA256 of X-Webhook-Timestamp, ".", and the body.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook body WebhookRequest true "Webhook details"
+// @Success 200 {object} WebhookResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/webhooks [post]
+// @Security BearerAuth
+func (ws *webhookService) Create(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := scopedWorkspace(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	var req WebhookRequest
+	if err := render.Bind(r, &req); err != nil {
+		ws.logger.Error("failed to bind webhook request", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+	if err := ws.checkURL(r.Context(), req.URL); err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	subscription := &webhook.Subscription{
+		WorkspaceID: workspaceID,
+		URL:         req.URL,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+	}
+	subscription.CreatedBy, _ = actor(r)
+
+	if req.ProjectID != "" {
+		subscription.ProjectID, _ = bson.ObjectIDFromHex(req.ProjectID)
+		project, err := ws.projectDal.GetByID(r.Context(), subscription.ProjectID)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			ws.logger.Error("failed to get project", zap.Error(err))
+			render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+			return
+		}
+		if err != nil || project.WorkspaceID != workspaceID {
+			render.Render(w, r, renderers.ErrorBadRequest(ErrProjectNotFound))
+			return
+		}
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		ws.logger.Error("failed to generate webhook secret", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	subscription.Secret = secret
+
+	if _, err := ws.webhookDal.Create(r.Context(), subscription); err != nil {
+		ws.logger.Error("failed to create webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	ws.auditor.Record(r, audit.ActionWebhookCreate, webhookTarget(subscription), nil, subscription)
+
+	render.Respond(w, r, &WebhookResponse{
+		Subscription: subscription,
+		Secret:       secret,
+	})
+}
+
+// @Summary List webhooks
+// @Description Lists the webhook subscriptions of a workspace
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} WebhooksResponse
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/webhooks [get]
+// @Security BearerAuth
+func (ws *webhookService) List(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := scopedWorkspace(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	subscriptions, err := ws.webhookDal.List(r.Context(), workspaceID)
+	if err != nil {
+		ws.logger.Error("failed to list webhooks", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []*webhook.Subscription{}
+	}
+
+	render.Respond(w, r, &WebhooksResponse{Webhooks: subscriptions})
+}
+
+// @Summary Get webhook
+// @Description Returns a webhook subscription
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 200 {object} WebhookResponse
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/webhooks/{webhook_id} [get]
+// @Security BearerAuth
+func (ws *webhookService) Get(w http.ResponseWriter, r *http.Request) {
+	subscription, err := ws.getWebhook(r)
+	if err != nil {
+		ws.logger.Error("failed to get webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if subscription == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+
+	render.Respond(w, r, &WebhookResponse{Subscription: subscription})
+}
+
+// @Summary Update webhook
+// @Description Changes the URL, event filters, description or active state of a webhook. The project and secret cannot be changed.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param webhook body WebhookRequest true "Webhook details"
+// @Success 200 {object} WebhookResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/webhooks/{webhook_id} [put]
+// @Security BearerAuth
+func (ws *webhookService) Update(w http.ResponseWriter, r *http.Request) {
+	subscription, err := ws.getWebhook(r)
+	if err != nil {
+		ws.logger.Error("failed to get webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if subscription == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+
+	var req WebhookRequest
+	if err := render.Bind(r, &req); err != nil {
+		ws.logger.Error("failed to bind webhook request", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+	if err := ws.checkURL(r.Context(), req.URL); err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	before := *subscription
+	subscription.URL = req.URL
+	subscription.EventTypes = req.EventTypes
+	subscription.Description = req.Description
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+
+	if err := ws.webhookDal.Update(r.Context(), subscription); err != nil {
+		ws.logger.Error("failed to update webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	ws.auditor.Record(r, audit.ActionWebhookUpdate, webhookTarget(subscription), &before, subscription)
+
+	render.Respond(w, r, &WebhookResponse{Subscription: subscription})
+}
+
+// @Summary Delete webhook
+// @Description Deletes a webhook subscription. Its pending deliveries are dead-lettered.
+// @Tags webhooks
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 204
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/webhooks/{webhook_id} [delete]
+// @Security BearerAuth
+func (ws *webhookService) Delete(w http.ResponseWriter, r *http.Request) {
+	subscription, err := ws.getWebhook(r)
+	if err != nil {
+		ws.logger.Error("failed to get webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if subscription == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+
+	if err := ws.webhookDal.Delete(r.Context(), subscription.ID); err != nil {
+		ws.logger.Error("failed to delete webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	ws.auditor.Record(r, audit.ActionWebhookDelete, webhookTarget(subscription), subscription, nil)
+
+	render.Status(r, http.StatusNoContent)
+	render.NoContent(w, r)
+}
+
+// @Summary List webhook deliveries
+// @Description Lists the delivery history of a webhook, newest first, with every attempt and its status code
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param status query string false "pending, succeeded or dead_letter"
+// @Param skip query int false "Number of deliveries to skip"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Success 200 {object} DeliveriesResponse
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/webhooks/{webhook_id}/deliveries [get]
+// @Security BearerAuth
+func (ws *webhookService) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	subscription, err := ws.getWebhook(r)
+	if err != nil {
+		ws.logger.Error("failed to get webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if subscription == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+
+	ws.listDeliveries(w, r, webhook_deliveries_dal.Filter{
+		WebhookID: subscription.ID,
+		Status:    r.URL.Query().Get("status"),
+	})
+}
+
+// @Summary List dead-lettered deliveries
+// @Description Lists the deliveries of a workspace's webhooks that ran out of attempts, newest first
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param skip query int false "Number of deliveries to skip"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Success 200 {object} DeliveriesResponse
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/webhooks/dead-letters [get]
+// @Security BearerAuth
+func (ws *webhookService) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := scopedWorkspace(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorBadRequest(err))
+		return
+	}
+
+	ws.listDeliveries(w, r, webhook_deliveries_dal.Filter{
+		WorkspaceID: workspaceID,
+		Status:      webhook.StatusDeadLetter,
+	})
+}
+
+func (ws *webhookService) listDeliveries(w http.ResponseWriter, r *http.Request, filter webhook_deliveries_dal.Filter) {
+	skip, limit := page(r)
+
+	deliveries, err := ws.deliveryDal.List(r.Context(), filter, skip, limit)
+	if err != nil {
+		ws.logger.Error("failed to list webhook deliveries", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*webhook.Delivery{}
+	}
+
+	render.Respond(w, r, &DeliveriesResponse{
+		Deliveries: deliveries,
+		Skip:       skip,
+		Limit:      limit,
+	})
+}
+
+// @Summary Redeliver webhook
+// @Description Queues the payload of a past delivery again, as a new delivery with its own attempts. The original signature is not reused; each attempt is signed when it is sent.
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 202 {object} webhook.Delivery
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 403 {object} errorinterface.ErrorResponse{}
+// @Failure 404 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver [post]
+// @Security BearerAuth
+func (ws *webhookService) Redeliver(w http.ResponseWriter, r *http.Request) {
+	subscription, err := ws.getWebhook(r)
+	if err != nil {
+		ws.logger.Error("failed to get webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if subscription == nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+	if !subscription.Active {
+		render.Render(w, r, renderers.ErrorBadRequest(ErrInactive))
+		return
+	}
+
+	deliveryID, err := bson.ObjectIDFromHex(chi.URLParam(r, "delivery_id"))
+	if err != nil {
+		render.Render(w, r, renderers.ErrorNotFound(ErrDeliveryNotFound))
+		return
+	}
+
+	delivery, err := ws.deliveryDal.Get(r.Context(), deliveryID)
+	if err != nil {
+		ws.logger.Error("failed to get webhook delivery", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+	if delivery == nil || delivery.WebhookID != subscription.ID {
+		render.Render(w, r, renderers.ErrorNotFound(ErrDeliveryNotFound))
+		return
+	}
+
+	redelivery, err := ws.dispatcher.Redeliver(r.Context(), delivery)
+	if err != nil {
+		ws.logger.Error("failed to redeliver webhook", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(ErrInternalServerError))
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.Respond(w, r, redelivery)
+}
+
+// actor names the caller: users by email, agents by token subject
+func actor(r *http.Request) (string, error) {
+	principal, err := authz.GetPrincipal(r)
+	if err != nil {
+		return "", err
+	}
+	if principal.Email != "" {
+		return principal.Email, nil
+	}
+	return principal.Subject, nil
+}
@@ -0,0 +1,35 @@
+package webhooks
+
+import (
+	projects_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/projects"
+	webhook_deliveries_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/webhook_deliveries"
+	webhooks_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/webhooks"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/webhook"
+	"github.com/agent-auth/common-lib/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type webhookService struct {
+	logger      *zap.Logger
+	webhookDal  webhooks_dal.WebhooksDal
+	deliveryDal webhook_deliveries_dal.WebhookDeliveriesDal
+	projectDal  projects_dal.ProjectsDal
+	dispatcher  *webhook.Dispatcher
+	auditor     *audit.Recorder
+	allowHTTP   bool
+}
+
+// NewWebhookService returns service impl. allowHTTP accepts plain http
+// webhook URLs, for local development.
+func NewWebhookService(webhookDal webhooks_dal.WebhooksDal, deliveryDal webhook_deliveries_dal.WebhookDeliveriesDal, dispatcher *webhook.Dispatcher, auditor *audit.Recorder, allowHTTP bool) WebhookService {
+	return &webhookService{
+		logger:      logger.NewLogger(),
+		webhookDal:  webhookDal,
+		deliveryDal: deliveryDal,
+		projectDal:  projects_dal.NewProjectsDal(),
+		dispatcher:  dispatcher,
+		auditor:     auditor,
+		allowHTTP:   allowHTTP,
+	}
+}
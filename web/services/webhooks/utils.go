@@ -0,0 +1,105 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
+	"github.com/agent-auth/agent-auth-api/pkg/authz"
+	"github.com/agent-auth/agent-auth-api/pkg/webhook"
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// webhookTarget identifies a subscription in the audit log
+func webhookTarget(subscription *webhook.Subscription) audit.Target {
+	return audit.Target{
+		Type:        audit.TargetWebhook,
+		ID:          subscription.ID.Hex(),
+		WorkspaceID: subscription.WorkspaceID,
+		ProjectID:   subscription.ProjectID,
+	}
+}
+
+// scopedWorkspace returns the workspace the caller's access was checked against
+func scopedWorkspace(r *http.Request) (bson.ObjectID, error) {
+	scope, ok := authz.WorkspaceScopeFromContext(r.Context())
+	if !ok {
+		return bson.NilObjectID, ErrIncompleteDetails
+	}
+
+	workspaceID, err := bson.ObjectIDFromHex(scope.WorkspaceID)
+	if err != nil {
+		return bson.NilObjectID, ErrIncompleteDetails
+	}
+	return workspaceID, nil
+}
+
+// getWebhook returns the subscription named in the URL, or nil when it does
+// not exist in the scoped workspace
+func (ws *webhookService) getWebhook(r *http.Request) (*webhook.Subscription, error) {
+	workspaceID, err := scopedWorkspace(r)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := bson.ObjectIDFromHex(chi.URLParam(r, "webhook_id"))
+	if err != nil {
+		return nil, nil
+	}
+
+	subscription, err := ws.webhookDal.Get(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil || subscription.WorkspaceID != workspaceID {
+		return nil, nil
+	}
+	return subscription, nil
+}
+
+// checkURL returns nil when raw is an absolute URL deliveries may be posted
+// to, and otherwise the error to present
+func (ws *webhookService) checkURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	if u.Scheme != "https" && !(ws.allowHTTP && u.Scheme == "http") {
+		return ErrInvalidURL
+	}
+
+	err = ws.dispatcher.CheckDestination(ctx, raw)
+	switch {
+	case errors.Is(err, webhook.ErrForbiddenDestination):
+		return ErrForbiddenURL
+	case err != nil:
+		ws.logger.Info("failed to resolve webhook url", zap.String("host", u.Hostname()), zap.Error(err))
+		return ErrUnresolvableURL
+	}
+	return nil
+}
+
+// page reads the skip and limit query parameters
+func page(r *http.Request) (int64, int64) {
+	query := r.URL.Query()
+
+	skip, err := strconv.ParseInt(query.Get("skip"), 10, 64)
+	if err != nil || skip < 0 {
+		skip = 0
+	}
+
+	limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 50 // default limit
+	}
+	if limit > 500 {
+		limit = 500 // maximum page size
+	}
+
+	return skip, limit
+}
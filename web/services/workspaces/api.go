@@ -13,6 +13,7 @@ type WorkspaceService interface {
 	Get(w http.ResponseWriter, r *http.Request)
 	List(w http.ResponseWriter, r *http.Request)
 	AddMember(w http.ResponseWriter, r *http.Request)
+	ListMembers(w http.ResponseWriter, r *http.Request)
 	RemoveMember(w http.ResponseWriter, r *http.Request)
 }
 
@@ -21,6 +22,7 @@ var (
 	ErrIncompleteDetails = errors.New("incorrect details provided, please provide correct details")
 	ErrNotFound          = errors.New("workspace not found")
 	ErrUnauthorized      = errors.New("unauthorized to perform this action")
+	ErrInvalidRole       = errors.New("invalid workspace role")
 )
 
 // List of error codes
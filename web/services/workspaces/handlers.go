@@ -1,12 +1,15 @@
 package workspaces
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	workspace_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspace_members"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/agent-auth-api/pkg/authz"
 	_ "github.com/agent-auth/agent-auth-api/web/interfaces/v1/errorinterface" // docs is generated by Swag CLI, you have to import it.
 	"github.com/agent-auth/agent-auth-api/web/renderers"
@@ -35,17 +38,40 @@ type WorkspaceResponse struct {
 type WorkspacesResponse struct {
 	Workspaces []*models.Workspace `json:"workspaces"`
 }
+
+// @Description Workspace member request model. Role defaults to member.
 type AddMemberRequest struct {
 	MemberID string `json:"memberID"`
+	Role     string `json:"role"`
+}
+
+func (a *AddMemberRequest) Bind(r *http.Request) error {
+	a.MemberID = strings.TrimSpace(a.MemberID)
+	if a.MemberID == "" {
+		return ErrIncompleteDetails
+	}
+	if a.Role == "" {
+		a.Role = string(authz.WorkspaceRoleMember)
+	}
+	// Ownership changes hands only through an explicit transfer
+	if role := authz.WorkspaceRole(a.Role); !role.Valid() || role == authz.WorkspaceRoleOwner {
+		return ErrInvalidRole
+	}
+	return nil
+}
+
+// @Description Workspace members list response model
+type MembersResponse struct {
+	Members []*workspace_members_dal.WorkspaceMember `json:"members"`
 }
 
 // @Summary Add member to workspace
-// @Description Adds a new member to a workspace (owner only)
+// @Description Adds a member to a workspace or changes their role (owner or admin only)
 // @Tags workspaces
 // @Accept json
 // @Produce json
 // @Param workspace_id path string true "Workspace ID"
-// @Param member body AddMemberRequest true "Member ID to add"
+// @Param member body AddMemberRequest true "Member to add"
 // @Success 204 "No Content"
 // @Failure 400 {object} errorinterface.ErrorResponse{}
 // @Failure 401 {object} errorinterface.ErrorResponse{}
@@ -64,46 +90,92 @@ func (ws *workspaceService) AddMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req AddMemberRequest
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := render.Bind(r, &req); err != nil {
 		ws.logger.Error("failed to decode request", zap.Error(err))
-		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		render.Render(w, r, renderers.ErrorBadRequest(err))
 		return
 	}
 
-	// Verify ownership
-	workspace, err := ws.workspaceDal.GetByID(workspaceID)
+	workspace, err := ws.workspaceDal.GetByID(r.Context(), workspaceID)
 	if err != nil {
 		ws.logger.Error("failed to get workspace", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
 		return
 	}
 
-	if workspace.OwnerID != email {
+	if !authz.ManagesWorkspace(r) {
 		ws.logger.Error("unauthorized add member attempt", zap.String("userID", email))
 		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
 		return
 	}
 
-	// Check if member already exists
-	for _, existingMember := range workspace.Members {
-		if existingMember == req.MemberID {
-			render.Status(r, http.StatusNoContent) // Member already exists, return success
-			return
-		}
+	if req.MemberID == workspace.OwnerID {
+		render.Render(w, r, renderers.ErrorBadRequest(errors.New("cannot change the role of the workspace owner")))
+		return
+	}
+
+	previous, err := ws.memberDal.Get(r.Context(), workspaceID, req.MemberID)
+	if err != nil {
+		ws.logger.Error("failed to get member", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	if err := ws.workspaceDal.AddMember(r.Context(), workspaceID.Hex(), req.MemberID); err != nil {
+		ws.logger.Error("failed to add member", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
 	}
 
-	if err := ws.workspaceDal.AddMember(workspaceID.Hex(), req.MemberID); err != nil {
+	member, err := ws.memberDal.Upsert(r.Context(), workspaceID, req.MemberID, req.Role)
+	if err != nil {
 		ws.logger.Error("failed to add member", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(err))
 		return
 	}
 
+	ws.auditor.Record(r, audit.ActionWorkspaceMemberAdd, audit.Target{
+		Type:        audit.TargetWorkspace,
+		ID:          workspaceID.Hex(),
+		WorkspaceID: workspaceID,
+	}, previous, member)
+
 	render.Status(r, http.StatusNoContent)
 }
 
+// @Summary List workspace members
+// @Description Lists the members of a workspace with their roles
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} MembersResponse
+// @Failure 400 {object} errorinterface.ErrorResponse{}
+// @Failure 500 {object} errorinterface.ErrorResponse{}
+// @Router /workspaces/{workspace_id}/members [get]
+// @Security BearerAuth
+func (ws *workspaceService) ListMembers(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := bson.ObjectIDFromHex(chi.URLParam(r, "workspace_id"))
+	if err != nil {
+		ws.logger.Error("invalid workspace ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
+	members, err := ws.memberDal.ListByWorkspace(r.Context(), workspaceID)
+	if err != nil {
+		ws.logger.Error("failed to list members", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	render.Respond(w, r, &MembersResponse{
+		Members: members,
+	})
+}
+
 // @Summary Remove member from workspace
-// @Description Removes a member from a workspace (owner only)
+// @Description Removes a member from a workspace (owner or admin only)
 // @Tags workspaces
 // @Accept json
 // @Produce json
@@ -126,34 +198,55 @@ func (ws *workspaceService) RemoveMember(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	memberID := chi.URLParam(r, "member_id")
-	if err != nil {
+	memberID, err := url.PathUnescape(chi.URLParam(r, "member_id"))
+	if err != nil || memberID == "" {
 		ws.logger.Error("invalid member ID", zap.Error(err))
 		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
 		return
 	}
 
-	// Verify ownership
-	workspace, err := ws.workspaceDal.GetByID(workspaceID)
+	workspace, err := ws.workspaceDal.GetByID(r.Context(), workspaceID)
 	if err != nil {
 		ws.logger.Error("failed to get workspace", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
 		return
 	}
 
-	if workspace.OwnerID != email {
+	if !authz.ManagesWorkspace(r) {
 		ws.logger.Error("unauthorized remove member attempt", zap.String("userID", email))
 		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
 		return
 	}
 
-	if memberID == email {
+	if memberID == workspace.OwnerID {
 		ws.logger.Error("attempt to remove workspace owner", zap.String("workspaceID", workspaceID.Hex()))
 		render.Render(w, r, renderers.ErrorBadRequest(errors.New("cannot remove workspace owner")))
 		return
 	}
 
-	if err := ws.workspaceDal.RemoveMember(workspaceID.Hex(), memberID); err != nil {
+	previous, err := ws.memberDal.Get(r.Context(), workspaceID, memberID)
+	if err != nil {
+		ws.logger.Error("failed to get member", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	// The membership record grants access, so it goes first and is what the
+	// audit event records; a failure after it leaves a stale Members entry
+	// rather than a member who keeps their role
+	if err := ws.memberDal.Remove(r.Context(), workspaceID, memberID); err != nil {
+		ws.logger.Error("failed to remove member", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	ws.auditor.Record(r, audit.ActionWorkspaceMemberRemove, audit.Target{
+		Type:        audit.TargetWorkspace,
+		ID:          workspaceID.Hex(),
+		WorkspaceID: workspaceID,
+	}, previous, nil)
+
+	if err := ws.workspaceDal.RemoveMember(r.Context(), workspaceID.Hex(), memberID); err != nil {
 		ws.logger.Error("failed to remove member", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(err))
 		return
@@ -196,13 +289,25 @@ func (ws *workspaceService) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := ws.workspaceDal.Create(workspace.Workspace)
+	resp, err := ws.workspaceDal.Create(r.Context(), workspace.Workspace)
 	if err != nil {
 		ws.logger.Error("failed to create workspace", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(err))
 		return
 	}
 
+	if _, err := ws.memberDal.Upsert(r.Context(), resp.ID, email, string(authz.WorkspaceRoleOwner)); err != nil {
+		ws.logger.Error("failed to add workspace owner", zap.Error(err))
+		render.Render(w, r, renderers.ErrorInternalServerError(err))
+		return
+	}
+
+	ws.auditor.Record(r, audit.ActionWorkspaceCreate, audit.Target{
+		Type:        audit.TargetWorkspace,
+		ID:          resp.ID.Hex(),
+		WorkspaceID: resp.ID,
+	}, nil, resp)
+
 	render.Respond(w, r, &WorkspaceResponse{
 		Workspace: resp,
 	})
@@ -228,7 +333,7 @@ func (ws *workspaceService) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workspace, err := ws.workspaceDal.GetByID(workspaceID)
+	workspace, err := ws.workspaceDal.GetByID(r.Context(), workspaceID)
 	if err != nil {
 		ws.logger.Error("failed to get workspace", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
@@ -255,6 +360,13 @@ func (ws *workspaceService) Get(w http.ResponseWriter, r *http.Request) {
 // @Router /workspaces/{workspace_id} [put]
 // @Security BearerAuth
 func (ws *workspaceService) Update(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := bson.ObjectIDFromHex(chi.URLParam(r, "workspace_id"))
+	if err != nil {
+		ws.logger.Error("invalid workspace ID", zap.Error(err))
+		render.Render(w, r, renderers.ErrorBadRequest(ErrIncompleteDetails))
+		return
+	}
+
 	workspace := &WorkspaceRequest{
 		Workspace: &models.Workspace{},
 	}
@@ -265,14 +377,16 @@ func (ws *workspaceService) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify ownership
-	existing, err := ws.workspaceDal.GetByID(workspace.ID)
+	// The route's workspace is the one access was checked against
+	existing, err := ws.workspaceDal.GetByID(r.Context(), workspaceID)
 	if err != nil {
 		ws.logger.Error("failed to get workspace", zap.Error(err))
 		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
 		return
 	}
 
+	before := *existing
+
 	// Prevent changes to owner, members, and slug
 	workspace.Workspace.OwnerID = existing.OwnerID
 	workspace.Workspace.Members = existing.Members
@@ -288,12 +402,18 @@ func (ws *workspaceService) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := ws.workspaceDal.Update(existing); err != nil {
+	if err := ws.workspaceDal.Update(r.Context(), existing); err != nil {
 		ws.logger.Error("failed to update workspace", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(err))
 		return
 	}
 
+	ws.auditor.Record(r, audit.ActionWorkspaceUpdate, audit.Target{
+		Type:        audit.TargetWorkspace,
+		ID:          workspaceID.Hex(),
+		WorkspaceID: workspaceID,
+	}, &before, existing)
+
 	render.Respond(w, r, &WorkspaceResponse{
 		Workspace: existing,
 	})
@@ -320,17 +440,30 @@ func (ws *workspaceService) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := ws.workspaceDal.Delete(workspaceID); err != nil {
+	existing, err := ws.workspaceDal.GetByID(r.Context(), workspaceID)
+	if err != nil {
+		ws.logger.Error("failed to get workspace", zap.Error(err))
+		render.Render(w, r, renderers.ErrorNotFound(ErrNotFound))
+		return
+	}
+
+	if err := ws.workspaceDal.Delete(r.Context(), workspaceID); err != nil {
 		ws.logger.Error("failed to delete workspace", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(err))
 		return
 	}
 
+	ws.auditor.Record(r, audit.ActionWorkspaceDelete, audit.Target{
+		Type:        audit.TargetWorkspace,
+		ID:          workspaceID.Hex(),
+		WorkspaceID: workspaceID,
+	}, existing, nil)
+
 	render.Status(r, http.StatusNoContent)
 }
 
 // @Summary List workspaces
-// @Description Lists all workspaces with pagination
+// @Description Lists the workspaces the caller is a member of with pagination
 // @Tags workspaces
 // @Accept json
 // @Produce json
@@ -357,7 +490,25 @@ func (ws *workspaceService) List(w http.ResponseWriter, r *http.Request) {
 		limit = 10 // enforce reasonable limits
 	}
 
-	workspaces, err := ws.workspaceDal.List(skip, limit)
+	principal, err := authz.GetPrincipal(r)
+	if err != nil {
+		render.Render(w, r, renderers.ErrorUnauthorized(ErrUnauthorized))
+		return
+	}
+
+	// System administrators see every workspace, others only their own
+	var workspaces []*models.Workspace
+	if principal.HasRole(authz.SystemAdmin) {
+		workspaces, err = ws.workspaceDal.List(r.Context(), skip, limit)
+	} else {
+		var ids []bson.ObjectID
+		if principal.Email != "" {
+			ids, err = ws.memberDal.ListWorkspaceIDs(r.Context(), principal.Email)
+		}
+		if err == nil && len(ids) > 0 {
+			workspaces, err = ws.workspaceDal.ListByIDs(r.Context(), ids, skip, limit)
+		}
+	}
 	if err != nil {
 		ws.logger.Error("failed to list workspaces", zap.Error(err))
 		render.Render(w, r, renderers.ErrorInternalServerError(err))
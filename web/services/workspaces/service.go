@@ -1,7 +1,9 @@
 package workspaces
 
 import (
+	workspace_members_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspace_members"
 	workspaces_dal "github.com/agent-auth/agent-auth-api/db/mongo_dal/workspaces"
+	"github.com/agent-auth/agent-auth-api/pkg/audit"
 	"github.com/agent-auth/common-lib/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -9,12 +11,16 @@ import (
 type workspaceService struct {
 	logger       *zap.Logger
 	workspaceDal workspaces_dal.WorkspaceDal
+	memberDal    workspace_members_dal.WorkspaceMembersDal
+	auditor      *audit.Recorder
 }
 
 // NewWorkspaceService returns service impl
-func NewWorkspaceService() WorkspaceService {
+func NewWorkspaceService(auditor *audit.Recorder) WorkspaceService {
 	return &workspaceService{
 		logger:       logger.NewLogger(),
 		workspaceDal: workspaces_dal.NewWorkspaceDal(),
+		memberDal:    workspace_members_dal.NewWorkspaceMembersDal(),
+		auditor:      auditor,
 	}
 }